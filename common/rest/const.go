@@ -11,6 +11,13 @@ const (
 	HeaderAccept          = "Accept"
 	HeaderContentType     = "Content-Type"
 	HeaderApplicationJSON = "application/json"
+	HeaderAuthorization   = "Authorization"
+	BearerPrefix          = "Bearer "
+)
+
+// define keys of gin context
+const (
+	ContextClaims = "claims" // claims of the access token
 )
 
 // user source
@@ -28,6 +35,52 @@ const (
 	UserTypeOrgCharity = "charity" // charity
 )
 
+// kyc status of account
+const (
+	KycStatusPending  = "pending"  // submitted, waiting for review by admin
+	KycStatusApproved = "approved" // approved by admin
+	KycStatusRejected = "rejected" // rejected by admin, can be submitted again
+)
+
+// kyc type
+const (
+	KycTypePerson = "person" // kyc of single person
+	KycTypeOrg    = "org"    // kyc of organization
+)
+
+// the status of application to become charity
+const (
+	ApplyStatusPending  = "pending"  // submitted, waiting for review by admin
+	ApplyStatusApproved = "approved" // approved by admin, the account is charity
+	ApplyStatusRejected = "rejected" // rejected by admin, can be submitted again
+)
+
+// the type of leaderboard
+const (
+	BoardCharity = "charity" // ranking of charities approved by kyc
+	BoardDonor   = "donor"   // ranking of donors
+)
+
+// the period of leaderboard, counted back from the time refreshed
+const (
+	PeriodWeek  = "week"  // the last 7 days
+	PeriodMonth = "month" // the last 30 days
+	PeriodYear  = "year"  // the last 365 days
+	PeriodAll   = "all"   // all time
+)
+
+// the metric ranked by leaderboard
+const (
+	MetricReceivedFunds       = "received_funds"       // funds donated to charity
+	MetricDistributedFunds    = "distributed_funds"    // funds distributed by charity
+	MetricDistributionRatio   = "distribution_ratio"   // distributed funds divided by received funds of charity
+	MetricReceivedSupplies    = "received_supplies"    // supplies donated to charity
+	MetricDistributedSupplies = "distributed_supplies" // supplies distributed by charity
+	MetricDonors              = "donors"               // number of donors of charity
+	MetricDonatedFunds        = "donated_funds"        // funds donated by donor
+	MetricDonatedSupplies     = "donated_supplies"     // supplies donated by donor
+)
+
 // publicity type
 const (
 	PubTypeDonate     = "donate"     // donation by aid user
@@ -60,10 +113,11 @@ const (
 
 // the type of image
 const (
-	ImageAvatar     = "avatar" // avatar image of user
-	ImageProof      = "proof"  // proof image of donation
-	ImageIDCardHead = "head"   // front image of id card
-	ImageIDCardBack = "back"   // back image of id card
+	ImageAvatar     = "avatar"  // avatar image of user
+	ImageProof      = "proof"   // proof image of donation
+	ImageIDCardHead = "head"    // front image of id card
+	ImageIDCardBack = "back"    // back image of id card
+	ImageGallery    = "gallery" // gallery image of charity
 )
 
 // the type of items donated
@@ -72,6 +126,45 @@ const (
 	DonatedTypeSupplies = "supplies" // supplies of donation
 )
 
+// the status of publicity on block chain
+const (
+	ChainStatusPending    = "pending"       // waiting in outbox to be published
+	ChainStatusPublishing = "publishing"    // claimed by one publisher, being published
+	ChainStatusPublished  = "published"     // accepted by block chain adapter, waiting for call back
+	ChainStatusConfirmed  = "confirmed"     // block chain call back received
+	ChainStatusFailed     = "failed"        // publish failed after max attempts
+	PendingChain          = "pending_chain" // status of the record returned when created
+)
+
+// the lifecycle status of funds and supplies on block chain
+const (
+	ChainStatusCreated   = "created"   // saved, never sent to block chain
+	ChainStatusSubmitted = "submitted" // sent to block chain, waiting for call back
+	ChainStatusRetried   = "retried"   // failed before, waiting to be sent again
+)
+
+// the status of block chain call back
+const (
+	CallBackApplied  = "applied"  // applied to funds or supplies
+	CallBackConflict = "conflict" // tx id conflicts with the applied one, waiting for review
+)
+
+// the schema version of publicity payload on block chain
+const (
+	PayloadSchemaLegacy  = 1 // plain json of publicity, without schema version and record type
+	PayloadSchemaVersion = 2 // canonical json with sorted keys, schema version and record type
+)
+
+// the record type of publicity payload on block chain
+const (
+	RecordFundsDonation       = "funds_donation"       // funds donated
+	RecordFundsReceived       = "funds_received"       // funds received by charity
+	RecordFundsDistributed    = "funds_distributed"    // funds distributed by charity
+	RecordSuppliesDonation    = "supplies_donation"    // supplies donated
+	RecordSuppliesReceived    = "supplies_received"    // supplies received by charity
+	RecordSuppliesDistributed = "supplies_distributed" // supplies distributed by charity
+)
+
 // the type of share
 const (
 	Prove = "prove" // donation prove of share
@@ -105,4 +198,10 @@ const (
 	RedisSet      = "SET"
 	RedisGet      = "GET"
 	RedisExpireAt = "EXPIREAT"
+	RedisEX       = "EX"
+	RedisNX       = "NX"
+	RedisDel      = "DEL"
+	RedisIncr     = "INCR"
+	RedisExpire   = "EXPIRE"
+	RedisPing     = "PING"
 )
@@ -22,6 +22,12 @@ const (
 	RepeatRegistration        = 1013 // repeat registration
 	PubToBlockChainFailure    = 1014 // publicity to block chain failure
 	BlockChainCallBackTimeout = 1015 // block chain call back timeout
+	BlockChainCallBackDenied  = 1016 // block chain call back rejected, bad signature, replayed or source not allowed
+	BlockChainTxIDConflict    = 1017 // tx id of block chain call back conflicts with the recorded one
+	BlockChainIDNotFound      = 1018 // block chain id matches no publicity
+	PubNotOnBlockChain        = 1019 // publicity not published to block chain yet
+	QueryBlockChainFailure    = 1020 // query publicity from block chain failure
+	InvalidToken              = 1021 // token missing, invalid or expired
 )
 
 // wechat error code
@@ -30,4 +36,32 @@ const (
 	WXUnboundDID      = 2101 // wechat account not bind, not auth
 	WXAlreadyboundDID = 2102 // wechat account not bind, auth fine
 	WhitelistNotExist = 2103 // user not in white list
+	WXSessionExpired  = 2104 // session key of wechat expired, login again
+	WXDecryptFailed   = 2105 // decrypt or validate the data of wechat failed
+	SoterRequired     = 2106 // soter signature required for the publicity
+	SoterVerifyFailed = 2107 // soter signature verified failed
+)
+
+// account error code
+const (
+	LoginFailed     = 2200 // account not found or password wrong
+	AccountLocked   = 2201 // account locked after too many failed logins
+	SmsCodeInvalid  = 2202 // sms code wrong or expired
+	SmsSendTooOften = 2203 // sms code requested again too soon
+	AccountConflict = 2204 // user name, phone or email used by another account
+	PasswordTooWeak = 2205 // password too short or too long
+)
+
+// kyc error code
+const (
+	KycUnderReview     = 2300 // kyc submitted before is waiting for review
+	KycAlreadyApproved = 2301 // kyc of account approved already
+	KycNotApproved     = 2302 // kyc of charity not approved
+	KycNotPending      = 2303 // kyc reviewed already
+)
+
+// charity application error code
+const (
+	ApplyUnderReview = 2400 // application submitted before is waiting for review
+	ApplyNotPending  = 2401 // application reviewed already
 )
@@ -10,6 +10,11 @@ import (
 	"crypto/rand"
 	"fmt"
 	"io"
+	"regexp"
+)
+
+var (
+	bankCardPattern = regexp.MustCompile(`^[0-9]{12,19}$`)
 )
 
 // GenerateUUID returns a UUID based on RFC 4122
@@ -38,3 +43,18 @@ func GenerateBytesUUID() []byte {
 func uuidBytesToStr(uuid []byte) string {
 	return fmt.Sprintf("%x", uuid)
 }
+
+// MaskString masks the characters of string with * except the last keep ones
+func MaskString(s string, keep int) string {
+	rs := []rune(s)
+	for i := 0; i < len(rs)-keep; i++ {
+		rs[i] = '*'
+	}
+
+	return string(rs)
+}
+
+// IsBankCardNum checks the string is a bank card num of 12 to 19 digits
+func IsBankCardNum(s string) bool {
+	return bankCardPattern.MatchString(s)
+}
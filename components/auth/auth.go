@@ -0,0 +1,82 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+import (
+	"fmt"
+
+	"github.com/csiabb/donation-service/common/log"
+)
+
+var (
+	logger = log.MustGetLogger("auth")
+)
+
+const (
+	defaultAccessTTL  = 7200       // seconds
+	defaultRefreshTTL = 30 * 86400 // seconds
+)
+
+// the kinds of token
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+)
+
+// Claims defines the claims signed in token
+type Claims struct {
+	UID      string `json:"uid"` // account id
+	UserType string `json:"typ"` // account type
+	AppID    string `json:"app"` // wechat app logged in, empty if not logged in by wechat
+	Session  string `json:"sid"` // id of the login session the token bound to
+	Kind     string `json:"knd"` // access or refresh
+	IssuedAt int64  `json:"iat"` // issued time
+	Expires  int64  `json:"exp"` // expired time
+}
+
+// Tokens defines the tokens issued on login
+type Tokens struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresIn        int64 // seconds the access token is valid
+	RefreshExpiresIn int64 // seconds the refresh token is valid
+}
+
+// BackendImpl signs the tokens with hmac-sha256
+type BackendImpl struct {
+	Config     *Config
+	accessTTL  int64
+	refreshTTL int64
+}
+
+// NewAuthBackend ...
+func NewAuthBackend(c *Config) (IAuthBackend, error) {
+	if nil == c {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	if c.Secret == "" {
+		return nil, fmt.Errorf("token secret is empty")
+	}
+
+	b := &BackendImpl{
+		Config:     c,
+		accessTTL:  int64(c.AccessTTL),
+		refreshTTL: int64(c.RefreshTTL),
+	}
+
+	if b.accessTTL <= 0 {
+		b.accessTTL = defaultAccessTTL
+	}
+
+	if b.refreshTTL <= 0 {
+		b.refreshTTL = defaultRefreshTTL
+	}
+
+	logger.Infof("token auth created, access ttl %ds, refresh ttl %ds", b.accessTTL, b.refreshTTL)
+	return b, nil
+}
@@ -0,0 +1,13 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+// IAuthBackend defines the interface to issue and validate tokens
+type IAuthBackend interface {
+	IssueTokens(uid, userType, appID, sessionID string) (*Tokens, error)
+	ParseToken(token, kind string) (*Claims, error)
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IssueTokens issues the access and refresh token bound to the account and login session
+func (b *BackendImpl) IssueTokens(uid, userType, appID, sessionID string) (*Tokens, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("uid can not be empty")
+	}
+
+	now := time.Now().Unix()
+	claims := &Claims{
+		UID:      uid,
+		UserType: userType,
+		AppID:    appID,
+		Session:  sessionID,
+		IssuedAt: now,
+	}
+
+	claims.Kind, claims.Expires = TokenAccess, now+b.accessTTL
+	access, err := b.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	claims.Kind, claims.Expires = TokenRefresh, now+b.refreshTTL
+	refresh, err := b.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresIn:        b.accessTTL,
+		RefreshExpiresIn: b.refreshTTL,
+	}, nil
+}
+
+// ParseToken validates the signature, kind and expiry of token and returns its claims
+func (b *BackendImpl) ParseToken(token, kind string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	if !hmac.Equal([]byte(b.mac(parts[0])), []byte(parts[1])) {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decode token error, %v", err)
+	}
+
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("decode token error, %v", err)
+	}
+
+	if claims.Kind != kind {
+		return nil, fmt.Errorf("expect %s token, got %s", kind, claims.Kind)
+	}
+
+	if time.Now().Unix() >= claims.Expires {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	return claims, nil
+}
+
+// SessionID returns the id of login session, the session key itself is never put in token
+func SessionID(session string) string {
+	if session == "" {
+		return ""
+	}
+
+	h := sha256.Sum256([]byte(session))
+	return hex.EncodeToString(h[:8])
+}
+
+func (b *BackendImpl) sign(claims *Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + b.mac(encoded), nil
+}
+
+func (b *BackendImpl) mac(encoded string) string {
+	mac := hmac.New(sha256.New, []byte(b.Config.Secret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
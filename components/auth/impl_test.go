@@ -0,0 +1,96 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+import (
+	"testing"
+)
+
+func TestIssueAndParseTokens(t *testing.T) {
+	b, err := NewAuthBackend(&Config{Secret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := b.IssueTokens("uid_test", "normal", "app_id", SessionID("session_key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tokens.ExpiresIn != defaultAccessTTL || tokens.RefreshExpiresIn != defaultRefreshTTL {
+		t.Errorf("expires in %d/%d, expect default ttl", tokens.ExpiresIn, tokens.RefreshExpiresIn)
+	}
+
+	claims, err := b.ParseToken(tokens.AccessToken, TokenAccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.UID != "uid_test" || claims.UserType != "normal" || claims.Session != SessionID("session_key") {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err := b.ParseToken(tokens.RefreshToken, TokenRefresh); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseTokenInvalid(t *testing.T) {
+	b, err := NewAuthBackend(&Config{Secret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewAuthBackend(&Config{Secret: "other-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := b.IssueTokens("uid_test", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.ParseToken(tokens.RefreshToken, TokenAccess); err == nil {
+		t.Error("refresh token accepted as access token")
+	}
+
+	if _, err := other.ParseToken(tokens.AccessToken, TokenAccess); err == nil {
+		t.Error("token signed by other secret accepted")
+	}
+
+	if _, err := b.ParseToken(tokens.AccessToken+"x", TokenAccess); err == nil {
+		t.Error("tampered token accepted")
+	}
+
+	if _, err := b.ParseToken("malformed", TokenAccess); err == nil {
+		t.Error("malformed token accepted")
+	}
+}
+
+func TestParseTokenExpired(t *testing.T) {
+	b := &BackendImpl{Config: &Config{Secret: "test-secret"}, accessTTL: -1, refreshTTL: -1}
+
+	tokens, err := b.IssueTokens("uid_test", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.ParseToken(tokens.AccessToken, TokenAccess); err == nil {
+		t.Error("expired token accepted")
+	}
+}
+
+func TestNewAuthBackend(t *testing.T) {
+	if _, err := NewAuthBackend(nil); err == nil {
+		t.Error("nil config accepted")
+	}
+
+	if _, err := NewAuthBackend(&Config{}); err == nil {
+		t.Error("empty secret accepted")
+	}
+}
@@ -0,0 +1,16 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+// Config defines the config of token authentication
+type Config struct {
+	Secret      string // secret to sign the tokens
+	AccessTTL   int    // seconds the access token is valid
+	RefreshTTL  int    // seconds the refresh token is valid
+	MaxFailures int    // failed logins in a row before the account is locked, 5 if 0
+	LockTTL     int    // seconds the account is locked after too many failed logins
+}
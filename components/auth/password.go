@@ -0,0 +1,57 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength  = 8
+	maxPasswordLength  = 72 // bcrypt ignores the bytes after
+	defaultMaxFailures = 5
+	defaultLockTTL     = 900 // seconds
+)
+
+// HashPassword hashes the password with bcrypt, only the hash is stored on account
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return "", fmt.Errorf("password must be %d to %d characters", minPasswordLength, maxPasswordLength)
+	}
+
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// CheckPassword checks the password against the hash, the password stored verbatim never matches
+func CheckPassword(hash, password string) bool {
+	if hash == "" || password == "" {
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// LoginLimit returns the failed logins in a row allowed and seconds the account is locked after
+func (c *Config) LoginLimit() (int64, int64) {
+	maxFailures, lockTTL := int64(c.MaxFailures), int64(c.LockTTL)
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFailures
+	}
+
+	if lockTTL <= 0 {
+		lockTTL = defaultLockTTL
+	}
+
+	return maxFailures, lockTTL
+}
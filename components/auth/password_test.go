@@ -0,0 +1,47 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package auth
+
+import (
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("Aa111111")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash == "Aa111111" || !CheckPassword(hash, "Aa111111") {
+		t.Error("password hash check failed")
+	}
+
+	if CheckPassword(hash, "Aa111112") {
+		t.Error("wrong password check failed")
+	}
+
+	// password stored verbatim before hashing
+	if CheckPassword("Aa111111", "Aa111111") {
+		t.Error("plain password check failed")
+	}
+
+	if _, err := HashPassword("short"); err == nil {
+		t.Error("short password check failed")
+	}
+}
+
+func TestLoginLimit(t *testing.T) {
+	maxFailures, lockTTL := (&Config{}).LoginLimit()
+	if maxFailures != defaultMaxFailures || lockTTL != defaultLockTTL {
+		t.Errorf("default login limit check failed, %d %d", maxFailures, lockTTL)
+	}
+
+	maxFailures, lockTTL = (&Config{MaxFailures: 3, LockTTL: 60}).LoginLimit()
+	if maxFailures != 3 || lockTTL != 60 {
+		t.Errorf("login limit check failed, %d %d", maxFailures, lockTTL)
+	}
+}
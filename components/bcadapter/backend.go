@@ -16,4 +16,6 @@ import (
 type IBCAdapter interface {
 	Register(accountID string) (*structs.RegisterResp, error)
 	Pubs(bcID string, pubs []*string) ([]*structs.PubResp, error)
+	BatchPubs(bcID string, pubs []string) ([]*structs.PubResp, error)
+	QueryPub(blockID string) (*structs.QueryPubResp, error)
 }
@@ -6,20 +6,80 @@
 
 package bcadapter
 
-import "github.com/csiabb/donation-service/common/log"
+import (
+	"net/http"
+	"time"
+
+	"github.com/csiabb/donation-service/common/log"
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/go-resty/resty/v2"
+)
 
 var (
 	logger = log.MustGetLogger("bcadapter")
 )
 
+// default value of http client
+const (
+	defaultConcurrency = 8  // requests
+	defaultTimeout     = 40 // seconds
+	defaultRetryCount  = 3  // times
+	defaultBatchSize   = 50 // publicities
+)
+
 // BackendImpl ...
 type BackendImpl struct {
-	Config *Config
+	Config      *Config
+	client      *resty.Client
+	batchClient *resty.Client // without retry
+	sem         chan struct{} // limits the concurrent requests
 }
 
 // NewBCAdapterBackend ...
 func NewBCAdapterBackend(c *Config) (*BackendImpl, error) {
 	logger.Infof("creating bc adapter service ...")
-	d := &BackendImpl{Config: c}
+
+	concurrency := c.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultConcurrency
+	}
+
+	timeout := c.Timeout
+	if timeout < 1 {
+		timeout = defaultTimeout
+	}
+
+	retryCount := c.RetryCount
+	if retryCount < 1 {
+		retryCount = defaultRetryCount
+	}
+
+	// one pooled transport shared by all requests
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        concurrency,
+		MaxIdleConnsPerHost: concurrency,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	client := resty.New().
+		SetTransport(transport).
+		SetTimeout(time.Duration(timeout)*time.Second).
+		SetRetryCount(retryCount).
+		SetHeader(rest.HeaderContentType, rest.HeaderApplicationJSON)
+
+	// batch publish is not idempotent, a retried request may put the publicities twice
+	batchClient := resty.New().
+		SetTransport(transport).
+		SetTimeout(time.Duration(timeout)*time.Second).
+		SetHeader(rest.HeaderContentType, rest.HeaderApplicationJSON)
+
+	d := &BackendImpl{
+		Config:      c,
+		client:      client,
+		batchClient: batchClient,
+		sem:         make(chan struct{}, concurrency),
+	}
 	return d, nil
 }
@@ -9,12 +9,10 @@ package bcadapter
 import (
 	"fmt"
 	"net/http"
-	"time"
+	"sync"
 
 	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/structs"
-
-	"github.com/go-resty/resty/v2"
 )
 
 const (
@@ -22,29 +20,24 @@ const (
 )
 
 const (
-	urlBlockChainAccounts    = "blockchain/accounts"
-	urlBlockChainPublicities = "blockchain/publicities"
-)
-
-const (
-	retryCount = 3  // retry times
-	timeOut    = 40 // seconds
+	urlBlockChainAccounts         = "blockchain/accounts"
+	urlBlockChainPublicities      = "blockchain/publicities"
+	urlBlockChainPublicitiesBatch = "blockchain/publicities/batch"
 )
 
 // Register defines register on block chain
 func (bc *BackendImpl) Register(accountID string) (*structs.RegisterResp, error) {
 	logger.Info("got bc adapter register request")
 
-	client := resty.New()
-
 	body := structs.RegisterReq{AccountID: accountID}
 	result := &structs.RegisterResp{}
 
-	resp, err := client.R().
-		SetHeader(rest.HeaderContentType, rest.HeaderApplicationJSON).
+	bc.sem <- struct{}{}
+	resp, err := bc.client.R().
 		SetBody(body).
 		SetResult(result).
 		Post(fmt.Sprintf("%s/api/%s/%s", bc.Config.Address, apiVersion, urlBlockChainAccounts))
+	<-bc.sem
 
 	logger.Debug("request body : %v, result : %v", body, result)
 
@@ -71,17 +64,15 @@ func (bc *BackendImpl) Pubs(bcID string, pubs []*string) ([]*structs.PubResp, er
 	results := make([]*structs.PubResp, 0)
 
 	for _, pub := range pubs {
-		client := resty.New()
-		client.SetRetryCount(retryCount).SetTimeout(timeOut * time.Second)
-
 		body := structs.PubReq{UID: bcID, Publicity: *pub}
 		result := &structs.PubResp{}
 
-		resp, err := client.R().
-			SetHeader(rest.HeaderContentType, rest.HeaderApplicationJSON).
+		bc.sem <- struct{}{}
+		resp, err := bc.client.R().
 			SetBody(body).
 			SetResult(result).
 			Post(fmt.Sprintf("%s/api/%s/%s", bc.Config.Address, apiVersion, urlBlockChainPublicities))
+		<-bc.sem
 
 		logger.Debug("request body : %v, result : %v", body, result)
 
@@ -111,3 +102,97 @@ func (bc *BackendImpl) Pubs(bcID string, pubs []*string) ([]*structs.PubResp, er
 	logger.Info("bc adapter publicity succeed")
 	return results, nil
 }
+
+// BatchPubs defines publicity data on block chain in batch, one result per publicity in order
+func (bc *BackendImpl) BatchPubs(bcID string, pubs []string) ([]*structs.PubResp, error) {
+	logger.Infof("got bc adapter batch publicity request, %d publicities", len(pubs))
+
+	batchSize := bc.Config.BatchSize
+	if batchSize < 1 {
+		batchSize = defaultBatchSize
+	}
+
+	results := make([]*structs.PubResp, len(pubs))
+	var wg sync.WaitGroup
+
+	for start := 0; start < len(pubs); start += batchSize {
+		end := start + batchSize
+		if end > len(pubs) {
+			end = len(pubs)
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+
+			bc.sem <- struct{}{}
+			batch := bc.batchPubs(bcID, pubs[start:end])
+			<-bc.sem
+
+			copy(results[start:end], batch)
+		}(start, end)
+	}
+	wg.Wait()
+
+	logger.Info("bc adapter batch publicity finished")
+	return results, nil
+}
+
+// batchPubs sends one batch request, the failure is reported on every publicity of the batch
+func (bc *BackendImpl) batchPubs(bcID string, pubs []string) []*structs.PubResp {
+	body := structs.BatchPubReq{UID: bcID, Publicities: pubs}
+	result := &structs.BatchPubResp{}
+
+	resp, err := bc.batchClient.R().
+		SetBody(body).
+		SetResult(result).
+		Post(fmt.Sprintf("%s/api/%s/%s", bc.Config.Address, apiVersion, urlBlockChainPublicitiesBatch))
+
+	var msg string
+	switch {
+	case err != nil:
+		msg = err.Error()
+	case resp.StatusCode() != http.StatusOK:
+		msg = fmt.Sprintf("code : %v, msg : %v", resp.StatusCode(), resp.String())
+	case len(result.Data) != len(pubs):
+		msg = fmt.Sprintf("got %d results of %d publicities", len(result.Data), len(pubs))
+	default:
+		return result.Data
+	}
+
+	logger.Errorf("batch pub failed, %s", msg)
+	failed := make([]*structs.PubResp, len(pubs))
+	for i := range failed {
+		failed[i] = &structs.PubResp{Code: rest.PubToBlockChainFailure, Msg: msg}
+	}
+
+	return failed
+}
+
+// QueryPub defines query the publicity on block chain by block chain id
+func (bc *BackendImpl) QueryPub(blockID string) (*structs.QueryPubResp, error) {
+	logger.Info("got bc adapter query publicity request")
+
+	result := &structs.QueryPubResp{}
+
+	bc.sem <- struct{}{}
+	resp, err := bc.client.R().
+		SetResult(result).
+		Get(fmt.Sprintf("%s/api/%s/%s/%s", bc.Config.Address, apiVersion, urlBlockChainPublicities, blockID))
+	<-bc.sem
+
+	if err != nil {
+		e := fmt.Errorf("query pub error, %v", err)
+		logger.Error(e)
+		return nil, e
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		e := fmt.Errorf("query pub failed, code : %v, msg : %v", resp.StatusCode(), resp.String())
+		logger.Error(e)
+		return nil, e
+	}
+
+	logger.Info("bc adapter query publicity succeed")
+	return result, nil
+}
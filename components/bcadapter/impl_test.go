@@ -0,0 +1,110 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bcadapter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/structs"
+)
+
+func TestBatchPubs(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		req := &structs.BatchPubReq{}
+		json.NewDecoder(r.Body).Decode(req)
+
+		resp := &structs.BatchPubResp{Data: make([]*structs.PubResp, 0)}
+		for _, v := range req.Publicities {
+			resp.Data = append(resp.Data, &structs.PubResp{Data: structs.PubRespData{ID: "block_" + v}})
+		}
+		w.Header().Set(rest.HeaderContentType, rest.HeaderApplicationJSON)
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	bc, err := NewBCAdapterBackend(&Config{Address: srv.URL, BatchSize: 2, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := bc.BatchPubs("did_test", []string{"a", "b", "c", "d", "e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 3 {
+		t.Errorf("sent %d requests, expect 3", requests)
+	}
+
+	for i, v := range []string{"a", "b", "c", "d", "e"} {
+		if results[i].Data.ID != "block_"+v {
+			t.Errorf("result %d is %s, expect block_%s", i, results[i].Data.ID, v)
+		}
+	}
+}
+
+func TestBatchPubsFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	bc, err := NewBCAdapterBackend(&Config{Address: srv.URL, RetryCount: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := bc.BatchPubs("did_test", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range results {
+		if v.Data.ID != "" || v.Msg == "" {
+			t.Errorf("unexpected result %v", v)
+		}
+	}
+}
+
+func TestBatchPubsNotRetried(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		// drop the connection as the response is lost
+		panic(http.ErrAbortHandler)
+	}))
+	defer srv.Close()
+
+	bc, err := NewBCAdapterBackend(&Config{Address: srv.URL, RetryCount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := bc.BatchPubs("did_test", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 1 {
+		t.Errorf("sent %d requests, expect 1", requests)
+	}
+
+	for _, v := range results {
+		if v.Data.ID != "" || v.Msg == "" {
+			t.Errorf("unexpected result %v", v)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package local
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/csiabb/donation-service/common/log"
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/bcadapter"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/go-resty/resty/v2"
+)
+
+var (
+	logger = log.MustGetLogger("bcadapter")
+)
+
+// DriverLocal is the driver of in-process local ledger
+const DriverLocal = "local"
+
+const (
+	chainName            = "local-ledger"
+	didPrefix            = "did:local:"
+	defaultCallBackDelay = 1  // seconds
+	callBackRetry        = 5  // times
+	callBackTimeout      = 10 // seconds
+)
+
+var genesisHash = strings.Repeat("0", 64)
+
+func init() {
+	bcadapter.RegisterDriver(DriverLocal, func(c *bcadapter.Config) (bcadapter.IBCAdapter, error) {
+		return NewLedger(c)
+	})
+}
+
+// Block defines one block of local ledger, holding one publicity
+type Block struct {
+	Height    int64  `json:"height"`    // block height
+	ID        string `json:"id"`        // block chain id
+	UID       string `json:"uid"`       // did of the one who publish
+	Publicity string `json:"publicity"` // publicity data
+	Time      int64  `json:"time"`      // block time
+	PrevHash  string `json:"prev_hash"` // hash of previous block
+	Hash      string `json:"hash"`      // hash of this block, used as tx id
+}
+
+// computeHash returns the hash chained to the previous block
+func (b *Block) computeHash() string {
+	h := sha256.New()
+	h.Write([]byte(b.PrevHash))
+	h.Write([]byte(strconv.FormatInt(b.Height, 10)))
+	h.Write([]byte(b.ID))
+	h.Write([]byte(b.UID))
+	h.Write([]byte(b.Publicity))
+	h.Write([]byte(strconv.FormatInt(b.Time, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Ledger is an append-only hash-chained ledger kept in process and persisted to file
+type Ledger struct {
+	config        *bcadapter.Config
+	callBackDelay time.Duration
+	client        *resty.Client
+
+	mu     sync.Mutex
+	file   *os.File
+	blocks []*Block
+	index  map[string]int
+}
+
+// NewLedger loads the ledger from file and verifies the hash chain, kept in memory if no file configured
+func NewLedger(c *bcadapter.Config) (*Ledger, error) {
+	if nil == c {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	logger.Infof("creating local ledger, file %s", c.LedgerFile)
+	l := &Ledger{
+		config:        c,
+		callBackDelay: time.Duration(c.CallBackDelay) * time.Second,
+		client:        resty.New().SetTimeout(callBackTimeout * time.Second),
+		blocks:        make([]*Block, 0),
+		index:         make(map[string]int),
+	}
+
+	if l.callBackDelay <= 0 {
+		l.callBackDelay = defaultCallBackDelay * time.Second
+	}
+
+	if c.LedgerFile == "" {
+		return l, nil
+	}
+
+	f, err := os.OpenFile(c.LedgerFile, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open ledger file error, %v", err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		b := &Block{}
+		if err := json.Unmarshal(scanner.Bytes(), b); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("decode ledger block error, %v", err)
+		}
+		l.index[b.ID] = len(l.blocks)
+		l.blocks = append(l.blocks, b)
+	}
+
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read ledger file error, %v", err)
+	}
+
+	if err := l.Verify(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l.file = f
+	logger.Infof("local ledger loaded, height %d", len(l.blocks))
+	return l, nil
+}
+
+// Register defines register on local ledger
+func (l *Ledger) Register(accountID string) (*structs.RegisterResp, error) {
+	return &structs.RegisterResp{
+		Code: rest.SuccCode,
+		Data: structs.RegisterRespData{ID: didPrefix + utils.GenerateUUID()},
+	}, nil
+}
+
+// Pubs appends one block per publicity and fires the call back in background
+func (l *Ledger) Pubs(bcID string, pubs []*string) ([]*structs.PubResp, error) {
+	results := make([]*structs.PubResp, 0)
+	blocks := make([]*Block, 0)
+
+	l.mu.Lock()
+	for _, pub := range pubs {
+		b, err := l.append(bcID, *pub)
+		if err != nil {
+			logger.Errorf("append block error, %v", err)
+			results = append(results, &structs.PubResp{Code: rest.PubToBlockChainFailure, Msg: err.Error()})
+			continue
+		}
+
+		blocks = append(blocks, b)
+		results = append(results, &structs.PubResp{Code: rest.SuccCode, Data: structs.PubRespData{ID: b.ID}})
+	}
+	l.mu.Unlock()
+
+	for _, b := range blocks {
+		go l.callBack(b)
+	}
+
+	return results, nil
+}
+
+// BatchPubs appends the publicities in one call, same as Pubs on local ledger
+func (l *Ledger) BatchPubs(bcID string, pubs []string) ([]*structs.PubResp, error) {
+	ptrs := make([]*string, 0)
+	for i := range pubs {
+		ptrs = append(ptrs, &pubs[i])
+	}
+
+	return l.Pubs(bcID, ptrs)
+}
+
+// Block returns the block by block chain id
+func (l *Ledger) Block(id string) (*Block, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i, ok := l.index[id]
+	if !ok {
+		return nil, false
+	}
+
+	b := *l.blocks[i]
+	return &b, true
+}
+
+// QueryPub returns the publicity by block chain id
+func (l *Ledger) QueryPub(blockID string) (*structs.QueryPubResp, error) {
+	b, ok := l.Block(blockID)
+	if !ok {
+		return nil, fmt.Errorf("block %s not found", blockID)
+	}
+
+	return &structs.QueryPubResp{
+		Code: rest.SuccCode,
+		Data: structs.QueryPubRespData{
+			ID:          b.ID,
+			UID:         b.UID,
+			Publicity:   b.Publicity,
+			TxID:        b.Hash,
+			BlockHeight: b.Height,
+			BlockTime:   b.Time,
+		},
+	}, nil
+}
+
+// Height returns the height of the last block
+func (l *Ledger) Height() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int64(len(l.blocks))
+}
+
+// Verify checks the height and hash of every block in the chain
+func (l *Ledger) Verify() error {
+	prevHash := genesisHash
+	for i, b := range l.blocks {
+		if b.Height != int64(i+1) {
+			return fmt.Errorf("ledger broken at height %d, got height %d", i+1, b.Height)
+		}
+
+		if b.PrevHash != prevHash {
+			return fmt.Errorf("ledger broken at height %d, previous hash mismatch", b.Height)
+		}
+
+		if b.computeHash() != b.Hash {
+			return fmt.Errorf("ledger broken at height %d, hash mismatch", b.Height)
+		}
+		prevHash = b.Hash
+	}
+
+	return nil
+}
+
+// append adds a block to the chain, the caller must hold the lock
+func (l *Ledger) append(uid, publicity string) (*Block, error) {
+	prevHash := genesisHash
+	if n := len(l.blocks); n > 0 {
+		prevHash = l.blocks[n-1].Hash
+	}
+
+	b := &Block{
+		Height:    int64(len(l.blocks) + 1),
+		ID:        utils.GenerateUUID(),
+		UID:       uid,
+		Publicity: publicity,
+		Time:      time.Now().Unix(),
+		PrevHash:  prevHash,
+	}
+	b.Hash = b.computeHash()
+
+	if l.file != nil {
+		line, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return nil, err
+		}
+
+		if err := l.file.Sync(); err != nil {
+			return nil, err
+		}
+	}
+
+	l.index[b.ID] = len(l.blocks)
+	l.blocks = append(l.blocks, b)
+	return b, nil
+}
+
+// callBack notifies the service that the block is on chain, retried until accepted
+func (l *Ledger) callBack(b *Block) {
+	if l.config.CallBackURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(&structs.BCCBReq{
+		BlockChain: chainName,
+		ID:         b.ID,
+		BlockNum:   b.Height,
+		TxID:       b.Hash,
+		Time:       b.Time,
+	})
+	if err != nil {
+		logger.Errorf("marshal call back error, %v", err)
+		return
+	}
+
+	for i := 0; i < callBackRetry; i++ {
+		// wait for the publisher to record the block chain id
+		time.Sleep(l.callBackDelay)
+
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		nonce := utils.GenerateUUID()
+		req := l.client.R().
+			SetHeader(rest.HeaderContentType, rest.HeaderApplicationJSON).
+			SetHeader(bcadapter.HeaderTimestamp, timestamp).
+			SetHeader(bcadapter.HeaderNonce, nonce).
+			SetBody(body)
+
+		if l.config.CallBackSecret != "" {
+			req.SetHeader(bcadapter.HeaderSignature, bcadapter.Sign(l.config.CallBackSecret, timestamp, nonce, body))
+		}
+
+		resp, err := req.Post(l.config.CallBackURL)
+		if err != nil {
+			logger.Errorf("call back block %s error, %v", b.ID, err)
+			continue
+		}
+
+		if resp.StatusCode() == http.StatusOK {
+			logger.Debugf("call back block %s succeed", b.ID)
+			return
+		}
+		logger.Errorf("call back block %s failed, code : %v, msg : %v", b.ID, resp.StatusCode(), resp.String())
+	}
+}
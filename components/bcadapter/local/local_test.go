@@ -0,0 +1,137 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package local
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/components/bcadapter"
+	"github.com/csiabb/donation-service/structs"
+)
+
+func publicities(data ...string) []*string {
+	pubs := make([]*string, 0)
+	for i := range data {
+		pubs = append(pubs, &data[i])
+	}
+	return pubs
+}
+
+func TestLedgerPersisted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &bcadapter.Config{Driver: DriverLocal, LedgerFile: filepath.Join(dir, "ledger.jsonl")}
+	adapter, err := bcadapter.NewBCAdapter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := adapter.Pubs("did:local:test", publicities(`{"amount":"100"}`, `{"amount":"200"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Data.ID == "" || results[1].Data.ID == "" {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	// reload from file
+	l, err := NewLedger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Height() != 2 {
+		t.Errorf("height %d, expect 2", l.Height())
+	}
+
+	b, ok := l.Block(results[1].Data.ID)
+	if !ok || b.Height != 2 || b.Publicity != `{"amount":"200"}` {
+		t.Errorf("unexpected block %v", b)
+	}
+
+	pub, err := l.QueryPub(results[1].Data.ID)
+	if err != nil || pub.Data.TxID != b.Hash || pub.Data.Publicity != b.Publicity {
+		t.Errorf("unexpected publicity %v, %v", pub, err)
+	}
+}
+
+func TestLedgerTampered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &bcadapter.Config{LedgerFile: filepath.Join(dir, "ledger.jsonl")}
+	l, err := NewLedger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := l.Pubs("did:local:test", publicities(`{"amount":"100"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := l.Block(l.blocks[0].ID)
+	b.Publicity = `{"amount":"999"}`
+	line, _ := json.Marshal(b)
+	if err := ioutil.WriteFile(cfg.LedgerFile, append(line, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewLedger(cfg); err == nil {
+		t.Error("tampered ledger check failed")
+	}
+}
+
+func TestLedgerCallBack(t *testing.T) {
+	got := make(chan *structs.BCCBReq, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if !bcadapter.VerifySign("test-secret", r.Header.Get(bcadapter.HeaderTimestamp), r.Header.Get(bcadapter.HeaderNonce), body, r.Header.Get(bcadapter.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		req := &structs.BCCBReq{}
+		json.Unmarshal(body, req)
+		got <- req
+	}))
+	defer srv.Close()
+
+	l, err := NewLedger(&bcadapter.Config{CallBackURL: srv.URL, CallBackSecret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.callBackDelay = 10 * time.Millisecond
+
+	results, err := l.Pubs("did:local:test", publicities(`{"amount":"100"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case req := <-got:
+		if req.ID != results[0].Data.ID || req.BlockNum != 1 || req.TxID == "" {
+			t.Errorf("unexpected call back %v", req)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("call back not fired")
+	}
+}
@@ -33,6 +33,21 @@ func (m *MockIBCAdapter) EXPECT() *MockIBCAdapterMockRecorder {
 	return m.recorder
 }
 
+// BatchPubs mocks base method
+func (m *MockIBCAdapter) BatchPubs(arg0 string, arg1 []string) ([]*structs.PubResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchPubs", arg0, arg1)
+	ret0, _ := ret[0].([]*structs.PubResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchPubs indicates an expected call of BatchPubs
+func (mr *MockIBCAdapterMockRecorder) BatchPubs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchPubs", reflect.TypeOf((*MockIBCAdapter)(nil).BatchPubs), arg0, arg1)
+}
+
 // Pubs mocks base method
 func (m *MockIBCAdapter) Pubs(arg0 string, arg1 []*string) ([]*structs.PubResp, error) {
 	m.ctrl.T.Helper()
@@ -48,6 +63,21 @@ func (mr *MockIBCAdapterMockRecorder) Pubs(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pubs", reflect.TypeOf((*MockIBCAdapter)(nil).Pubs), arg0, arg1)
 }
 
+// QueryPub mocks base method
+func (m *MockIBCAdapter) QueryPub(arg0 string) (*structs.QueryPubResp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryPub", arg0)
+	ret0, _ := ret[0].(*structs.QueryPubResp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryPub indicates an expected call of QueryPub
+func (mr *MockIBCAdapterMockRecorder) QueryPub(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryPub", reflect.TypeOf((*MockIBCAdapter)(nil).QueryPub), arg0)
+}
+
 // Register mocks base method
 func (m *MockIBCAdapter) Register(arg0 string) (*structs.RegisterResp, error) {
 	m.ctrl.T.Helper()
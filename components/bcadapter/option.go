@@ -8,5 +8,16 @@ package bcadapter
 
 // Config defines the config of block chain adapter
 type Config struct {
-	Address string
+	Driver           string   // ledger driver, http or local
+	Address          string   // address of remote adapter, used by http driver
+	Concurrency      int      // max concurrent requests to remote adapter
+	Timeout          int      // seconds of one request to remote adapter
+	RetryCount       int      // retry times of one request to remote adapter
+	BatchSize        int      // max publicities in one batch request
+	CallBackSecret   string   // shared secret to sign the call back
+	CallBackTTL      int      // seconds the signed call back is valid
+	CallBackAllowIPs []string // ip or cidr allowed to call back, all allowed if empty
+	LedgerFile       string   // file of local ledger, used by local driver
+	CallBackURL      string   // call back url of local ledger, used by local driver
+	CallBackDelay    int      // seconds before local ledger fires the call back
 }
@@ -0,0 +1,67 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bcadapter
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DriverHTTP is the driver of remote block chain adapter over http
+const DriverHTTP = "http"
+
+// Driver creates the block chain adapter from config
+type Driver func(c *Config) (IBCAdapter, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+func init() {
+	RegisterDriver(DriverHTTP, func(c *Config) (IBCAdapter, error) {
+		return NewBCAdapterBackend(c)
+	})
+}
+
+// RegisterDriver makes a ledger driver available by the name, panics if registered twice
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("bcadapter: register driver is nil")
+	}
+
+	if _, dup := drivers[name]; dup {
+		panic("bcadapter: register driver twice for " + name)
+	}
+	drivers[name] = driver
+}
+
+// NewBCAdapter creates the block chain adapter by the driver of config, http by default
+func NewBCAdapter(c *Config) (IBCAdapter, error) {
+	if nil == c {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	name := c.Driver
+	if name == "" {
+		name = DriverHTTP
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown block chain adapter driver %s", name)
+	}
+
+	logger.Infof("creating block chain adapter by driver %s", name)
+	return driver(c)
+}
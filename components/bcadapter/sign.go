@@ -0,0 +1,37 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bcadapter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// the headers of signed call back
+const (
+	HeaderTimestamp = "X-BC-Timestamp"
+	HeaderNonce     = "X-BC-Nonce"
+	HeaderSignature = "X-BC-Signature"
+)
+
+// Sign returns the hex encoded hmac-sha256 of the call back
+func Sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySign checks the signature of the call back
+func VerifySign(secret, timestamp, nonce string, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -46,6 +46,7 @@ type Client struct {
 	Font        *freetype.Context
 	FontType    *truetype.Font
 	WXClient    wx.IWXClient
+	WXTokens    wx.ITokenManager
 }
 
 // Init initializes a new background image
@@ -96,9 +97,13 @@ func (c *Client) Init() error {
 
 // CreateWXQrCode create a wx qr code
 func (c *Client) CreateWXQrCode(appID string, secret string, scene string) (img image.Image, err error) {
-	token, err := c.WXClient.GetAccessToken(appID, secret)
-	qrCode, err := c.WXClient.GetWXQrCode(token, scene)
-	return qrCode, err
+	token, err := c.WXTokens.AccessToken(appID, secret)
+	if err != nil {
+		logger.Errorf("failed to get access token : %s", err)
+		return nil, err
+	}
+
+	return c.WXClient.GetWXQrCode(token, scene)
 }
 
 // DrawText define string drawing
@@ -161,6 +166,9 @@ func (c *Client) CreateDonationImage(content []string, appID string, secret stri
 	if isShare {
 		var qrCodeImg image.Image
 		qrCodeImg, err = c.CreateWXQrCode(appID, secret, scene)
+		if err != nil {
+			return nil, err
+		}
 		draw.Draw(c.Bg, qrCodeImg.Bounds().Add(image.Pt(100, 720)), qrCodeImg, image.Point{X: 0, Y: 0}, draw.Over)
 		err = c.DrawText(color2, rest.QRContent, freetype.Pt(235, 828), 22)
 	}
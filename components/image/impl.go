@@ -14,9 +14,9 @@ type BackendImpl struct {
 }
 
 // NewImageBackend ...
-func NewImageBackend(cfg *Config, wxClient wx.IWXClient) (*BackendImpl, error) {
+func NewImageBackend(cfg *Config, wxClient wx.IWXClient, wxTokens wx.ITokenManager) (*BackendImpl, error) {
 	logger.Infof("creating db backend ...")
-	d := &BackendImpl{Client: Client{ImageConfig: cfg, WXClient: wxClient}}
+	d := &BackendImpl{Client: Client{ImageConfig: cfg, WXClient: wxClient, WXTokens: wxTokens}}
 
 	return d, nil
 }
@@ -0,0 +1,17 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package policy
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// IPolicy defines the interface to authorize publicity by user type
+type IPolicy interface {
+	Authorize(userType, pubType string) (string, error)
+	RequireSoter(userType, pubType string, amount decimal.Decimal) bool
+}
@@ -0,0 +1,19 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package policy
+
+// Config defines the config of role based authorization
+type Config struct {
+	Rules          map[string][]Rule // user type to the publicities it is allowed to publish, default rules if empty
+	SoterThreshold float64           // distributed funds of charity above the amount require soter signature, disabled if 0
+}
+
+// Rule defines the publicity allowed for a user type
+type Rule struct {
+	PubType string // publicity type
+	Target  string // whom the publicity may target, any, self or charity
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/csiabb/donation-service/common/log"
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	logger = log.MustGetLogger("policy")
+
+	// ErrPermissionDenied is returned when the publicity is not allowed for the user type
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// the rules of publicity target
+const (
+	TargetAny     = "any"     // any user
+	TargetSelf    = "self"    // the one who publishes
+	TargetCharity = "charity" // account of charity
+)
+
+// defaultRules donors donate to and receive from charities, charities distribute what
+// they received, admin is allowed all
+var defaultRules = map[string][]Rule{
+	rest.UserTypeNormal: {
+		{PubType: rest.PubTypeDonate, Target: TargetCharity},
+		{PubType: rest.PubTypeReceive, Target: TargetCharity},
+	},
+	rest.UserTypeOrg: {
+		{PubType: rest.PubTypeDonate, Target: TargetCharity},
+		{PubType: rest.PubTypeReceive, Target: TargetCharity},
+	},
+	rest.UserTypeOrgCharity: {
+		{PubType: rest.PubTypeDonate, Target: TargetCharity},
+		{PubType: rest.PubTypeDistribute, Target: TargetSelf},
+	},
+	rest.UserTypeAdmin: {
+		{PubType: rest.PubTypeDonate, Target: TargetAny},
+		{PubType: rest.PubTypeReceive, Target: TargetAny},
+		{PubType: rest.PubTypeDistribute, Target: TargetAny},
+	},
+}
+
+// Impl authorizes publicity with the rules of user type
+type Impl struct {
+	rules          map[string]map[string]string // user type to publicity type to target
+	soterThreshold decimal.Decimal              // amount of distributed funds requiring soter signature
+}
+
+// NewPolicy ...
+func NewPolicy(c *Config) (IPolicy, error) {
+	if nil == c {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	rules := c.Rules
+	if len(rules) == 0 {
+		logger.Info("policy rules not configured, use default rules")
+		rules = defaultRules
+	}
+
+	if c.SoterThreshold < 0 {
+		return nil, fmt.Errorf("invalid soter threshold %v in policy", c.SoterThreshold)
+	}
+
+	p := &Impl{
+		rules:          make(map[string]map[string]string),
+		soterThreshold: decimal.NewFromFloat(c.SoterThreshold),
+	}
+	for userType, v := range rules {
+		if !isUserType(userType) {
+			return nil, fmt.Errorf("invalid user type %s in policy", userType)
+		}
+
+		p.rules[userType] = make(map[string]string)
+		for _, r := range v {
+			if !isPubType(r.PubType) {
+				return nil, fmt.Errorf("invalid pub type %s of user type %s in policy", r.PubType, userType)
+			}
+
+			if r.Target != TargetAny && r.Target != TargetSelf && r.Target != TargetCharity {
+				return nil, fmt.Errorf("invalid target %s of user type %s in policy", r.Target, userType)
+			}
+
+			p.rules[userType][r.PubType] = r.Target
+		}
+	}
+
+	return p, nil
+}
+
+// Authorize returns the target rule of the publicity type allowed for user type
+func (p *Impl) Authorize(userType, pubType string) (string, error) {
+	target, ok := p.rules[userType][pubType]
+	if !ok {
+		return "", ErrPermissionDenied
+	}
+
+	return target, nil
+}
+
+// RequireSoter returns whether the soter signature is required, only the charity distributing
+// funds above the threshold is required
+func (p *Impl) RequireSoter(userType, pubType string, amount decimal.Decimal) bool {
+	if !p.soterThreshold.IsPositive() {
+		return false
+	}
+
+	return userType == rest.UserTypeOrgCharity && pubType == rest.PubTypeDistribute && amount.GreaterThan(p.soterThreshold)
+}
+
+func isUserType(userType string) bool {
+	switch userType {
+	case rest.UserTypeNormal, rest.UserTypeOrg, rest.UserTypeAdmin, rest.UserTypeOrgCharity:
+		return true
+	default:
+		return false
+	}
+}
+
+func isPubType(pubType string) bool {
+	switch pubType {
+	case rest.PubTypeDonate, rest.PubTypeReceive, rest.PubTypeDistribute:
+		return true
+	default:
+		return false
+	}
+}
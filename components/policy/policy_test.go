@@ -0,0 +1,127 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDefaultRules(t *testing.T) {
+	p, err := NewPolicy(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		userType string
+		pubType  string
+		target   string
+		allowed  bool
+	}{
+		{rest.UserTypeNormal, rest.PubTypeDonate, TargetCharity, true},
+		{rest.UserTypeNormal, rest.PubTypeReceive, TargetCharity, true},
+		{rest.UserTypeNormal, rest.PubTypeDistribute, "", false},
+		{rest.UserTypeOrg, rest.PubTypeDonate, TargetCharity, true},
+		{rest.UserTypeOrg, rest.PubTypeReceive, TargetCharity, true},
+		{rest.UserTypeOrg, rest.PubTypeDistribute, "", false},
+		{rest.UserTypeOrgCharity, rest.PubTypeDonate, TargetCharity, true},
+		{rest.UserTypeOrgCharity, rest.PubTypeReceive, "", false},
+		{rest.UserTypeOrgCharity, rest.PubTypeDistribute, TargetSelf, true},
+		{rest.UserTypeAdmin, rest.PubTypeDonate, TargetAny, true},
+		{rest.UserTypeAdmin, rest.PubTypeReceive, TargetAny, true},
+		{rest.UserTypeAdmin, rest.PubTypeDistribute, TargetAny, true},
+		{"unknown", rest.PubTypeDonate, "", false},
+	}
+
+	for _, v := range cases {
+		target, err := p.Authorize(v.userType, v.pubType)
+		if v.allowed != (err == nil) {
+			t.Errorf("%s %s allowed %v, got %v", v.userType, v.pubType, v.allowed, err)
+			continue
+		}
+
+		if target != v.target {
+			t.Errorf("%s %s target %s, got %s", v.userType, v.pubType, v.target, target)
+		}
+	}
+}
+
+func TestConfiguredRules(t *testing.T) {
+	p, err := NewPolicy(&Config{Rules: map[string][]Rule{
+		rest.UserTypeNormal: {{PubType: rest.PubTypeDonate, Target: TargetAny}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if target, err := p.Authorize(rest.UserTypeNormal, rest.PubTypeDonate); err != nil || target != TargetAny {
+		t.Errorf("configured rule not applied, %s %v", target, err)
+	}
+
+	// configured rules replace the default rules
+	if _, err := p.Authorize(rest.UserTypeAdmin, rest.PubTypeDonate); err != ErrPermissionDenied {
+		t.Errorf("expect permission denied, got %v", err)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	invalid := []map[string][]Rule{
+		{"unknown": {{PubType: rest.PubTypeDonate, Target: TargetAny}}},
+		{rest.UserTypeNormal: {{PubType: "unknown", Target: TargetAny}}},
+		{rest.UserTypeNormal: {{PubType: rest.PubTypeDonate, Target: "unknown"}}},
+	}
+
+	for _, v := range invalid {
+		if _, err := NewPolicy(&Config{Rules: v}); err == nil {
+			t.Errorf("invalid rules %v accepted", v)
+		}
+	}
+
+	if _, err := NewPolicy(nil); err == nil {
+		t.Error("nil config accepted")
+	}
+}
+
+func TestRequireSoter(t *testing.T) {
+	p, err := NewPolicy(&Config{SoterThreshold: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		userType string
+		pubType  string
+		amount   int64
+		required bool
+	}{
+		{rest.UserTypeOrgCharity, rest.PubTypeDistribute, 10001, true},
+		{rest.UserTypeOrgCharity, rest.PubTypeDistribute, 10000, false},
+		{rest.UserTypeOrgCharity, rest.PubTypeDonate, 10001, false},
+		{rest.UserTypeNormal, rest.PubTypeDonate, 10001, false},
+		{rest.UserTypeAdmin, rest.PubTypeDistribute, 10001, false},
+	}
+
+	for _, v := range cases {
+		if p.RequireSoter(v.userType, v.pubType, decimal.NewFromInt(v.amount)) != v.required {
+			t.Errorf("%s %s %d require soter %v", v.userType, v.pubType, v.amount, v.required)
+		}
+	}
+
+	// disabled without threshold
+	p, err = NewPolicy(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.RequireSoter(rest.UserTypeOrgCharity, rest.PubTypeDistribute, decimal.NewFromInt(10001)) {
+		t.Error("soter required without threshold")
+	}
+}
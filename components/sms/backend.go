@@ -0,0 +1,12 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package sms
+
+// ISender defines the interface to send sms code to phone
+type ISender interface {
+	Send(phone, code string) error
+}
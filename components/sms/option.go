@@ -0,0 +1,14 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package sms
+
+// Config defines the config of sms sender
+type Config struct {
+	Driver   string // sms driver, log by default
+	CodeTTL  int    // seconds the sms code is valid
+	Interval int    // seconds before another sms code is sent to the same phone
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package sms
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/csiabb/donation-service/common/log"
+)
+
+var (
+	logger = log.MustGetLogger("sms")
+)
+
+// DriverLog is the driver only logging the sms code, for development and test
+const DriverLog = "log"
+
+const (
+	defaultCodeTTL  = 300 // seconds
+	defaultInterval = 60  // seconds
+)
+
+// Driver creates the sms sender from config
+type Driver func(c *Config) (ISender, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Driver)
+)
+
+func init() {
+	RegisterDriver(DriverLog, func(c *Config) (ISender, error) {
+		return &LogSender{}, nil
+	})
+}
+
+// RegisterDriver makes a sms driver available by the name, panics if registered twice
+func RegisterDriver(name string, driver Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if driver == nil {
+		panic("sms: register driver is nil")
+	}
+
+	if _, dup := drivers[name]; dup {
+		panic("sms: register driver twice for " + name)
+	}
+	drivers[name] = driver
+}
+
+// NewSender creates the sms sender by the driver of config, log by default
+func NewSender(c *Config) (ISender, error) {
+	if nil == c {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	name := c.Driver
+	if name == "" {
+		name = DriverLog
+	}
+
+	driversMu.RLock()
+	driver, ok := drivers[name]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown sms driver %s", name)
+	}
+
+	logger.Infof("creating sms sender by driver %s", name)
+	return driver(c)
+}
+
+// CodeLimit returns seconds the sms code is valid and before another code is sent to the same phone
+func (c *Config) CodeLimit() (int64, int64) {
+	ttl, interval := int64(c.CodeTTL), int64(c.Interval)
+	if ttl <= 0 {
+		ttl = defaultCodeTTL
+	}
+
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	return ttl, interval
+}
+
+// LogSender logs the sms code instead of sending it
+type LogSender struct{}
+
+// Send logs the sms code of phone
+func (s *LogSender) Send(phone, code string) error {
+	logger.Warningf("sms code %s of phone %s is not sent, only logged", code, phone)
+	return nil
+}
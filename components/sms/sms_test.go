@@ -0,0 +1,30 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package sms
+
+import (
+	"testing"
+)
+
+func TestNewSender(t *testing.T) {
+	s, err := NewSender(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.(*LogSender); !ok {
+		t.Errorf("default driver check failed, %T", s)
+	}
+
+	if err := s.Send("18518265711", "123456"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewSender(&Config{Driver: "unknown"}); err == nil {
+		t.Error("unknown driver check failed")
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package fakewx implements a local stand-in of the wechat api for integration tests and
+// development, the openids, session keys, access tokens and qr codes are deterministic
+package fakewx
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+
+	"github.com/op/go-logging"
+)
+
+var logger = logging.MustGetLogger("fakewx")
+
+const (
+	tokenExpiresIn = 7200 // seconds
+	qrCodeSize     = 280  // pixels
+	qrCodeModules  = 28   // modules of each side of qr code
+)
+
+// error codes of wechat api
+const (
+	errInvalidCredential = 40001 // access token invalid
+	errInvalidAppID      = 40013 // app id or secret invalid
+	errInvalidCode       = 40029 // js code invalid
+)
+
+// Server serves the wechat api of the app
+type Server struct {
+	appID  string
+	secret string
+	mux    *http.ServeMux
+}
+
+type response struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// NewServer returns the wechat api stand-in of the app
+func NewServer(appID, secret string) *Server {
+	s := &Server{appID: appID, secret: secret, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/sns/jscode2session", s.code2Session)
+	s.mux.HandleFunc("/cgi-bin/token", s.token)
+	s.mux.HandleFunc("/wxa/getwxacodeunlimit", s.wxaCode)
+	s.mux.HandleFunc("/cgi-bin/soter/verify_signature", s.soterVerify)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger.Debugf("%s %s", r.Method, r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+// OpenID returns the openid of the user logged in with js code
+func OpenID(appID, code string) string {
+	return "o" + digest(appID, "openid", code)[:27]
+}
+
+// UnionID returns the unionid of the user logged in with js code
+func UnionID(appID, code string) string {
+	return "u" + digest(appID, "unionid", code)[:27]
+}
+
+// SessionKey returns the session key of the user logged in with js code
+func SessionKey(appID, code string) string {
+	h := sha256.Sum256([]byte(appID + "/session_key/" + code))
+	return base64.StdEncoding.EncodeToString(h[:16])
+}
+
+// AccessToken returns the access token of the app
+func AccessToken(appID string) string {
+	return "token_" + digest(appID, "access_token", "")[:32]
+}
+
+// SoterSignature returns the valid soter signature of json string signed by the user
+func SoterSignature(openID, jsonString string) string {
+	return digest(openID, "soter", jsonString)
+}
+
+func digest(parts ...string) string {
+	h := sha256.New()
+	for _, v := range parts {
+		h.Write([]byte(v + "/"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Server) code2Session(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("appid") != s.appID || q.Get("secret") != s.secret {
+		writeJSON(w, &response{ErrCode: errInvalidAppID, ErrMsg: "invalid appid"})
+		return
+	}
+
+	code := q.Get("js_code")
+	if code == "" {
+		writeJSON(w, &response{ErrCode: errInvalidCode, ErrMsg: "invalid code"})
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"openid":      OpenID(s.appID, code),
+		"session_key": SessionKey(s.appID, code),
+		"unionid":     UnionID(s.appID, code),
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("appid") != s.appID || q.Get("secret") != s.secret {
+		writeJSON(w, &response{ErrCode: errInvalidAppID, ErrMsg: "invalid appid"})
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": AccessToken(s.appID),
+		"expires_in":   tokenExpiresIn,
+	})
+}
+
+func (s *Server) wxaCode(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("access_token") != AccessToken(s.appID) {
+		writeJSON(w, &response{ErrCode: errInvalidCredential, ErrMsg: "invalid credential, access_token is invalid"})
+		return
+	}
+
+	req := &struct {
+		Scene string `json:"scene"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeJSON(w, &response{ErrCode: -1, ErrMsg: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, qrCode(s.appID, req.Scene))
+}
+
+func (s *Server) soterVerify(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("access_token") != AccessToken(s.appID) {
+		writeJSON(w, &response{ErrCode: errInvalidCredential, ErrMsg: "invalid credential, access_token is invalid"})
+		return
+	}
+
+	req := &struct {
+		OpenID        string `json:"openid"`
+		JSONString    string `json:"json_string"`
+		JSONSignature string `json:"json_signature"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeJSON(w, &response{ErrCode: -1, ErrMsg: err.Error()})
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"errcode": 0,
+		"errmsg":  "ok",
+		"is_ok":   req.OpenID != "" && req.JSONSignature == SoterSignature(req.OpenID, req.JSONString),
+	})
+}
+
+// qrCode draws the modules of qr code from the digest of scene, not a scannable qr code
+func qrCode(appID, scene string) image.Image {
+	bits := digest(appID, "qrcode", scene)
+	img := image.NewGray(image.Rect(0, 0, qrCodeSize, qrCodeSize))
+	size := qrCodeSize / qrCodeModules
+	for y := 0; y < qrCodeSize; y++ {
+		for x := 0; x < qrCodeSize; x++ {
+			n := (y/size)*qrCodeModules + x/size
+			c := color.Gray{Y: 255}
+			if bits[n%len(bits)]&(1<<uint(n%4)) != 0 {
+				c = color.Gray{Y: 0}
+			}
+			img.SetGray(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
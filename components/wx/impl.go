@@ -17,6 +17,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/csiabb/donation-service/components/wx/utils"
 	"github.com/csiabb/donation-service/structs"
@@ -40,11 +42,13 @@ const (
 	checkFingerURL = "%s/cgi-bin/soter/verify_signature?access_token=%s"
 	accessTokenURL = "%s/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s"
 	wxacodeURL     = "%s/wxa/getwxacodeunlimit?access_token=%s"
+	requestTimeout = 10 * time.Second
 )
 
 // NewWXBackend returns a handle to the agent endpoints
 func NewWXBackend(config *ClientCfg) (IWXClient, error) {
 	return &Client{c: config, HTTPClient: &http.Client{
+		Timeout: requestTimeout,
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true,
@@ -53,6 +57,14 @@ func NewWXBackend(config *ClientCfg) (IWXClient, error) {
 	}}, nil
 }
 
+func (c *Client) address() string {
+	if c.c == nil || c.c.Address == "" {
+		return wxAddress
+	}
+
+	return strings.TrimSuffix(c.c.Address, "/")
+}
+
 // WXLogin get the login data
 func (c *Client) WXLogin(appID string, secret string, code string) (lres LoginResponse, err error) {
 	if code == "" {
@@ -60,12 +72,12 @@ func (c *Client) WXLogin(appID string, secret string, code string) (lres LoginRe
 		return
 	}
 
-	api, err := code2url(appID, secret, code, wxAddress)
+	api, err := code2url(appID, secret, code, c.address())
 	if err != nil {
 		return
 	}
 
-	res, err := http.Get(api)
+	res, err := c.HTTPClient.Get(api)
 	if err != nil {
 		return
 	}
@@ -141,7 +153,7 @@ func code2url(appID, secret, code, baseURL string) (string, error) {
 
 // CheckFinger ...
 func (c *Client) CheckFinger(finger structs.FingerRequest, accessToken string) (*structs.FingerResponse, error) {
-	wxURL := fmt.Sprintf(checkFingerURL, wxAddress, accessToken)
+	wxURL := fmt.Sprintf(checkFingerURL, c.address(), accessToken)
 	pushByte, err := json.Marshal(finger)
 	body := bytes.NewBuffer(pushByte)
 	response, err := c.HTTPClient.Post(wxURL, "application/json;charset=utf-8", body)
@@ -149,12 +161,12 @@ func (c *Client) CheckFinger(finger structs.FingerRequest, accessToken string) (
 		logger.Errorf("Failed to push message: %v", err)
 		return nil, err
 	}
+	defer response.Body.Close()
 	if response.StatusCode != http.StatusOK {
 		e := fmt.Errorf("Get response status is not ok")
 		logger.Error(e)
-		return nil, err
+		return nil, e
 	}
-	defer response.Body.Close()
 	res, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		logger.Errorf("Failed to read response body: %v", err)
@@ -169,37 +181,42 @@ func (c *Client) CheckFinger(finger structs.FingerRequest, accessToken string) (
 	return &s, nil
 }
 
-// GetAccessToken ...
-func (c *Client) GetAccessToken(appID string, secret string) (string, error) {
-	wxURL := fmt.Sprintf(accessTokenURL, wxAddress, appID, secret)
-	logger.Debugf("Get access token url: %v", wxURL)
-	response, err := http.Get(wxURL)
+// GetAccessToken returns the access token of wechat app and seconds it expires in
+func (c *Client) GetAccessToken(appID string, secret string) (string, int64, error) {
+	wxURL := fmt.Sprintf(accessTokenURL, c.address(), appID, secret)
+	response, err := c.HTTPClient.Get(wxURL)
 	if err != nil {
 		logger.Errorf("Failed to do get access token: %v", err)
-		return "", err
+		return "", 0, err
 	}
+	defer response.Body.Close()
 	if response.StatusCode != http.StatusOK {
-		logger.Errorf("Get response status is not ok")
-		return "", err
+		e := fmt.Errorf("Get response status is not ok")
+		logger.Error(e)
+		return "", 0, e
 	}
-	defer response.Body.Close()
 	res, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		logger.Errorf("Failed to read response body: %v", err)
-		return "", err
+		return "", 0, err
 	}
-	body := structs.TokenResponse{}
+	body := accessTokenResponse{}
 	err = json.Unmarshal(res, &body)
 	if err != nil {
 		logger.Errorf("Failed to UnMarshal response body: %v", err)
-		return "", err
+		return "", 0, err
+	}
+	if body.ErrCode != 0 || body.AccessToken == "" {
+		e := fmt.Errorf("get access token failed, %d %s", body.ErrCode, body.ErrMsg)
+		logger.Error(e)
+		return "", 0, e
 	}
-	return body.AccessToken, nil
+	return body.AccessToken, body.ExpiresIn, nil
 }
 
 // GetWXQrCode get WeChat qr code
 func (c *Client) GetWXQrCode(token string, scene string) (image.Image, error) {
-	wxURL := fmt.Sprintf(wxacodeURL, wxAddress, token)
+	wxURL := fmt.Sprintf(wxacodeURL, c.address(), token)
 	logger.Debugf("Get wx code url: %v", wxURL)
 	var req = &GetWXQRCodeRequest{
 		Scene:     scene,
@@ -211,17 +228,18 @@ func (c *Client) GetWXQrCode(token string, scene string) (image.Image, error) {
 	pushByte, err := json.Marshal(req)
 	body := bytes.NewBuffer(pushByte)
 
-	response, err := http.Post(wxURL, "application/json", body)
+	response, err := c.HTTPClient.Post(wxURL, "application/json", body)
 	if err != nil {
 		logger.Errorf("Failed to do get wx qr code : %v", err)
 		return nil, err
 	}
 
+	defer response.Body.Close()
 	if response.StatusCode != http.StatusOK {
-		logger.Errorf("Get response status is not ok")
-		return nil, err
+		e := fmt.Errorf("Get response status is not ok")
+		logger.Error(e)
+		return nil, e
 	}
-	defer response.Body.Close()
 
 	qrCode, err := png.Decode(response.Body)
 	if err != nil {
@@ -0,0 +1,112 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package wx
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/csiabb/donation-service/components/wx/fakewx"
+	"github.com/csiabb/donation-service/structs"
+)
+
+func initFakeWX(t *testing.T) (*httptest.Server, IWXClient) {
+	srv := httptest.NewServer(fakewx.NewServer("app_id", "secret"))
+
+	client, err := NewWXBackend(&ClientCfg{AppID: "app_id", Secret: "secret", Address: srv.URL + "/"})
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+
+	return srv, client
+}
+
+func TestWXLogin(t *testing.T) {
+	srv, client := initFakeWX(t)
+	defer srv.Close()
+
+	lr, err := client.WXLogin("app_id", "secret", "js_code")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lr.OpenID != fakewx.OpenID("app_id", "js_code") || lr.SessionKey != fakewx.SessionKey("app_id", "js_code") {
+		t.Errorf("unexpected login response %+v", lr)
+	}
+
+	if _, err := client.WXLogin("app_id", "wrong_secret", "js_code"); err == nil {
+		t.Error("login with wrong secret succeed")
+	}
+}
+
+func TestGetAccessToken(t *testing.T) {
+	srv, client := initFakeWX(t)
+	defer srv.Close()
+
+	token, expiresIn, err := client.GetAccessToken("app_id", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != fakewx.AccessToken("app_id") || expiresIn != 7200 {
+		t.Errorf("unexpected access token %s expires in %d", token, expiresIn)
+	}
+
+	if _, _, err := client.GetAccessToken("app_id", "wrong_secret"); err == nil {
+		t.Error("get access token with wrong secret succeed")
+	}
+}
+
+func TestGetWXQrCode(t *testing.T) {
+	srv, client := initFakeWX(t)
+	defer srv.Close()
+
+	img, err := client.GetWXQrCode(fakewx.AccessToken("app_id"), "scene")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != newDx {
+		t.Errorf("qr code width %d, expect %d", img.Bounds().Dx(), newDx)
+	}
+
+	if _, err := client.GetWXQrCode("wrong_token", "scene"); err == nil {
+		t.Error("get qr code with wrong token succeed")
+	}
+}
+
+func TestCheckFinger(t *testing.T) {
+	srv, client := initFakeWX(t)
+	defer srv.Close()
+
+	openID := fakewx.OpenID("app_id", "js_code")
+	finger := structs.FingerRequest{
+		OpenID:        openID,
+		JSONString:    `{"raw":"msg"}`,
+		JSONSignature: fakewx.SoterSignature(openID, `{"raw":"msg"}`),
+	}
+
+	resp, err := client.CheckFinger(finger, fakewx.AccessToken("app_id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !resp.IsOK {
+		t.Error("valid soter signature not verified")
+	}
+
+	finger.JSONSignature = "forged"
+	resp, err = client.CheckFinger(finger, fakewx.AccessToken("app_id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.IsOK {
+		t.Error("forged soter signature verified")
+	}
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/csiabb/donation-service/components/wx (interfaces: IWXClient)
+// Source: github.com/csiabb/donation-service/components/wx (interfaces: IWXClient,ITokenManager)
 
 // Package mock_wx is a generated GoMock package.
 package mock_wx
@@ -81,12 +81,13 @@ func (mr *MockIWXClientMockRecorder) DecryptUserInfo(arg0, arg1, arg2, arg3, arg
 }
 
 // GetAccessToken mocks base method
-func (m *MockIWXClient) GetAccessToken(arg0, arg1 string) (string, error) {
+func (m *MockIWXClient) GetAccessToken(arg0, arg1 string) (string, int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAccessToken", arg0, arg1)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetAccessToken indicates an expected call of GetAccessToken
@@ -124,3 +125,41 @@ func (mr *MockIWXClientMockRecorder) WXLogin(arg0, arg1, arg2 interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WXLogin", reflect.TypeOf((*MockIWXClient)(nil).WXLogin), arg0, arg1, arg2)
 }
+
+// MockITokenManager is a mock of ITokenManager interface
+type MockITokenManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockITokenManagerMockRecorder
+}
+
+// MockITokenManagerMockRecorder is the mock recorder for MockITokenManager
+type MockITokenManagerMockRecorder struct {
+	mock *MockITokenManager
+}
+
+// NewMockITokenManager creates a new mock instance
+func NewMockITokenManager(ctrl *gomock.Controller) *MockITokenManager {
+	mock := &MockITokenManager{ctrl: ctrl}
+	mock.recorder = &MockITokenManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockITokenManager) EXPECT() *MockITokenManagerMockRecorder {
+	return m.recorder
+}
+
+// AccessToken mocks base method
+func (m *MockITokenManager) AccessToken(arg0, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccessToken", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AccessToken indicates an expected call of AccessToken
+func (mr *MockITokenManagerMockRecorder) AccessToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccessToken", reflect.TypeOf((*MockITokenManager)(nil).AccessToken), arg0, arg1)
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package wx
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	accessTokenKey     = "wx:access_token:%s"      // access token of wechat app shared across instances
+	accessTokenLockKey = "wx:access_token:lock:%s" // lock of refreshing access token across instances
+	refreshAhead       = 300                       // seconds the access token is refreshed before it expires
+	refreshLockTTL     = 10                        // seconds the refreshing lock is held at most
+	refreshRetry       = 100 * time.Millisecond    // interval of waiting for the token refreshed by other instance
+)
+
+// ITokenManager defines the interface to get the access token of wechat app
+type ITokenManager interface {
+	AccessToken(appID, secret string) (string, error)
+}
+
+type cachedToken struct {
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (t *cachedToken) valid() bool {
+	return t != nil && t.Token != "" && time.Now().Unix() < t.ExpiresAt
+}
+
+// TokenManager caches the access tokens of wechat apps in memory and redis, the token is
+// refreshed by one goroutine of one instance at a time
+type TokenManager struct {
+	client IWXClient
+	pool   *redis.Pool
+	wait   time.Duration // max time waiting for the token refreshed by other instance
+
+	mu     sync.Mutex
+	tokens map[string]*cachedToken
+	locks  map[string]*sync.Mutex
+}
+
+// NewTokenManager ...
+func NewTokenManager(client IWXClient, pool *redis.Pool) (ITokenManager, error) {
+	if client == nil || pool == nil {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	return &TokenManager{
+		client: client,
+		pool:   pool,
+		wait:   refreshLockTTL * time.Second,
+		tokens: make(map[string]*cachedToken),
+		locks:  make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// AccessToken returns the cached access token of wechat app, refreshed just before it expires
+func (m *TokenManager) AccessToken(appID, secret string) (string, error) {
+	if t := m.cached(appID); t.valid() {
+		return t.Token, nil
+	}
+
+	lock := m.lock(appID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// refreshed by another goroutine while waiting for the lock
+	if t := m.cached(appID); t.valid() {
+		return t.Token, nil
+	}
+
+	t, err := m.refresh(appID, secret)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.tokens[appID] = t
+	m.mu.Unlock()
+
+	return t.Token, nil
+}
+
+// refresh loads the token refreshed by other instances from redis, or refreshes it from
+// wechat while holding the lock across instances
+func (m *TokenManager) refresh(appID, secret string) (*cachedToken, error) {
+	key := fmt.Sprintf(accessTokenKey, appID)
+	lockKey := fmt.Sprintf(accessTokenLockKey, appID)
+
+	deadline := time.Now().Add(m.wait)
+	for {
+		t, err := m.load(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.valid() {
+			return t, nil
+		}
+
+		ok, err := m.do(rest.RedisSet, lockKey, 1, rest.RedisEX, refreshLockTTL, rest.RedisNX)
+		if err != nil {
+			return nil, fmt.Errorf("lock access token error, %v", err)
+		}
+
+		if ok != nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("wait for access token refreshed timeout")
+		}
+		time.Sleep(refreshRetry)
+	}
+	defer m.do(rest.RedisDel, lockKey)
+
+	token, expiresIn, err := m.client.GetAccessToken(appID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := expiresIn - refreshAhead
+	if ttl <= 0 {
+		ttl = expiresIn
+	}
+
+	t := &cachedToken{Token: token, ExpiresAt: time.Now().Unix() + ttl}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.do(rest.RedisSet, key, string(b), rest.RedisEX, ttl); err != nil {
+		logger.Warningf("cache access token of %s error, %v", appID, err)
+	}
+
+	logger.Infof("access token of %s refreshed, expires in %ds", appID, ttl)
+	return t, nil
+}
+
+func (m *TokenManager) load(key string) (*cachedToken, error) {
+	b, err := redis.Bytes(m.do(rest.RedisGet, key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("load access token error, %v", err)
+	}
+
+	t := &cachedToken{}
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, fmt.Errorf("decode access token error, %v", err)
+	}
+
+	return t, nil
+}
+
+func (m *TokenManager) cached(appID string) *cachedToken {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokens[appID]
+}
+
+func (m *TokenManager) lock(appID string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lock, ok := m.locks[appID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[appID] = lock
+	}
+
+	return lock
+}
+
+// do runs the redis command on a connection of the pool, which is not safe for concurrent use
+func (m *TokenManager) do(cmd string, args ...interface{}) (interface{}, error) {
+	conn := m.pool.Get()
+	defer conn.Close()
+	return conn.Do(cmd, args...)
+}
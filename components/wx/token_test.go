@@ -0,0 +1,111 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package wx
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+)
+
+func mockPool(conn *redigomock.Conn) *redis.Pool {
+	return &redis.Pool{Dial: func() (redis.Conn, error) { return conn, nil }}
+}
+
+// tokenClient counts the access tokens got from wechat
+type tokenClient struct {
+	IWXClient
+	calls int32
+}
+
+func (c *tokenClient) GetAccessToken(appID string, secret string) (string, int64, error) {
+	atomic.AddInt32(&c.calls, 1)
+	time.Sleep(10 * time.Millisecond)
+	return "token_" + appID, 7200, nil
+}
+
+func TestAccessTokenSingleFlight(t *testing.T) {
+	conn := redigomock.NewConn()
+	conn.Command(rest.RedisGet, "wx:access_token:app_id").Expect(nil)
+	conn.GenericCommand(rest.RedisSet).Expect("OK")
+	conn.GenericCommand(rest.RedisDel).Expect(int64(1))
+
+	client := &tokenClient{}
+	m, err := NewTokenManager(client, mockPool(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := m.AccessToken("app_id", "secret")
+			if err != nil || token != "token_app_id" {
+				t.Errorf("got access token %s, %v", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if client.calls != 1 {
+		t.Errorf("got access token from wechat %d times, expect 1", client.calls)
+	}
+}
+
+func TestAccessTokenFromRedis(t *testing.T) {
+	b, _ := json.Marshal(&cachedToken{Token: "shared_token", ExpiresAt: time.Now().Unix() + 600})
+
+	// refreshed by other instance
+	conn := redigomock.NewConn()
+	conn.Command(rest.RedisGet, "wx:access_token:app_id").Expect(b)
+
+	client := &tokenClient{}
+	m, err := NewTokenManager(client, mockPool(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := m.AccessToken("app_id", "secret")
+	if err != nil || token != "shared_token" {
+		t.Errorf("got access token %s, %v", token, err)
+	}
+
+	if client.calls != 0 {
+		t.Errorf("got access token from wechat %d times, expect 0", client.calls)
+	}
+}
+
+func TestAccessTokenLocked(t *testing.T) {
+	// locked by other instance which never refreshes
+	conn := redigomock.NewConn()
+	conn.Command(rest.RedisGet, "wx:access_token:app_id").Expect(nil)
+	conn.Command(rest.RedisSet, "wx:access_token:lock:app_id", 1, rest.RedisEX, refreshLockTTL, rest.RedisNX).Expect(nil)
+
+	client := &tokenClient{}
+	m, err := NewTokenManager(client, mockPool(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.(*TokenManager).wait = 300 * time.Millisecond
+
+	if _, err := m.AccessToken("app_id", "secret"); err == nil {
+		t.Error("expect timeout waiting for access token")
+	}
+
+	if client.calls != 0 {
+		t.Errorf("got access token from wechat %d times, expect 0", client.calls)
+	}
+}
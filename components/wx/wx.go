@@ -13,7 +13,7 @@ import (
 	"github.com/csiabb/donation-service/structs"
 )
 
-//go:generate mockgen -destination=mock_wx/mock_wx.go -package=mock_wx github.com/csiabb/donation-service/components/wx IWXClient
+//go:generate mockgen -destination=mock_wx/mock_wx.go -package=mock_wx github.com/csiabb/donation-service/components/wx IWXClient,ITokenManager
 
 // IWXClient defines the wx client interface
 type IWXClient interface {
@@ -22,7 +22,7 @@ type IWXClient interface {
 	DecryptPhoneNumber(ssk, data, iv string) (phone PhoneNumber, err error)
 	CheckFinger(finger structs.FingerRequest, accessToken string) (*structs.FingerResponse, error)
 	GetWXQrCode(token string, scene string) (image.Image, error)
-	GetAccessToken(appID string, secret string) (string, error)
+	GetAccessToken(appID string, secret string) (token string, expiresIn int64, err error)
 }
 
 // ClientCfg ...
@@ -32,6 +32,30 @@ type ClientCfg struct {
 	Secret  string
 	Name    string
 	Env     string
+	Address string // base url of wechat api, https://api.weixin.qq.com if empty
+	Apps    []App  // other mini-programs sharing the service, selected by app id
+}
+
+// App defines the mini-program of wechat
+type App struct {
+	AppID  string
+	Secret string
+	Name   string
+}
+
+// App returns the mini-program of app id, the default one if app id is empty
+func (c *ClientCfg) App(appID string) (*App, bool) {
+	if appID == "" || appID == c.AppID {
+		return &App{AppID: c.AppID, Secret: c.Secret, Name: c.Name}, true
+	}
+
+	for i := range c.Apps {
+		if c.Apps[i].AppID == appID {
+			return &c.Apps[i], true
+		}
+	}
+
+	return nil, false
 }
 
 // Client wx Client
@@ -77,6 +101,12 @@ type LoginResponse struct {
 	UnionID    string `json:"unionid"`
 }
 
+type accessTokenResponse struct {
+	Response
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
 // PhoneNumber defines the phone number
 type PhoneNumber struct {
 	PhoneNumber     string    `json:"phoneNumber"`
@@ -0,0 +1,35 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package wx
+
+import (
+	"testing"
+)
+
+func TestClientCfgApp(t *testing.T) {
+	cfg := &ClientCfg{
+		AppID:  "app_id",
+		Secret: "secret",
+		Apps:   []App{{AppID: "other_app_id", Secret: "other_secret"}},
+	}
+
+	for _, appID := range []string{"", "app_id"} {
+		app, ok := cfg.App(appID)
+		if !ok || app.AppID != "app_id" || app.Secret != "secret" {
+			t.Errorf("default app of %q check failed, %v", appID, app)
+		}
+	}
+
+	app, ok := cfg.App("other_app_id")
+	if !ok || app.Secret != "other_secret" {
+		t.Errorf("other app check failed, %v", app)
+	}
+
+	if _, ok := cfg.App("unknown_app_id"); ok {
+		t.Error("unknown app check failed")
+	}
+}
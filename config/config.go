@@ -11,9 +11,12 @@ import (
 
 	"github.com/csiabb/donation-service/common/log"
 	"github.com/csiabb/donation-service/components/aliyun"
+	"github.com/csiabb/donation-service/components/auth"
 	"github.com/csiabb/donation-service/components/bcadapter"
 	"github.com/csiabb/donation-service/components/database"
 	"github.com/csiabb/donation-service/components/image"
+	"github.com/csiabb/donation-service/components/policy"
+	"github.com/csiabb/donation-service/components/sms"
 	"github.com/csiabb/donation-service/components/wx"
 )
 
@@ -37,6 +40,12 @@ type SrvcCfg struct {
 	LocalFileSystem string
 	BCAdapterCfg    bcadapter.Config
 	Redis           RedisCfg
+	Publisher       PublisherCfg
+	Reconciler      ReconcilerCfg
+	Ranker          RankerCfg
+	AuthCfg         auth.Config
+	Policy          policy.Config
+	SMS             sms.Config
 }
 
 // ServerGeneralCfg general configure of service
@@ -47,8 +56,32 @@ type ServerGeneralCfg struct {
 
 // RedisCfg redis config
 type RedisCfg struct {
-	Addr string
-	Auth string
+	Addr        string
+	Auth        string
+	MaxIdle     int // idle connections kept in the pool
+	MaxActive   int // connections allocated by the pool at most, zero for no limit
+	IdleTimeout int // seconds the idle connection is closed after
+}
+
+// PublisherCfg block chain publisher config
+type PublisherCfg struct {
+	Interval    int // seconds between two outbox scans
+	BatchSize   int // max publicities published in one scan
+	MaxAttempts int // attempts before the publicity is marked failed
+}
+
+// ReconcilerCfg block chain reconciler config
+type ReconcilerCfg struct {
+	Interval    int // seconds between two reconciliations
+	StuckAge    int // seconds without call back before the publicity is re-published
+	BatchSize   int // max publicities re-published in one reconciliation
+	MaxAttempts int // attempts before the reconciler gives up
+}
+
+// RankerCfg leaderboard ranker config
+type RankerCfg struct {
+	Interval int // seconds between two refreshes of leaderboard snapshots
+	TopN     int // max items kept for each metric of leaderboard
 }
 
 // GetServiceCfg returns the configurations for the service
@@ -8,11 +8,17 @@ package context
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/csiabb/donation-service/common/log"
+	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/components/aliyun"
+	"github.com/csiabb/donation-service/components/auth"
 	"github.com/csiabb/donation-service/components/bcadapter"
+	_ "github.com/csiabb/donation-service/components/bcadapter/local" // register local ledger driver
 	"github.com/csiabb/donation-service/components/image"
+	"github.com/csiabb/donation-service/components/policy"
+	"github.com/csiabb/donation-service/components/sms"
 	"github.com/csiabb/donation-service/components/wx"
 	"github.com/csiabb/donation-service/config"
 	"github.com/csiabb/donation-service/models"
@@ -21,6 +27,11 @@ import (
 	"github.com/gomodule/redigo/redis"
 )
 
+const (
+	defaultRedisMaxIdle     = 10
+	defaultRedisIdleTimeout = 240 // seconds
+)
+
 var (
 	serverContext *Context
 	logger        = log.MustGetLogger("context")
@@ -30,11 +41,15 @@ var (
 type Context struct {
 	IBCAdapter    bcadapter.IBCAdapter
 	WXClient      wx.IWXClient
+	WXTokens      wx.ITokenManager
 	Config        *config.SrvcCfg
 	DBStorage     models.IDBBackend
 	ALiYunBackend aliyun.IALiYunBackend
 	ImageBackend  image.IImageBackend
-	RedisCli      redis.Conn
+	RedisPool     *redis.Pool
+	AuthBackend   auth.IAuthBackend
+	Policy        policy.IPolicy
+	SMSSender     sms.ISender
 }
 
 // GetServerContext ...
@@ -66,6 +81,12 @@ func (c *Context) Init() error {
 		return err
 	}
 
+	err = c.initRedis()
+	if nil != err {
+		logger.Errorf("Initialize redis backend failed, %v", err)
+		return err
+	}
+
 	err = c.initWXBackend()
 	if nil != err {
 		logger.Errorf("Initialize wechat backend failed, %v", err)
@@ -84,9 +105,21 @@ func (c *Context) Init() error {
 		return err
 	}
 
-	err = c.initRedis()
+	err = c.initAuthBackend()
 	if nil != err {
-		logger.Errorf("Initialize redis backend failed, %v", err)
+		logger.Errorf("Initialize auth backend failed, %v", err)
+		return err
+	}
+
+	err = c.initPolicy()
+	if nil != err {
+		logger.Errorf("Initialize policy failed, %v", err)
+		return err
+	}
+
+	err = c.initSMSSender()
+	if nil != err {
+		logger.Errorf("Initialize sms sender failed, %v", err)
 		return err
 	}
 
@@ -119,12 +152,19 @@ func (c *Context) initWXBackend() error {
 		return err
 	}
 
+	// access token of wechat shared across instances by redis
+	c.WXTokens, err = wx.NewTokenManager(c.WXClient, c.RedisPool)
+	if err != nil {
+		logger.Errorf("Failed new wx token manager: %v", err)
+		return err
+	}
+
 	return nil
 }
 
 func (c *Context) initBCAdapter() error {
 	var err error
-	c.IBCAdapter, err = bcadapter.NewBCAdapterBackend(&c.Config.BCAdapterCfg)
+	c.IBCAdapter, err = bcadapter.NewBCAdapter(&c.Config.BCAdapterCfg)
 	if err != nil {
 		logger.Errorf("Failed new block chain adapter : %v", err)
 		return err
@@ -146,7 +186,7 @@ func (c *Context) initALiYunServices() error {
 
 func (c *Context) initImageBackend() error {
 	var err error
-	c.ImageBackend, err = image.NewImageBackend(&c.Config.ImageCfg, c.WXClient)
+	c.ImageBackend, err = image.NewImageBackend(&c.Config.ImageCfg, c.WXClient, c.WXTokens)
 	if nil != err {
 		logger.Errorf("New aliyun services error, %v", err)
 		return err
@@ -156,10 +196,61 @@ func (c *Context) initImageBackend() error {
 }
 
 func (c *Context) initRedis() error {
+	cfg := c.Config.Redis
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = defaultRedisMaxIdle
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultRedisIdleTimeout
+	}
+
+	// redis connection is not safe for concurrent use, each request gets its own from the pool
+	c.RedisPool = &redis.Pool{
+		MaxIdle:     cfg.MaxIdle,
+		MaxActive:   cfg.MaxActive,
+		IdleTimeout: time.Duration(cfg.IdleTimeout) * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", cfg.Addr, redis.DialPassword(cfg.Auth))
+		},
+	}
+
+	conn := c.RedisPool.Get()
+	defer conn.Close()
+	if _, err := conn.Do(rest.RedisPing); err != nil {
+		logger.Errorf("Connect redis failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Context) initAuthBackend() error {
 	var err error
-	c.RedisCli, err = redis.Dial("tcp", c.Config.Redis.Addr, redis.DialPassword(c.Config.Redis.Auth))
+	c.AuthBackend, err = auth.NewAuthBackend(&c.Config.AuthCfg)
 	if err != nil {
-		logger.Errorf("Connect redis failed: %v", err)
+		logger.Errorf("Failed new auth backend: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Context) initPolicy() error {
+	var err error
+	c.Policy, err = policy.NewPolicy(&c.Config.Policy)
+	if err != nil {
+		logger.Errorf("Failed new policy: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *Context) initSMSSender() error {
+	var err error
+	c.SMSSender, err = sms.NewSender(&c.Config.SMS)
+	if err != nil {
+		logger.Errorf("Failed new sms sender: %v", err)
 		return err
 	}
 
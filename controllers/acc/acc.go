@@ -10,16 +10,28 @@ import (
 	"fmt"
 	wlog "github.com/csiabb/donation-service/common/log"
 	"net/http"
+	"time"
 
 	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/components/wx"
+	"github.com/csiabb/donation-service/middleware"
 	"github.com/csiabb/donation-service/models"
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
 )
 
+const (
+	sessionKey   = "acc:session:%s"   // id of the latest login session of account
+	wxSessionKey = "acc:wx:ssk:%s:%s" // session key of wechat app of the latest login session
+
+	wxDataMaxAge = 600 // seconds the encrypted data of wechat is valid since its watermark
+)
+
 // LoginWXApp defines the user login
 func (h *RestHandler) LoginWXApp(c *gin.Context) {
 	logger.Info("got login request")
@@ -43,7 +55,14 @@ func (h *RestHandler) LoginWXApp(c *gin.Context) {
 	}
 	wlog.Debugf("code参数验证:%+v", wlog.ToJson(req.CertCode))
 
-	wxApp := h.srvcContext.Config.WXCfg
+	wxApp, ok := h.srvcContext.Config.WXCfg.App(req.AppID)
+	if !ok {
+		e := fmt.Errorf("unknown wechat app %s", req.AppID)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
 	wxCredentials, err := h.srvcContext.WXClient.WXLogin(wxApp.AppID, wxApp.Secret, req.CertCode)
 	if err != nil {
 		wlog.Errorf("获取微信详情出错 %+v", err)
@@ -60,21 +79,17 @@ func (h *RestHandler) LoginWXApp(c *gin.Context) {
 		return
 	}
 
-	user, err := h.srvcContext.DBStorage.QueryAccount(wxCredentials.OpenID, "")
+	user, err := h.boundAccount(wxApp.AppID, &wxCredentials)
 	if err != nil {
-		if err != gorm.ErrRecordNotFound {
-			logger.Errorf("query account return err %v", err)
-			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, err.Error()))
-			return
-		}
-	} else {
-		if user.ID != "" {
-			logger.Debug("user already exists")
-			c.JSON(http.StatusOK, rest.SuccessResponse(&structs.LoginResp{
-				UID: user.ID,
-			}))
-			return
-		}
+		logger.Errorf("query account return err %v", err)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, err.Error()))
+		return
+	}
+
+	if user != nil {
+		logger.Debug("user already exists")
+		h.loginSucceed(c, user, wxApp.AppID, auth.SessionID(wxCredentials.SessionKey), wxCredentials.SessionKey)
+		return
 	}
 	wlog.Debugf("查询用户 %+v", wlog.ToJson(user))
 
@@ -88,18 +103,17 @@ func (h *RestHandler) LoginWXApp(c *gin.Context) {
 	}
 	wlog.Debugf("产生用户id %+v", wlog.ToJson(bcResp))
 
+	// user name, phone and email are not verified on wechat sign up, the phone is bound by BindWXPhone
 	acc := &models.Account{
 		ID:       id,
-		Access:   req.Access,
-		Password: req.Password,
 		NickName: req.Nickname,
-		Phone:    req.Phone,
-		Email:    req.Email,
 		Remark:   req.Remark,
+		Type:     rest.UserTypeNormal,
 		OpenID:   wxCredentials.OpenID,
 		AppID:    wxApp.AppID,
 		UnionID:  wxCredentials.UnionID,
 		DID:      bcResp.Data.ID,
+		Source:   rest.SourceWechat,
 	}
 
 	wlog.Debugf("插入用户 %+v", wlog.ToJson(acc))
@@ -111,7 +125,350 @@ func (h *RestHandler) LoginWXApp(c *gin.Context) {
 		return
 	}
 
+	err = h.srvcContext.DBStorage.CreateWXBinding(&models.WXBinding{
+		ID:      utils.GenerateUUID(),
+		UID:     acc.ID,
+		AppID:   wxApp.AppID,
+		OpenID:  wxCredentials.OpenID,
+		UnionID: wxCredentials.UnionID,
+	})
+	if err != nil {
+		e := fmt.Errorf("bind wechat user error, %v", err)
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	h.loginSucceed(c, acc, wxApp.AppID, auth.SessionID(wxCredentials.SessionKey), wxCredentials.SessionKey)
+}
+
+// boundAccount returns the account the user of wechat app bound to, nil if not bound. The user
+// is bound by its open id of the app, otherwise linked by union id to the account of other app,
+// the account created before bindings exist is bound on its first login
+func (h *RestHandler) boundAccount(appID string, lr *wx.LoginResponse) (*models.Account, error) {
+	binding, err := h.srvcContext.DBStorage.QueryWXBinding("", appID, lr.OpenID, "")
+	if err == nil {
+		return h.srvcContext.DBStorage.QueryAccount("", "", binding.UID)
+	}
+
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	acc, err := h.srvcContext.DBStorage.QueryAccount(appID, lr.OpenID, "")
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		if lr.UnionID == "" {
+			return nil, nil
+		}
+
+		binding, err = h.srvcContext.DBStorage.QueryWXBinding("", "", "", lr.UnionID)
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		acc, err = h.srvcContext.DBStorage.QueryAccount("", "", binding.UID)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("wechat user of app %s linked to account %s by union id", appID, acc.ID)
+	}
+
+	err = h.srvcContext.DBStorage.CreateWXBinding(&models.WXBinding{
+		ID:      utils.GenerateUUID(),
+		UID:     acc.ID,
+		AppID:   appID,
+		OpenID:  lr.OpenID,
+		UnionID: lr.UnionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return acc, nil
+}
+
+// RefreshToken defines refreshing the access token of the latest login session
+func (h *RestHandler) RefreshToken(c *gin.Context) {
+	logger.Info("got refresh token request")
+
+	req := &structs.RefreshTokenRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	claims, err := h.srvcContext.AuthBackend.ParseToken(req.RefreshToken, auth.TokenRefresh)
+	if err != nil {
+		e := fmt.Errorf("invalid refresh token, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return
+	}
+
+	sid, err := redis.String(h.redisDo(rest.RedisGet, fmt.Sprintf(sessionKey, claims.UID)))
+	if err != nil && err != redis.ErrNil {
+		e := fmt.Errorf("query login session error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	if sid == "" || sid != claims.Session {
+		e := fmt.Errorf("login session expired")
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return
+	}
+
+	acc, err := h.srvcContext.DBStorage.QueryAccount("", "", claims.UID)
+	if err != nil {
+		e := fmt.Errorf("query account error, %s", err.Error())
+		logger.Error(e)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	h.loginSucceed(c, acc, claims.AppID, claims.Session, "")
+}
+
+// loginSucceed issues the tokens bound to the account and login session, only the latest
+// session of the account is able to refresh its access token, the session key of wechat
+// is kept as long as the session if not empty
+func (h *RestHandler) loginSucceed(c *gin.Context, acc *models.Account, appID, sessionID, ssk string) {
+	tokens, err := h.srvcContext.AuthBackend.IssueTokens(acc.ID, acc.Type, appID, sessionID)
+	if err != nil {
+		e := fmt.Errorf("issue tokens error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	_, err = h.redisDo(rest.RedisSet, fmt.Sprintf(sessionKey, acc.ID), sessionID, rest.RedisEX, tokens.RefreshExpiresIn)
+	if err != nil {
+		e := fmt.Errorf("save login session error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	if ssk != "" {
+		_, err = h.redisDo(rest.RedisSet, fmt.Sprintf(wxSessionKey, acc.ID, appID), ssk, rest.RedisEX, tokens.RefreshExpiresIn)
+		if err != nil {
+			e := fmt.Errorf("save wechat session key error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.LoginResp{
-		UID: acc.ID,
+		UID:          acc.ID,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
 	}))
 }
+
+// BindWXUserInfo defines decrypting the user info of wechat and saving nick name and avatar on account
+func (h *RestHandler) BindWXUserInfo(c *gin.Context) {
+	logger.Info("got bind wechat user info request")
+
+	req := &structs.WXUserInfoRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	claims, ssk, ok := h.wxSession(c)
+	if !ok {
+		return
+	}
+
+	ui, err := h.srvcContext.WXClient.DecryptUserInfo(req.RawData, req.EncryptedData, req.Signature, req.IV, ssk)
+	if err != nil {
+		e := fmt.Errorf("decrypt wechat user info error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.WXDecryptFailed, e.Error()))
+		return
+	}
+
+	if !h.checkWatermark(c, claims.AppID, ui.Watermark.AppID, ui.Watermark.Timestamp) {
+		return
+	}
+
+	fields := map[string]interface{}{"nick_name": ui.Nickname}
+	if ui.UnionID != "" {
+		fields["union_id"] = ui.UnionID
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err = h.srvcContext.DBStorage.UpdateAccount(tx, claims.UID, fields)
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	// the avatar of user is kept as image of account, replaced on each binding
+	err = h.srvcContext.DBStorage.DeleteImages(tx, claims.UID, rest.ImageAvatar)
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("delete avatar error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	if ui.Avatar != "" {
+		err = h.srvcContext.DBStorage.CreateImages(tx, []*models.Image{
+			{
+				ID:        utils.GenerateUUID(),
+				RelatedID: claims.UID,
+				Type:      rest.ImageAvatar,
+				URL:       ui.Avatar,
+			},
+		})
+		if err != nil {
+			h.srvcContext.DBStorage.DBTransactionRollback(tx)
+			e := fmt.Errorf("create avatar error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return
+		}
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.WXUserInfoResp{
+		UID:      claims.UID,
+		NickName: ui.Nickname,
+		Avatar:   ui.Avatar,
+	}))
+	logger.Info("response bind wechat user info success.")
+}
+
+// BindWXPhone defines decrypting the phone number of wechat and saving it on account
+func (h *RestHandler) BindWXPhone(c *gin.Context) {
+	logger.Info("got bind wechat phone number request")
+
+	req := &structs.WXPhoneRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	claims, ssk, ok := h.wxSession(c)
+	if !ok {
+		return
+	}
+
+	phone, err := h.srvcContext.WXClient.DecryptPhoneNumber(ssk, req.EncryptedData, req.IV)
+	if err != nil {
+		e := fmt.Errorf("decrypt wechat phone number error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.WXDecryptFailed, e.Error()))
+		return
+	}
+
+	if !h.checkWatermark(c, claims.AppID, phone.Watermark.AppID, phone.Watermark.Timestamp) {
+		return
+	}
+
+	if phone.PurePhoneNumber == "" {
+		e := fmt.Errorf("phone number of wechat is empty")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.WXDecryptFailed, e.Error()))
+		return
+	}
+
+	if !h.checkLoginFree(c, claims.UID, phone.PurePhoneNumber, "") {
+		return
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err = h.srvcContext.DBStorage.UpdateAccount(tx, claims.UID, map[string]interface{}{"phone": phone.PurePhoneNumber})
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.WXPhoneResp{
+		UID:   claims.UID,
+		Phone: phone.PurePhoneNumber,
+	}))
+	logger.Info("response bind wechat phone number success.")
+}
+
+// wxSession returns the user of access token and the session key of wechat kept on login
+func (h *RestHandler) wxSession(c *gin.Context) (*auth.Claims, string, bool) {
+	claims, ok := middleware.CurrentUser(c)
+	if !ok {
+		e := fmt.Errorf("missing access token")
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return nil, "", false
+	}
+
+	ssk, err := redis.String(h.redisDo(rest.RedisGet, fmt.Sprintf(wxSessionKey, claims.UID, claims.AppID)))
+	if err != nil && err != redis.ErrNil {
+		e := fmt.Errorf("query wechat session key error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return nil, "", false
+	}
+
+	if ssk == "" {
+		e := fmt.Errorf("wechat session key expired, login again")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.WXSessionExpired, e.Error()))
+		return nil, "", false
+	}
+
+	return claims, ssk, true
+}
+
+// checkWatermark checks the encrypted data of wechat is issued to the app logged in recently
+func (h *RestHandler) checkWatermark(c *gin.Context, loginAppID, appID string, timestamp int64) bool {
+	wxApp, ok := h.srvcContext.Config.WXCfg.App(loginAppID)
+	if !ok || appID != wxApp.AppID {
+		e := fmt.Errorf("watermark app id %s mismatch", appID)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.WXDecryptFailed, e.Error()))
+		return false
+	}
+
+	now := time.Now().Unix()
+	if timestamp > now+wxDataMaxAge || now-timestamp > wxDataMaxAge {
+		e := fmt.Errorf("watermark timestamp %d expired", timestamp)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.WXDecryptFailed, e.Error()))
+		return false
+	}
+
+	return true
+}
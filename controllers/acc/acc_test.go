@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
 	"github.com/csiabb/donation-service/components/bcadapter/mock_bcadapter"
 	"github.com/csiabb/donation-service/components/wx"
 	"github.com/csiabb/donation-service/components/wx/mock_wx"
@@ -27,11 +28,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
+	"github.com/rafaeljusto/redigomock"
 )
 
 const (
-	urlAccLoginWXApp = "api/v1/acc/login/wxapp"
+	urlAccLoginWXApp   = "api/v1/acc/login/wxapp"
+	urlAccTokenRefresh = "api/v1/acc/token/refresh"
+	urlAccWXUserInfo   = "api/v1/acc/wx/userinfo"
+	urlAccWXPhone      = "api/v1/acc/wx/phone"
 )
 
 const (
@@ -50,33 +56,51 @@ const (
   "app_id": "xxyyzz",
   "remark": "desc"
 }`
+
+	wxEncryptedBodyJSON = `{
+  "encrypted_data": "encrypted_data",
+  "iv": "iv"
+}`
 )
 
-func Init(t *testing.T) (*gomock.Controller, *RestHandler, *mock_backend.MockIDBBackend, *mock_wx.MockIWXClient, *mock_bcadapter.MockIBCAdapter, *httptest.ResponseRecorder, *gin.Context) {
+func Init(t *testing.T) (*gomock.Controller, *RestHandler, *mock_backend.MockIDBBackend, *mock_wx.MockIWXClient, *mock_bcadapter.MockIBCAdapter, *redigomock.Conn, *httptest.ResponseRecorder, *gin.Context) {
 	mockCtl := gomock.NewController(t)
 	mockDB := mock_backend.NewMockIDBBackend(mockCtl)
 	mockWX := mock_wx.NewMockIWXClient(mockCtl)
 	mockBCAdapter := mock_bcadapter.NewMockIBCAdapter(mockCtl)
 
+	// init redigo mock connection
+	redisCli := redigomock.NewConn()
+
+	authBackend, err := auth.NewAuthBackend(&auth.Config{Secret: "test-secret"})
+	if err != nil {
+		t.Fatalf("init auth backend error, %v", err)
+	}
+
 	// init mock handler
 	handler := RestHandler{}
 	handler.srvcContext = &context.Context{}
 	handler.srvcContext.DBStorage = mockDB
 	handler.srvcContext.WXClient = mockWX
 	handler.srvcContext.Config = &config.SrvcCfg{}
-	handler.srvcContext.Config.WXCfg = wx.ClientCfg{}
+	handler.srvcContext.Config.WXCfg = wx.ClientCfg{
+		AppID: "wx_app_id",
+		Apps:  []wx.App{{AppID: "xxyyzz", Secret: "xxyyzz_secret"}},
+	}
 	handler.srvcContext.IBCAdapter = mockBCAdapter
+	handler.srvcContext.RedisPool = &redis.Pool{Dial: func() (redis.Conn, error) { return redisCli, nil }}
+	handler.srvcContext.AuthBackend = authBackend
 
 	// init test mode gin
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
-	return mockCtl, &handler, mockDB, mockWX, mockBCAdapter, w, c
+	return mockCtl, &handler, mockDB, mockWX, mockBCAdapter, redisCli, w, c
 }
 
 func TestLoginWXAppUserExist(t *testing.T) {
-	mockCtl, handler, mockDB, mockWX, _, w, c := Init(t)
+	mockCtl, handler, mockDB, mockWX, _, redisCli, w, c := Init(t)
 	defer mockCtl.Finish()
 
 	lr := wx.LoginResponse{
@@ -96,8 +120,14 @@ func TestLoginWXAppUserExist(t *testing.T) {
 		CreatedAt: time.Now(),
 	}
 
-	mockWX.EXPECT().WXLogin(gomock.Any(), gomock.Any(), gomock.Any()).Return(lr, nil)
-	mockDB.EXPECT().QueryAccount(gomock.Any(), gomock.Any()).Return(acc, nil)
+	mockWX.EXPECT().WXLogin("xxyyzz", "xxyyzz_secret", gomock.Any()).Return(lr, nil)
+	mockDB.EXPECT().QueryWXBinding("", "xxyyzz", lr.OpenID, "").Return(&models.WXBinding{
+		UID:    "uid",
+		AppID:  "xxyyzz",
+		OpenID: lr.OpenID,
+	}, nil)
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(acc, nil)
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
 
 	// mock request
 	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLoginWXApp, bytes.NewBufferString(wxLoginBodyJSON))
@@ -107,7 +137,7 @@ func TestLoginWXAppUserExist(t *testing.T) {
 }
 
 func TestLoginWXAppWXResp(t *testing.T) {
-	mockCtl, handler, _, mockWX, _, w, c := Init(t)
+	mockCtl, handler, _, mockWX, _, _, w, c := Init(t)
 	defer mockCtl.Finish()
 
 	lr := wx.LoginResponse{
@@ -134,7 +164,7 @@ func TestLoginWXAppWXResp(t *testing.T) {
 }
 
 func TestLoginWXAppCreateAccount(t *testing.T) {
-	mockCtl, handler, mockDB, mockWX, mockBCAdapter, w, c := Init(t)
+	mockCtl, handler, mockDB, mockWX, mockBCAdapter, redisCli, w, c := Init(t)
 	defer mockCtl.Finish()
 
 	lr := wx.LoginResponse{
@@ -144,13 +174,16 @@ func TestLoginWXAppCreateAccount(t *testing.T) {
 	}
 
 	mockWX.EXPECT().WXLogin(gomock.Any(), gomock.Any(), gomock.Any()).Return(lr, nil)
-	mockDB.EXPECT().QueryAccount(gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockDB.EXPECT().QueryWXBinding(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, gorm.ErrRecordNotFound).Times(2)
+	mockDB.EXPECT().QueryAccount("xxyyzz", lr.OpenID, "").Return(nil, gorm.ErrRecordNotFound)
 	mockBCAdapter.EXPECT().Register(gomock.Any()).Return(&structs.RegisterResp{
 		Code: 0,
 		Msg:  "",
 		Data: structs.RegisterRespData{ID: "aabbcc"},
 	}, nil)
 	mockDB.EXPECT().CreateAccount(gomock.Any()).Return(nil)
+	mockDB.EXPECT().CreateWXBinding(gomock.Any()).Return(nil)
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
 
 	// mock request
 	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLoginWXApp, bytes.NewBufferString(wxLoginBodyJSON))
@@ -167,6 +200,270 @@ func TestLoginWXAppCreateAccount(t *testing.T) {
 	}
 }
 
+func TestLoginWXAppLinkByUnionID(t *testing.T) {
+	mockCtl, handler, mockDB, mockWX, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	lr := wx.LoginResponse{
+		OpenID:     "qpsjkayuenzvdsgdflf",
+		SessionKey: "adkakdakdkad",
+		UnionID:    "dapelemajla",
+	}
+
+	mockWX.EXPECT().WXLogin("xxyyzz", "xxyyzz_secret", gomock.Any()).Return(lr, nil)
+	mockDB.EXPECT().QueryWXBinding("", "xxyyzz", lr.OpenID, "").Return(nil, gorm.ErrRecordNotFound)
+	mockDB.EXPECT().QueryAccount("xxyyzz", lr.OpenID, "").Return(nil, gorm.ErrRecordNotFound)
+	// bound to the account by another app
+	mockDB.EXPECT().QueryWXBinding("", "", "", lr.UnionID).Return(&models.WXBinding{
+		UID:     "uid",
+		AppID:   "wx_app_id",
+		OpenID:  "other_open_id",
+		UnionID: lr.UnionID,
+	}, nil)
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", Type: "normal"}, nil)
+	mockDB.EXPECT().CreateWXBinding(gomock.Any()).DoAndReturn(func(b *models.WXBinding) error {
+		if b.UID != "uid" || b.AppID != "xxyyzz" || b.OpenID != lr.OpenID {
+			t.Errorf("wechat binding check failed, %v", b)
+		}
+		return nil
+	})
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLoginWXApp, bytes.NewBufferString(wxLoginBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.LoginWXApp(c)
+	CommRespCheck(t, w)
+}
+
+func TestLoginWXAppUnknownApp(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	handler.srvcContext.Config.WXCfg.Apps = nil
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLoginWXApp, bytes.NewBufferString(wxLoginBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.LoginWXApp(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("unknown app check failed")
+	}
+}
+
+func TestRefreshTokenSucceed(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	tokens, err := handler.srvcContext.AuthBackend.IssueTokens("uid", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatalf("issue tokens error, %v", err)
+	}
+
+	mockDB.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "uid").Return(&models.Account{
+		ID:   "uid",
+		Type: "normal",
+	}, nil)
+	redisCli.Command(rest.RedisGet, "acc:session:uid").Expect("session_id")
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+
+	// mock request
+	body, _ := json.Marshal(&structs.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccTokenRefresh, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.RefreshToken(c)
+	CommRespCheck(t, w)
+}
+
+func TestRefreshTokenSessionExpired(t *testing.T) {
+	mockCtl, handler, _, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	tokens, err := handler.srvcContext.AuthBackend.IssueTokens("uid", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatalf("issue tokens error, %v", err)
+	}
+
+	// account logged in again with another session
+	redisCli.Command(rest.RedisGet, "acc:session:uid").Expect("other_session_id")
+
+	// mock request
+	body, _ := json.Marshal(&structs.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccTokenRefresh, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.RefreshToken(c)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("login session check failed")
+	}
+}
+
+func TestRefreshTokenKind(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	tokens, err := handler.srvcContext.AuthBackend.IssueTokens("uid", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatalf("issue tokens error, %v", err)
+	}
+
+	// mock request with access token
+	body, _ := json.Marshal(&structs.RefreshTokenRequest{RefreshToken: tokens.AccessToken})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccTokenRefresh, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.RefreshToken(c)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("token kind check failed")
+	}
+}
+
+func TestBindWXUserInfoSucceed(t *testing.T) {
+	mockCtl, handler, mockDB, mockWX, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	ui := wx.UserInfo{
+		OpenID:   "qpsjkayuenzvdsgdflf",
+		Nickname: "nick name",
+		Avatar:   "https://wx.qlogo.cn/avatar.png",
+	}
+	ui.Watermark.AppID = "wx_app_id"
+	ui.Watermark.Timestamp = time.Now().Unix()
+
+	db := &gorm.DB{}
+	redisCli.Command(rest.RedisGet, "acc:wx:ssk:uid:wx_app_id").Expect("session_key")
+	mockWX.EXPECT().DecryptUserInfo(gomock.Any(), "encrypted_data", gomock.Any(), "iv", "session_key").Return(ui, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateAccount(db, "uid", map[string]interface{}{"nick_name": "nick name"}).Return(nil)
+	mockDB.EXPECT().DeleteImages(db, "uid", rest.ImageAvatar).Return(nil)
+	mockDB.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccWXUserInfo, bytes.NewBufferString(wxEncryptedBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BindWXUserInfo(c)
+	CommRespCheck(t, w)
+}
+
+func TestBindWXUserInfoWatermark(t *testing.T) {
+	mockCtl, handler, _, mockWX, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// encrypted for another app
+	ui := wx.UserInfo{Nickname: "nick name"}
+	ui.Watermark.AppID = "other_app_id"
+	ui.Watermark.Timestamp = time.Now().Unix()
+
+	redisCli.Command(rest.RedisGet, "acc:wx:ssk:uid:wx_app_id").Expect("session_key")
+	mockWX.EXPECT().DecryptUserInfo(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(ui, nil)
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccWXUserInfo, bytes.NewBufferString(wxEncryptedBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BindWXUserInfo(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("watermark app id check failed")
+	}
+}
+
+func TestBindWXPhoneSucceed(t *testing.T) {
+	mockCtl, handler, mockDB, mockWX, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	phone := wx.PhoneNumber{
+		PhoneNumber:     "+8618518265711",
+		PurePhoneNumber: "18518265711",
+		CountryCode:     "86",
+	}
+	phone.Watermark.AppID = "wx_app_id"
+	phone.Watermark.Timestamp = time.Now().Unix()
+
+	db := &gorm.DB{}
+	redisCli.Command(rest.RedisGet, "acc:wx:ssk:uid:wx_app_id").Expect("session_key")
+	mockWX.EXPECT().DecryptPhoneNumber("session_key", "encrypted_data", "iv").Return(phone, nil)
+	mockDB.EXPECT().QueryAccountByLogin("", "18518265711", "").Return(nil, gorm.ErrRecordNotFound)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateAccount(db, "uid", map[string]interface{}{"phone": "18518265711"}).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccWXPhone, bytes.NewBufferString(wxEncryptedBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BindWXPhone(c)
+	CommRespCheck(t, w)
+}
+
+func TestBindWXPhoneConflict(t *testing.T) {
+	mockCtl, handler, mockDB, mockWX, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	phone := wx.PhoneNumber{PurePhoneNumber: "18518265711"}
+	phone.Watermark.AppID = "wx_app_id"
+	phone.Watermark.Timestamp = time.Now().Unix()
+
+	// phone bound by another account already
+	redisCli.Command(rest.RedisGet, "acc:wx:ssk:uid:wx_app_id").Expect("session_key")
+	mockWX.EXPECT().DecryptPhoneNumber(gomock.Any(), gomock.Any(), gomock.Any()).Return(phone, nil)
+	mockDB.EXPECT().QueryAccountByLogin("", "18518265711", "").Return(&models.Account{ID: "other_uid"}, nil)
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccWXPhone, bytes.NewBufferString(wxEncryptedBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BindWXPhone(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("phone conflict check failed")
+	}
+}
+
+func TestBindWXPhoneExpired(t *testing.T) {
+	mockCtl, handler, _, mockWX, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// data decrypted long after it is issued
+	phone := wx.PhoneNumber{PurePhoneNumber: "18518265711"}
+	phone.Watermark.AppID = "wx_app_id"
+	phone.Watermark.Timestamp = time.Now().Add(-time.Hour).Unix()
+
+	redisCli.Command(rest.RedisGet, "acc:wx:ssk:uid:wx_app_id").Expect("session_key")
+	mockWX.EXPECT().DecryptPhoneNumber(gomock.Any(), gomock.Any(), gomock.Any()).Return(phone, nil)
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccWXPhone, bytes.NewBufferString(wxEncryptedBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BindWXPhone(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("watermark timestamp check failed")
+	}
+}
+
+func TestBindWXPhoneSessionExpired(t *testing.T) {
+	mockCtl, handler, _, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// session key of wechat not kept
+	redisCli.Command(rest.RedisGet, "acc:wx:ssk:uid:wx_app_id").Expect(nil)
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccWXPhone, bytes.NewBufferString(wxEncryptedBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BindWXPhone(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("wechat session key check failed")
+	}
+}
+
 func CommRespCheck(t *testing.T, w *httptest.ResponseRecorder) {
 	b, err := ioutil.ReadAll(w.Body)
 
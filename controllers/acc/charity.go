@@ -0,0 +1,363 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+// ApplyCharity defines applying the current user to become charity
+func (h *RestHandler) ApplyCharity(c *gin.Context) {
+	logger.Info("got apply charity request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.CharityApplyRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	switch claims.UserType {
+	case rest.UserTypeNormal, rest.UserTypeOrg:
+	case rest.UserTypeOrgCharity:
+		e := fmt.Errorf("user is charity already")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.RepeatRegistration, e.Error()))
+		return
+	default:
+		e := fmt.Errorf("user type %s can not apply to become charity", claims.UserType)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return
+	}
+
+	if !utils.IsBankCardNum(req.BankCardNum) {
+		e := fmt.Errorf("bank card num invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	last, err := h.srvcContext.DBStorage.QueryCharityApply("", claims.UID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		e := fmt.Errorf("query charity application error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	if err == nil && last.Status == rest.ApplyStatusPending {
+		e := fmt.Errorf("application submitted before is waiting for review")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.ApplyUnderReview, e.Error()))
+		return
+	}
+
+	apply := &models.CharityApply{
+		ID:          utils.GenerateUUID(),
+		UID:         claims.UID,
+		Name:        req.Name,
+		Logo:        req.Logo,
+		Bank:        req.Bank,
+		BankCardNum: req.BankCardNum,
+		Description: req.Description,
+		Country:     req.Country,
+		Province:    req.Province,
+		City:        req.City,
+		District:    req.District,
+		Address:     req.Address,
+		ZipCode:     req.ZipCode,
+		Status:      rest.ApplyStatusPending,
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err = h.srvcContext.DBStorage.CreateCharityApply(tx, apply)
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("create charity application error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(ownApplyItem(apply)))
+	logger.Info("response apply charity success.")
+}
+
+// GetCharityApply defines reading the latest application to become charity of the current user
+func (h *RestHandler) GetCharityApply(c *gin.Context) {
+	logger.Info("got get charity application request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	apply, ok := h.queryCharityApply(c, "", claims.UID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(ownApplyItem(apply)))
+	logger.Info("response get charity application success.")
+}
+
+// CharityApplyList defines the review queue of applications to become charity for admin, the earliest submitted first
+func (h *RestHandler) CharityApplyList(c *gin.Context) {
+	logger.Info("got charity application list request")
+
+	if _, ok := h.adminUser(c); !ok {
+		return
+	}
+
+	req := &structs.CharityApplyListRequest{}
+	if err := c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+	logger.Debugf("request params %v", req)
+
+	if req.Status == "" {
+		req.Status = rest.ApplyStatusPending
+	}
+
+	params := &structs.QueryParams{
+		PageNum:   req.PageNum,
+		PageLimit: req.PageLimit,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+
+	result, err := h.srvcContext.DBStorage.QueryCharityApplyList(req.Status, params)
+	if err != nil {
+		e := fmt.Errorf("query charity application list error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	var items []*structs.CharityApplyItem
+	for _, v := range result {
+		items = append(items, applyItem(v))
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.CharityApplyListResp{
+		Total:     params.Total,
+		PageNum:   params.PageNum,
+		PageLimit: params.PageLimit,
+		Results:   items,
+	}))
+	logger.Info("response charity application list success.")
+}
+
+// ReviewCharityApply defines approving or rejecting the pending application by admin, on approval the account
+// is switched to charity with its registration address, logo, bank details and donation statistics
+func (h *RestHandler) ReviewCharityApply(c *gin.Context) {
+	logger.Info("got review charity application request")
+
+	if _, ok := h.adminUser(c); !ok {
+		return
+	}
+
+	req := &structs.CharityApplyReviewRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if !req.Approved && req.Remark == "" {
+		e := fmt.Errorf("remark required on rejection")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.MissingParamsErrCode, e.Error()))
+		return
+	}
+
+	apply, ok := h.queryCharityApply(c, req.ID, "")
+	if !ok {
+		return
+	}
+
+	if apply.Status != rest.ApplyStatusPending {
+		e := fmt.Errorf("application %s reviewed already", req.ID)
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.ApplyNotPending, e.Error()))
+		return
+	}
+
+	status := rest.ApplyStatusRejected
+	var acc *models.Account
+	if req.Approved {
+		status = rest.ApplyStatusApproved
+		if acc, ok = h.queryAccount(c, apply.UID); !ok {
+			return
+		}
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err := h.srvcContext.DBStorage.UpdateCharityApplyStatus(tx, req.ID, status, req.Remark)
+	if err == gorm.ErrRecordNotFound {
+		// reviewed by another admin after queried
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("application %s reviewed already", req.ID)
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.ApplyNotPending, e.Error()))
+		return
+	}
+	if err == nil && req.Approved {
+		err = h.onboardCharity(tx, acc, apply)
+	}
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("review charity application error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	apply.Status = status
+	apply.Remark = req.Remark
+	c.JSON(http.StatusOK, rest.SuccessResponse(applyItem(apply)))
+	logger.Info("response review charity application success.")
+}
+
+// onboardCharity switches the account to charity, the kyc of person does not count for charity
+func (h *RestHandler) onboardCharity(tx *gorm.DB, acc *models.Account, apply *models.CharityApply) error {
+	fields := map[string]interface{}{
+		"type":          rest.UserTypeOrgCharity,
+		"nick_name":     apply.Name,
+		"bank":          apply.Bank,
+		"bank_card_num": apply.BankCardNum,
+		"remark":        apply.Description,
+	}
+	if acc.Type != rest.UserTypeOrg {
+		fields["kyc_status"] = ""
+	}
+
+	err := h.srvcContext.DBStorage.UpdateAccount(tx, acc.ID, fields)
+	if err != nil {
+		return err
+	}
+
+	// the registration address of previous application is replaced
+	err = h.srvcContext.DBStorage.DeleteAddresses(tx, acc.ID, rest.AddrReg)
+	if err != nil {
+		return err
+	}
+
+	err = h.srvcContext.DBStorage.CreateAddresses(tx, []*models.Address{
+		{
+			ID:        utils.GenerateUUID(),
+			UID:       acc.ID,
+			RelatedID: apply.ID,
+			Type:      rest.AddrReg,
+			Country:   apply.Country,
+			Province:  apply.Province,
+			City:      apply.City,
+			District:  apply.District,
+			Address:   apply.Address,
+			ZipCode:   apply.ZipCode,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the logo of charity is the avatar of account
+	err = h.srvcContext.DBStorage.DeleteImages(tx, acc.ID, rest.ImageAvatar)
+	if err != nil {
+		return err
+	}
+
+	err = h.srvcContext.DBStorage.CreateImages(tx, []*models.Image{
+		{
+			ID:        utils.GenerateUUID(),
+			RelatedID: acc.ID,
+			Type:      rest.ImageAvatar,
+			URL:       apply.Logo,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// the statistics kept from before, e.g. the charity approved again, is not reset
+	return h.srvcContext.DBStorage.AddDonationStat(tx, &models.DonationStat{
+		UID:              acc.ID,
+		ReceivedFunds:    decimal.Zero,
+		DistributedFunds: decimal.Zero,
+	})
+}
+
+// queryCharityApply returns the application by id, or the latest one of user
+func (h *RestHandler) queryCharityApply(c *gin.Context, id, uid string) (*models.CharityApply, bool) {
+	apply, err := h.srvcContext.DBStorage.QueryCharityApply(id, uid)
+	if err != nil {
+		e := fmt.Errorf("query charity application error, %s", err.Error())
+		logger.Error(e)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return nil, false
+	}
+
+	return apply, true
+}
+
+// applyItem returns the application to become charity
+func applyItem(apply *models.CharityApply) *structs.CharityApplyItem {
+	return &structs.CharityApplyItem{
+		ID:          apply.ID,
+		UID:         apply.UID,
+		Name:        apply.Name,
+		Logo:        apply.Logo,
+		Bank:        apply.Bank,
+		BankCardNum: apply.BankCardNum,
+		Description: apply.Description,
+		Country:     apply.Country,
+		Province:    apply.Province,
+		City:        apply.City,
+		District:    apply.District,
+		Address:     apply.Address,
+		ZipCode:     apply.ZipCode,
+		Status:      apply.Status,
+		Remark:      apply.Remark,
+		CreatedAt:   apply.CreatedAt.Unix(),
+	}
+}
+
+// ownApplyItem returns the application read by the applicant, the bank card num is masked
+func ownApplyItem(apply *models.CharityApply) *structs.CharityApplyItem {
+	item := applyItem(apply)
+	item.BankCardNum = utils.MaskString(item.BankCardNum, maskKeep)
+	return item
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	urlAccApply       = "api/v1/acc/charity/apply"
+	urlAccApplyReview = "api/v1/acc/charity/apply/review"
+)
+
+var (
+	applyRequest = &structs.CharityApplyRequest{
+		Name:        "charity",
+		Logo:        "https://image/logo.png",
+		Bank:        "bank",
+		BankCardNum: "6222020200112233445",
+		Description: "description",
+		Province:    "beijing",
+		Address:     "address",
+	}
+)
+
+func TestApplyCharity(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockDB.EXPECT().QueryCharityApply("", "uid").Return(&models.CharityApply{ID: "apply_id", Status: rest.ApplyStatusRejected}, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().CreateCharityApply(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(applyRequest)
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccApply, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ApplyCharity(c)
+	CommRespCheck(t, w)
+
+	if bytes.Contains(w.Body.Bytes(), []byte(applyRequest.BankCardNum)) {
+		t.Error("bank card num not masked")
+	}
+}
+
+func TestApplyCharityUnderReview(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryCharityApply("", "uid").Return(&models.CharityApply{ID: "apply_id", Status: rest.ApplyStatusPending}, nil)
+
+	// mock request
+	body, _ := json.Marshal(applyRequest)
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccApply, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ApplyCharity(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("application under review check failed")
+	}
+}
+
+func TestApplyCharityAlready(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	body, _ := json.Marshal(applyRequest)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "charity_uid", UserType: rest.UserTypeOrgCharity, Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccApply, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ApplyCharity(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("charity apply again check failed")
+	}
+}
+
+func TestReviewCharityApplyApproved(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockDB.EXPECT().QueryCharityApply("apply_id", "").Return(&models.CharityApply{
+		ID:          "apply_id",
+		UID:         "uid",
+		Name:        "charity",
+		Logo:        "https://image/logo.png",
+		Bank:        "bank",
+		BankCardNum: "6222020200112233445",
+		Description: "description",
+		Status:      rest.ApplyStatusPending,
+	}, nil)
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", Type: rest.UserTypeNormal, KycStatus: rest.KycStatusApproved}, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateCharityApplyStatus(db, "apply_id", rest.ApplyStatusApproved, "").Return(nil)
+	mockDB.EXPECT().UpdateAccount(db, "uid", map[string]interface{}{
+		"type":          rest.UserTypeOrgCharity,
+		"nick_name":     "charity",
+		"bank":          "bank",
+		"bank_card_num": "6222020200112233445",
+		"remark":        "description",
+		"kyc_status":    "",
+	}).Return(nil)
+	mockDB.EXPECT().DeleteAddresses(db, "uid", rest.AddrReg).Return(nil)
+	mockDB.EXPECT().CreateAddresses(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().DeleteImages(db, "uid", rest.ImageAvatar).Return(nil)
+	mockDB.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().AddDonationStat(db, &models.DonationStat{UID: "uid", ReceivedFunds: decimal.Zero, DistributedFunds: decimal.Zero}).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(&structs.CharityApplyReviewRequest{ID: "apply_id", Approved: true})
+	setAdmin(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccApplyReview, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReviewCharityApply(c)
+	CommRespCheck(t, w)
+}
+
+func TestReviewCharityApplyRejected(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockDB.EXPECT().QueryCharityApply("apply_id", "").Return(&models.CharityApply{ID: "apply_id", UID: "uid", Status: rest.ApplyStatusPending}, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateCharityApplyStatus(db, "apply_id", rest.ApplyStatusRejected, "bank card not matched").Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(&structs.CharityApplyReviewRequest{ID: "apply_id", Remark: "bank card not matched"})
+	setAdmin(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccApplyReview, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReviewCharityApply(c)
+	CommRespCheck(t, w)
+}
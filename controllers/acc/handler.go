@@ -24,3 +24,10 @@ type RestHandler struct {
 func NewRestHandler(c *context.Context) (*RestHandler, error) {
 	return &RestHandler{srvcContext: c}, nil
 }
+
+// redisDo runs the redis command on a connection of the pool, which is released right after
+func (h *RestHandler) redisDo(cmd string, args ...interface{}) (interface{}, error) {
+	conn := h.srvcContext.RedisPool.Get()
+	defer conn.Close()
+	return conn.Do(cmd, args...)
+}
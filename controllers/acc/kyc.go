@@ -0,0 +1,497 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	certTypeIDCard = "id_card" // default certification type of person
+)
+
+var (
+	// unified social credit code of organization, 18 characters without I, O, Z, S and V
+	creditCodePattern = regexp.MustCompile(`^[0-9A-HJ-NPQRTUWXY]{2}[0-9]{6}[0-9A-HJ-NPQRTUWXY]{10}$`)
+)
+
+// SubmitPersonKyc defines submitting the kyc of the current user with the images of id card
+func (h *RestHandler) SubmitPersonKyc(c *gin.Context) {
+	logger.Info("got submit person kyc request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.PersonKycRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if kycTypeOf(claims.UserType) != rest.KycTypePerson {
+		e := fmt.Errorf("user type %s must submit kyc of organization", claims.UserType)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return
+	}
+
+	if !h.checkKycSubmittable(c, claims.UID) {
+		return
+	}
+
+	if req.CertType == "" {
+		req.CertType = certTypeIDCard
+	}
+
+	kyc := &models.PersonKyc{
+		ID:          utils.GenerateUUID(),
+		UID:         claims.UID,
+		RealName:    req.RealName,
+		Gender:      req.Gender,
+		CertType:    req.CertType,
+		CertNum:     req.CertNum,
+		CertExpired: req.CertExpired,
+		Status:      rest.KycStatusPending,
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err := h.srvcContext.DBStorage.CreatePersonKyc(tx, kyc)
+	if err == nil {
+		err = h.createKycImages(tx, claims.UID, kyc.ID, req.IDCardHead, req.IDCardBack)
+	}
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("create person kyc error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(personKycItem(kyc, req.IDCardHead, req.IDCardBack)))
+	logger.Info("response submit person kyc success.")
+}
+
+// SubmitOrgKyc defines submitting the kyc of the current organization with its credit code
+func (h *RestHandler) SubmitOrgKyc(c *gin.Context) {
+	logger.Info("got submit organization kyc request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.OrgKycRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if kycTypeOf(claims.UserType) != rest.KycTypeOrg {
+		e := fmt.Errorf("user type %s must submit kyc of person", claims.UserType)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return
+	}
+
+	if !creditCodePattern.MatchString(req.CreditCode) {
+		e := fmt.Errorf("credit code invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if !h.checkKycSubmittable(c, claims.UID) {
+		return
+	}
+
+	kyc := &models.OrgKyc{
+		ID:          utils.GenerateUUID(),
+		UID:         claims.UID,
+		LegalPerson: req.LegalPerson,
+		CreditCode:  req.CreditCode,
+		Name:        req.Name,
+		Region:      req.Region,
+		CertType:    req.CertType,
+		Type:        req.OrgType,
+		Expired:     req.Expired,
+		Status:      rest.KycStatusPending,
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err := h.srvcContext.DBStorage.CreateOrgKyc(tx, kyc)
+	if err == nil {
+		err = h.createKycImages(tx, claims.UID, kyc.ID, req.IDCardHead, req.IDCardBack)
+	}
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("create organization kyc error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(orgKycItem(kyc, req.IDCardHead, req.IDCardBack)))
+	logger.Info("response submit organization kyc success.")
+}
+
+// GetKyc defines reading the latest kyc submitted by the current user
+func (h *RestHandler) GetKyc(c *gin.Context) {
+	logger.Info("got get kyc request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.queryKyc(kycTypeOf(claims.UserType), "", claims.UID)
+	if err != nil {
+		e := fmt.Errorf("query kyc error, %s", err.Error())
+		logger.Error(e)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(item))
+	logger.Info("response get kyc success.")
+}
+
+// KycList defines the review queue of kyc for admin, the earliest submitted first
+func (h *RestHandler) KycList(c *gin.Context) {
+	logger.Info("got kyc list request")
+
+	if _, ok := h.adminUser(c); !ok {
+		return
+	}
+
+	req := &structs.KycListRequest{}
+	if err := c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+	logger.Debugf("request params %v", req)
+
+	if req.Status == "" {
+		req.Status = rest.KycStatusPending
+	}
+
+	params := &structs.QueryParams{
+		PageNum:   req.PageNum,
+		PageLimit: req.PageLimit,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+
+	var items []*structs.KycItem
+	var err error
+	switch req.KycType {
+	case rest.KycTypePerson:
+		var result []*models.PersonKyc
+		result, err = h.srvcContext.DBStorage.QueryPersonKycList(req.Status, params)
+		for _, v := range result {
+			items = append(items, personKycItem(v, "", ""))
+		}
+	case rest.KycTypeOrg:
+		var result []*models.OrgKyc
+		result, err = h.srvcContext.DBStorage.QueryOrgKycList(req.Status, params)
+		for _, v := range result {
+			items = append(items, orgKycItem(v, "", ""))
+		}
+	default:
+		e := fmt.Errorf("kyc type %s invalid", req.KycType)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if err == nil {
+		for _, v := range items {
+			if err = h.fillKycImages(v); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		e := fmt.Errorf("query kyc list error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.KycListResp{
+		Total:     params.Total,
+		PageNum:   params.PageNum,
+		PageLimit: params.PageLimit,
+		Results:   items,
+	}))
+	logger.Info("response kyc list success.")
+}
+
+// ReviewKyc defines approving or rejecting the pending kyc by admin, the status is kept on the account too
+func (h *RestHandler) ReviewKyc(c *gin.Context) {
+	logger.Info("got review kyc request")
+
+	if _, ok := h.adminUser(c); !ok {
+		return
+	}
+
+	req := &structs.KycReviewRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if req.KycType != rest.KycTypePerson && req.KycType != rest.KycTypeOrg {
+		e := fmt.Errorf("kyc type %s invalid", req.KycType)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if !req.Approved && req.Remark == "" {
+		e := fmt.Errorf("remark required on rejection")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.MissingParamsErrCode, e.Error()))
+		return
+	}
+
+	item, err := h.queryKyc(req.KycType, req.ID, "")
+	if err != nil {
+		e := fmt.Errorf("query kyc error, %s", err.Error())
+		logger.Error(e)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	if item.Status != rest.KycStatusPending {
+		e := fmt.Errorf("kyc %s reviewed already", req.ID)
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.KycNotPending, e.Error()))
+		return
+	}
+
+	status := rest.KycStatusRejected
+	if req.Approved {
+		status = rest.KycStatusApproved
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err = h.srvcContext.DBStorage.UpdateKycStatus(tx, req.KycType, req.ID, status, req.Remark)
+	if err == gorm.ErrRecordNotFound {
+		// reviewed by another admin after queried
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("kyc %s reviewed already", req.ID)
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.KycNotPending, e.Error()))
+		return
+	}
+	if err == nil {
+		err = h.srvcContext.DBStorage.UpdateAccount(tx, item.UID, map[string]interface{}{"kyc_status": status})
+	}
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update kyc status error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	item.Status = status
+	item.Remark = req.Remark
+	c.JSON(http.StatusOK, rest.SuccessResponse(item))
+	logger.Info("response review kyc success.")
+}
+
+// checkKycSubmittable checks the account has no kyc waiting for review or approved
+func (h *RestHandler) checkKycSubmittable(c *gin.Context, uid string) bool {
+	acc, ok := h.queryAccount(c, uid)
+	if !ok {
+		return false
+	}
+
+	switch acc.KycStatus {
+	case rest.KycStatusPending:
+		e := fmt.Errorf("kyc submitted before is waiting for review")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.KycUnderReview, e.Error()))
+		return false
+	case rest.KycStatusApproved:
+		e := fmt.Errorf("kyc approved already")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.KycAlreadyApproved, e.Error()))
+		return false
+	}
+
+	return true
+}
+
+// createKycImages saves the images of id card to the kyc and marks the kyc of account pending
+func (h *RestHandler) createKycImages(tx *gorm.DB, uid, kycID, head, back string) error {
+	err := h.srvcContext.DBStorage.CreateImages(tx, []*models.Image{
+		{
+			ID:        utils.GenerateUUID(),
+			RelatedID: kycID,
+			Type:      rest.ImageIDCardHead,
+			URL:       head,
+		},
+		{
+			ID:        utils.GenerateUUID(),
+			RelatedID: kycID,
+			Type:      rest.ImageIDCardBack,
+			URL:       back,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.srvcContext.DBStorage.UpdateAccount(tx, uid, map[string]interface{}{"kyc_status": rest.KycStatusPending})
+}
+
+// queryKyc returns the kyc by id, or the latest one of user, with the images of id card
+func (h *RestHandler) queryKyc(kycType, id, uid string) (*structs.KycItem, error) {
+	var item *structs.KycItem
+	switch kycType {
+	case rest.KycTypePerson:
+		kyc, err := h.srvcContext.DBStorage.QueryPersonKyc(id, uid)
+		if err != nil {
+			return nil, err
+		}
+		item = personKycItem(kyc, "", "")
+	default:
+		kyc, err := h.srvcContext.DBStorage.QueryOrgKyc(id, uid)
+		if err != nil {
+			return nil, err
+		}
+		item = orgKycItem(kyc, "", "")
+	}
+
+	if err := h.fillKycImages(item); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// fillKycImages sets the latest images of id card of the kyc
+func (h *RestHandler) fillKycImages(item *structs.KycItem) error {
+	images, err := h.srvcContext.DBStorage.QueryImages(item.ID, "")
+	if err != nil {
+		return err
+	}
+
+	for _, v := range images {
+		switch v.Type {
+		case rest.ImageIDCardHead:
+			item.IDCardHead = v.URL
+		case rest.ImageIDCardBack:
+			item.IDCardBack = v.URL
+		}
+	}
+
+	return nil
+}
+
+// adminUser returns the user of access token, only admin is allowed
+func (h *RestHandler) adminUser(c *gin.Context) (*auth.Claims, bool) {
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	if claims.UserType != rest.UserTypeAdmin {
+		e := fmt.Errorf("user type %s not allowed", claims.UserType)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// kycTypeOf returns the type of kyc submitted by the user type, organization and charity submit kyc of organization
+func kycTypeOf(userType string) string {
+	if userType == rest.UserTypeOrg || userType == rest.UserTypeOrgCharity {
+		return rest.KycTypeOrg
+	}
+
+	return rest.KycTypePerson
+}
+
+// personKycItem returns the kyc of person with the images of id card
+func personKycItem(kyc *models.PersonKyc, head, back string) *structs.KycItem {
+	return &structs.KycItem{
+		ID:         kyc.ID,
+		UID:        kyc.UID,
+		KycType:    rest.KycTypePerson,
+		Status:     kyc.Status,
+		Remark:     kyc.Remark,
+		RealName:   kyc.RealName,
+		Gender:     kyc.Gender,
+		CertType:   kyc.CertType,
+		CertNum:    kyc.CertNum,
+		Expired:    kyc.CertExpired,
+		IDCardHead: head,
+		IDCardBack: back,
+		CreatedAt:  kyc.CreatedAt.Unix(),
+	}
+}
+
+// orgKycItem returns the kyc of organization with the images of id card of legal person
+func orgKycItem(kyc *models.OrgKyc, head, back string) *structs.KycItem {
+	return &structs.KycItem{
+		ID:          kyc.ID,
+		UID:         kyc.UID,
+		KycType:     rest.KycTypeOrg,
+		Status:      kyc.Status,
+		Remark:      kyc.Remark,
+		CertType:    kyc.CertType,
+		Name:        kyc.Name,
+		CreditCode:  kyc.CreditCode,
+		LegalPerson: kyc.LegalPerson,
+		Region:      kyc.Region,
+		OrgType:     kyc.Type,
+		Expired:     kyc.Expired,
+		IDCardHead:  head,
+		IDCardBack:  back,
+		CreatedAt:   kyc.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	urlAccKycPerson = "api/v1/acc/kyc/person"
+	urlAccKycOrg    = "api/v1/acc/kyc/org"
+	urlAccKycList   = "api/v1/acc/kyc/list"
+	urlAccKycReview = "api/v1/acc/kyc/review"
+)
+
+// setAdmin sets the admin user of access token
+func setAdmin(c *gin.Context) {
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "admin_uid", UserType: rest.UserTypeAdmin, Kind: auth.TokenAccess})
+}
+
+func TestSubmitPersonKyc(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", KycStatus: rest.KycStatusRejected}, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().CreatePersonKyc(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().UpdateAccount(db, "uid", map[string]interface{}{"kyc_status": rest.KycStatusPending}).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(&structs.PersonKycRequest{
+		RealName:   "real name",
+		CertNum:    "110101199003074477",
+		IDCardHead: "https://image/head.png",
+		IDCardBack: "https://image/back.png",
+	})
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccKycPerson, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.SubmitPersonKyc(c)
+	CommRespCheck(t, w)
+}
+
+func TestSubmitPersonKycUnderReview(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", KycStatus: rest.KycStatusPending}, nil)
+
+	// mock request
+	body, _ := json.Marshal(&structs.PersonKycRequest{
+		RealName:   "real name",
+		CertNum:    "110101199003074477",
+		IDCardHead: "https://image/head.png",
+		IDCardBack: "https://image/back.png",
+	})
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccKycPerson, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.SubmitPersonKyc(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("kyc under review check failed")
+	}
+}
+
+func TestSubmitOrgKycInvalidCreditCode(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	body, _ := json.Marshal(&structs.OrgKycRequest{
+		Name:        "charity",
+		CreditCode:  "91110000600037341O",
+		LegalPerson: "legal person",
+		IDCardHead:  "https://image/head.png",
+		IDCardBack:  "https://image/back.png",
+	})
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "charity_uid", UserType: rest.UserTypeOrgCharity, Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccKycOrg, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.SubmitOrgKyc(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("credit code check failed")
+	}
+}
+
+func TestKycListNotAdmin(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodGet, urlAccKycList+"?kyc_type=person", nil)
+	handler.KycList(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("admin check failed")
+	}
+}
+
+func TestKycList(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryOrgKycList(rest.KycStatusPending, gomock.Any()).Return([]*models.OrgKyc{
+		{ID: "kyc_id", UID: "charity_uid", Name: "charity", Status: rest.KycStatusPending},
+	}, nil)
+	mockDB.EXPECT().QueryImages("kyc_id", "").Return([]*models.Image{
+		{Type: rest.ImageIDCardHead, URL: "https://image/head.png"},
+		{Type: rest.ImageIDCardBack, URL: "https://image/back.png"},
+	}, nil)
+
+	// mock request
+	setAdmin(c)
+	c.Request, _ = http.NewRequest(http.MethodGet, urlAccKycList+"?kyc_type=org", nil)
+	handler.KycList(c)
+	CommRespCheck(t, w)
+}
+
+func TestReviewKycApproved(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockDB.EXPECT().QueryOrgKyc("kyc_id", "").Return(&models.OrgKyc{ID: "kyc_id", UID: "charity_uid", Status: rest.KycStatusPending}, nil)
+	mockDB.EXPECT().QueryImages("kyc_id", "").Return(nil, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateKycStatus(db, rest.KycTypeOrg, "kyc_id", rest.KycStatusApproved, "").Return(nil)
+	mockDB.EXPECT().UpdateAccount(db, "charity_uid", map[string]interface{}{"kyc_status": rest.KycStatusApproved}).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(&structs.KycReviewRequest{KycType: rest.KycTypeOrg, ID: "kyc_id", Approved: true})
+	setAdmin(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccKycReview, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReviewKyc(c)
+	CommRespCheck(t, w)
+}
+
+func TestReviewKycRejectWithoutRemark(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	body, _ := json.Marshal(&structs.KycReviewRequest{KycType: rest.KycTypePerson, ID: "kyc_id"})
+	setAdmin(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccKycReview, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReviewKyc(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("remark of rejection check failed")
+	}
+}
+
+func TestReviewKycNotPending(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryPersonKyc("kyc_id", "").Return(&models.PersonKyc{ID: "kyc_id", UID: "uid", Status: rest.KycStatusApproved}, nil)
+	mockDB.EXPECT().QueryImages("kyc_id", "").Return(nil, nil)
+
+	// mock request
+	body, _ := json.Marshal(&structs.KycReviewRequest{KycType: rest.KycTypePerson, ID: "kyc_id", Remark: "blurred image"})
+	setAdmin(c)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccKycReview, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReviewKyc(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("reviewed kyc check failed")
+	}
+}
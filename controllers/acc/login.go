@@ -0,0 +1,447 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	smsCodeKey      = "acc:sms:code:%s"      // sms code sent to phone
+	smsIntervalKey  = "acc:sms:interval:%s"  // set while another sms code to phone is not allowed
+	smsFailureKey   = "acc:sms:failure:%s"   // wrong sms codes tried for phone
+	loginFailureKey = "acc:login:failure:%s" // failed password logins in a row of account
+
+	maxSmsFailures = 5 // the sms code is dropped after the wrong ones tried
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+// SendSmsCode defines sending the sms code to phone, to register or login from app or web
+func (h *RestHandler) SendSmsCode(c *gin.Context) {
+	logger.Info("got send sms code request")
+
+	req := &structs.SmsCodeRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if !phonePattern.MatchString(req.Phone) {
+		e := fmt.Errorf("invalid phone %s", req.Phone)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	ttl, interval := h.srvcContext.Config.SMS.CodeLimit()
+	_, err := redis.String(h.redisDo(rest.RedisSet, fmt.Sprintf(smsIntervalKey, req.Phone), 1, rest.RedisEX, interval, rest.RedisNX))
+	if err != nil {
+		if err == redis.ErrNil {
+			e := fmt.Errorf("sms code sent to %s already, retry after %d seconds", req.Phone, interval)
+			logger.Error(e)
+			c.JSON(http.StatusTooManyRequests, rest.ErrorResponse(rest.SmsSendTooOften, e.Error()))
+			return
+		}
+
+		e := fmt.Errorf("save sms interval error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	code, err := smsCode()
+	if err != nil {
+		e := fmt.Errorf("generate sms code error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	_, err = h.redisDo(rest.RedisSet, fmt.Sprintf(smsCodeKey, req.Phone), code, rest.RedisEX, ttl)
+	if err == nil {
+		_, err = h.redisDo(rest.RedisDel, fmt.Sprintf(smsFailureKey, req.Phone))
+	}
+	if err != nil {
+		e := fmt.Errorf("save sms code error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	if err = h.srvcContext.SMSSender.Send(req.Phone, code); err != nil {
+		e := fmt.Errorf("send sms code error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.SmsCodeResp{
+		Phone:     req.Phone,
+		ExpiresIn: ttl,
+	}))
+	logger.Info("response send sms code success.")
+}
+
+// Register defines registering the account of app or web user with phone verified by sms code and password
+func (h *RestHandler) Register(c *gin.Context) {
+	logger.Info("got register request")
+
+	req := &structs.LoginRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if !h.checkSource(c, req.Source) {
+		return
+	}
+
+	if req.Phone == "" || req.SmsCode == "" || req.Password == "" {
+		e := fmt.Errorf("phone, sms code and password are required")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.MissingParamsErrCode, e.Error()))
+		return
+	}
+
+	password, err := auth.HashPassword(req.Password)
+	if err != nil {
+		logger.Error(err)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.PasswordTooWeak, err.Error()))
+		return
+	}
+
+	if !h.verifySmsCode(c, req.Phone, req.SmsCode) {
+		return
+	}
+
+	_, err = h.srvcContext.DBStorage.QueryAccountByLogin(req.Access, req.Phone, req.Email)
+	if err == nil || err == models.ErrDuplicated {
+		e := fmt.Errorf("user name, phone or email registered already")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.AccountConflict, e.Error()))
+		return
+	}
+
+	if err != gorm.ErrRecordNotFound {
+		e := fmt.Errorf("query account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	id := utils.GenerateUUID()
+	bcResp, err := h.srvcContext.IBCAdapter.Register(id)
+	if err != nil {
+		e := fmt.Errorf("register on block chain failed, %v", err)
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	acc := &models.Account{
+		ID:       id,
+		Access:   req.Access,
+		Password: password,
+		NickName: req.Nickname,
+		Type:     rest.UserTypeNormal,
+		Phone:    req.Phone,
+		Email:    req.Email,
+		Remark:   req.Remark,
+		DID:      bcResp.Data.ID,
+		Source:   req.Source,
+	}
+
+	err = h.srvcContext.DBStorage.CreateAccount(acc)
+	if err == models.ErrDuplicated {
+		e := fmt.Errorf("user name, phone or email registered already")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.AccountConflict, e.Error()))
+		return
+	}
+
+	if err != nil {
+		e := fmt.Errorf("create account error, %v", err)
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	h.loginSucceed(c, acc, "", utils.GenerateUUID(), "")
+}
+
+// Login defines the login of app or web user, by user name, phone or email with password, or by phone
+// with sms code. The account is locked after too many wrong passwords in a row, sms code login still
+// works while locked
+func (h *RestHandler) Login(c *gin.Context) {
+	logger.Info("got login request")
+
+	req := &structs.LoginRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if !h.checkSource(c, req.Source) {
+		return
+	}
+
+	if req.SmsCode != "" {
+		h.loginBySmsCode(c, req)
+		return
+	}
+
+	if req.Password == "" || countNotEmpty(req.Access, req.Phone, req.Email) != 1 {
+		e := fmt.Errorf("password and one of user name, phone or email are required")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.MissingParamsErrCode, e.Error()))
+		return
+	}
+
+	acc, ok := h.loginAccount(c, req.Access, req.Phone, req.Email)
+	if !ok {
+		return
+	}
+
+	maxFailures, lockTTL := h.srvcContext.Config.AuthCfg.LoginLimit()
+	failureKey := fmt.Sprintf(loginFailureKey, acc.ID)
+	failures, err := redis.Int64(h.redisDo(rest.RedisGet, failureKey))
+	if err != nil && err != redis.ErrNil {
+		e := fmt.Errorf("query login failures error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	if failures >= maxFailures {
+		e := fmt.Errorf("account locked after %d failed logins, retry later or login by sms code", failures)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.AccountLocked, e.Error()))
+		return
+	}
+
+	if !auth.CheckPassword(acc.Password, req.Password) {
+		failures, err = redis.Int64(h.redisDo(rest.RedisIncr, failureKey))
+		if err == nil {
+			_, err = h.redisDo(rest.RedisExpire, failureKey, lockTTL)
+		}
+		if err != nil {
+			e := fmt.Errorf("save login failures error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return
+		}
+
+		e := fmt.Errorf("account or password wrong, %d attempts left", maxFailures-failures)
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.LoginFailed, e.Error()))
+		return
+	}
+
+	if _, err = h.redisDo(rest.RedisDel, failureKey); err != nil {
+		e := fmt.Errorf("clear login failures error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	h.loginSucceed(c, acc, "", utils.GenerateUUID(), "")
+}
+
+// loginBySmsCode logs in the account of phone verified by sms code
+func (h *RestHandler) loginBySmsCode(c *gin.Context, req *structs.LoginRequest) {
+	if req.Phone == "" {
+		e := fmt.Errorf("phone is required to login by sms code")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.MissingParamsErrCode, e.Error()))
+		return
+	}
+
+	if !h.verifySmsCode(c, req.Phone, req.SmsCode) {
+		return
+	}
+
+	acc, ok := h.loginAccount(c, "", req.Phone, "")
+	if !ok {
+		return
+	}
+
+	h.loginSucceed(c, acc, "", utils.GenerateUUID(), "")
+}
+
+// LinkAccount defines setting the phone verified by sms code and password on the account logged in,
+// so the account of wechat is able to login from app or web
+func (h *RestHandler) LinkAccount(c *gin.Context) {
+	logger.Info("got link account request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.LinkAccountRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	fields := map[string]interface{}{"phone": req.Phone}
+	if req.Password != "" {
+		password, err := auth.HashPassword(req.Password)
+		if err != nil {
+			logger.Error(err)
+			c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.PasswordTooWeak, err.Error()))
+			return
+		}
+		fields["password"] = password
+	}
+
+	if !h.checkLoginFree(c, claims.UID, req.Phone, "") || !h.verifySmsCode(c, req.Phone, req.SmsCode) {
+		return
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err := h.srvcContext.DBStorage.UpdateAccount(tx, claims.UID, fields)
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.LinkAccountResp{
+		UID:   claims.UID,
+		Phone: req.Phone,
+	}))
+	logger.Info("response link account success.")
+}
+
+// loginAccount returns the account to login by user name, phone or email
+func (h *RestHandler) loginAccount(c *gin.Context, access, phone, email string) (*models.Account, bool) {
+	acc, err := h.srvcContext.DBStorage.QueryAccountByLogin(access, phone, email)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			e := fmt.Errorf("account or password wrong")
+			logger.Error(e)
+			c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.LoginFailed, e.Error()))
+			return nil, false
+		}
+
+		e := fmt.Errorf("query account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return nil, false
+	}
+
+	return acc, true
+}
+
+// verifySmsCode checks the sms code sent to phone, the code is used once and dropped after too many
+// wrong ones tried
+func (h *RestHandler) verifySmsCode(c *gin.Context, phone, code string) bool {
+	codeKey, failureKey := fmt.Sprintf(smsCodeKey, phone), fmt.Sprintf(smsFailureKey, phone)
+	saved, err := redis.String(h.redisDo(rest.RedisGet, codeKey))
+	if err != nil && err != redis.ErrNil {
+		e := fmt.Errorf("query sms code error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return false
+	}
+
+	if saved != "" && subtle.ConstantTimeCompare([]byte(saved), []byte(code)) == 1 {
+		if _, err = h.redisDo(rest.RedisDel, codeKey, failureKey); err != nil {
+			e := fmt.Errorf("drop sms code error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return false
+		}
+
+		return true
+	}
+
+	if saved != "" {
+		ttl, _ := h.srvcContext.Config.SMS.CodeLimit()
+		failures, err := redis.Int64(h.redisDo(rest.RedisIncr, failureKey))
+		if err == nil {
+			_, err = h.redisDo(rest.RedisExpire, failureKey, ttl)
+		}
+		if err == nil && failures >= maxSmsFailures {
+			_, err = h.redisDo(rest.RedisDel, codeKey)
+		}
+		if err != nil {
+			e := fmt.Errorf("save sms code failures error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return false
+		}
+	}
+
+	e := fmt.Errorf("sms code of %s wrong or expired", phone)
+	logger.Error(e)
+	c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.SmsCodeInvalid, e.Error()))
+	return false
+}
+
+// checkSource checks the user is from app or web
+func (h *RestHandler) checkSource(c *gin.Context, source string) bool {
+	if source != rest.SourceApp && source != rest.SourceWeb {
+		e := fmt.Errorf("invalid source %s, expect %s or %s", source, rest.SourceApp, rest.SourceWeb)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return false
+	}
+
+	return true
+}
+
+// smsCode generates the random sms code of 6 digits
+func smsCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// countNotEmpty returns the count of not empty strings
+func countNotEmpty(ss ...string) int {
+	n := 0
+	for _, s := range ss {
+		if s != "" {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,323 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	urlAccSmsCode  = "api/v1/acc/sms/code"
+	urlAccRegister = "api/v1/acc/register"
+	urlAccLogin    = "api/v1/acc/login"
+	urlAccLink     = "api/v1/acc/link"
+
+	testPhone    = "18518265711"
+	testPassword = "Aa111111"
+	testSmsCode  = "123456"
+)
+
+// smsRecorder records the sms code sent
+type smsRecorder struct {
+	phone string
+	code  string
+}
+
+func (s *smsRecorder) Send(phone, code string) error {
+	s.phone, s.code = phone, code
+	return nil
+}
+
+func loginBody(req *structs.LoginRequest) *bytes.Buffer {
+	b, _ := json.Marshal(req)
+	return bytes.NewBuffer(b)
+}
+
+func TestSendSmsCode(t *testing.T) {
+	mockCtl, handler, _, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	sender := &smsRecorder{}
+	handler.srvcContext.SMSSender = sender
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+	redisCli.GenericCommand(rest.RedisDel).Expect(int64(0))
+
+	// mock request
+	body, _ := json.Marshal(&structs.SmsCodeRequest{Phone: testPhone})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccSmsCode, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.SendSmsCode(c)
+	CommRespCheck(t, w)
+
+	if sender.phone != testPhone || len(sender.code) != 6 {
+		t.Errorf("sms code check failed, %+v", sender)
+	}
+}
+
+func TestSendSmsCodeTooOften(t *testing.T) {
+	mockCtl, handler, _, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	redisCli.Command(rest.RedisSet, "acc:sms:interval:"+testPhone, 1, rest.RedisEX, int64(60), rest.RedisNX).Expect(nil)
+
+	// mock request
+	body, _ := json.Marshal(&structs.SmsCodeRequest{Phone: testPhone})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccSmsCode, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.SendSmsCode(c)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Error("sms interval check failed")
+	}
+}
+
+func TestRegisterSucceed(t *testing.T) {
+	mockCtl, handler, mockDB, _, mockBCAdapter, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	redisCli.Command(rest.RedisGet, "acc:sms:code:"+testPhone).Expect(testSmsCode)
+	redisCli.GenericCommand(rest.RedisDel).Expect(int64(1))
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+	mockDB.EXPECT().QueryAccountByLogin("user", testPhone, "").Return(nil, gorm.ErrRecordNotFound)
+	mockBCAdapter.EXPECT().Register(gomock.Any()).Return(&structs.RegisterResp{
+		Data: structs.RegisterRespData{ID: "aabbcc"},
+	}, nil)
+	mockDB.EXPECT().CreateAccount(gomock.Any()).DoAndReturn(func(acc *models.Account) error {
+		if acc.Source != rest.SourceWeb || acc.Type != rest.UserTypeNormal || !auth.CheckPassword(acc.Password, testPassword) {
+			t.Errorf("account check failed, %+v", acc)
+		}
+		return nil
+	})
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccRegister, loginBody(&structs.LoginRequest{
+		Access:   "user",
+		Phone:    testPhone,
+		Password: testPassword,
+		SmsCode:  testSmsCode,
+		Source:   rest.SourceWeb,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Register(c)
+	CommRespCheck(t, w)
+}
+
+func TestRegisterSmsCodeInvalid(t *testing.T) {
+	mockCtl, handler, _, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	redisCli.Command(rest.RedisGet, "acc:sms:code:"+testPhone).Expect("654321")
+	redisCli.GenericCommand(rest.RedisIncr).Expect(int64(1))
+	redisCli.GenericCommand(rest.RedisExpire).Expect(int64(1))
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccRegister, loginBody(&structs.LoginRequest{
+		Phone:    testPhone,
+		Password: testPassword,
+		SmsCode:  testSmsCode,
+		Source:   rest.SourceApp,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Register(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("sms code check failed")
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	redisCli.Command(rest.RedisGet, "acc:sms:code:"+testPhone).Expect(testSmsCode)
+	redisCli.GenericCommand(rest.RedisDel).Expect(int64(1))
+	mockDB.EXPECT().QueryAccountByLogin("", testPhone, "").Return(&models.Account{ID: "uid"}, nil)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccRegister, loginBody(&structs.LoginRequest{
+		Phone:    testPhone,
+		Password: testPassword,
+		SmsCode:  testSmsCode,
+		Source:   rest.SourceApp,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Register(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("account conflict check failed")
+	}
+}
+
+func TestLoginPasswordSucceed(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	password, _ := auth.HashPassword(testPassword)
+	mockDB.EXPECT().QueryAccountByLogin("", "", "aaa@icloud.com").Return(&models.Account{
+		ID:       "uid",
+		Type:     rest.UserTypeNormal,
+		Password: password,
+	}, nil)
+	redisCli.Command(rest.RedisGet, "acc:login:failure:uid").Expect(nil)
+	redisCli.Command(rest.RedisDel, "acc:login:failure:uid").Expect(int64(1))
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLogin, loginBody(&structs.LoginRequest{
+		Email:    "aaa@icloud.com",
+		Password: testPassword,
+		Source:   rest.SourceApp,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Login(c)
+	CommRespCheck(t, w)
+}
+
+func TestLoginPasswordWrong(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	password, _ := auth.HashPassword(testPassword)
+	mockDB.EXPECT().QueryAccountByLogin("", testPhone, "").Return(&models.Account{
+		ID:       "uid",
+		Password: password,
+	}, nil)
+	redisCli.Command(rest.RedisGet, "acc:login:failure:uid").Expect(int64(1))
+	redisCli.Command(rest.RedisIncr, "acc:login:failure:uid").Expect(int64(2))
+	redisCli.Command(rest.RedisExpire, "acc:login:failure:uid", int64(900)).Expect(int64(1))
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLogin, loginBody(&structs.LoginRequest{
+		Phone:    testPhone,
+		Password: "Bb222222",
+		Source:   rest.SourceApp,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Login(c)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("wrong password check failed")
+	}
+}
+
+func TestLoginLocked(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	password, _ := auth.HashPassword(testPassword)
+	mockDB.EXPECT().QueryAccountByLogin("user", "", "").Return(&models.Account{
+		ID:       "uid",
+		Password: password,
+	}, nil)
+	redisCli.Command(rest.RedisGet, "acc:login:failure:uid").Expect(int64(5))
+
+	// the right password is refused while locked
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLogin, loginBody(&structs.LoginRequest{
+		Access:   "user",
+		Password: testPassword,
+		Source:   rest.SourceWeb,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Login(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("account lock check failed")
+	}
+}
+
+func TestLoginBySmsCode(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	redisCli.Command(rest.RedisGet, "acc:sms:code:"+testPhone).Expect(testSmsCode)
+	redisCli.GenericCommand(rest.RedisDel).Expect(int64(1))
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+	mockDB.EXPECT().QueryAccountByLogin("", testPhone, "").Return(&models.Account{ID: "uid"}, nil)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLogin, loginBody(&structs.LoginRequest{
+		Phone:   testPhone,
+		SmsCode: testSmsCode,
+		Source:  rest.SourceApp,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Login(c)
+	CommRespCheck(t, w)
+}
+
+func TestLoginSource(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLogin, loginBody(&structs.LoginRequest{
+		Phone:    testPhone,
+		Password: testPassword,
+		Source:   rest.SourceWechat,
+	}))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.Login(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("source check failed")
+	}
+}
+
+func TestLinkAccountSucceed(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	redisCli.Command(rest.RedisGet, "acc:sms:code:"+testPhone).Expect(testSmsCode)
+	redisCli.GenericCommand(rest.RedisDel).Expect(int64(1))
+	mockDB.EXPECT().QueryAccountByLogin("", testPhone, "").Return(nil, gorm.ErrRecordNotFound)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateAccount(db, "uid", gomock.Any()).DoAndReturn(func(_ *gorm.DB, _ string, fields map[string]interface{}) error {
+		if fields["phone"] != testPhone || !auth.CheckPassword(fields["password"].(string), testPassword) {
+			t.Errorf("account fields check failed, %v", fields)
+		}
+		return nil
+	})
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(&structs.LinkAccountRequest{Phone: testPhone, SmsCode: testSmsCode, Password: testPassword})
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLink, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.LinkAccount(c)
+	CommRespCheck(t, w)
+}
+
+func TestLinkAccountConflict(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryAccountByLogin("", testPhone, "").Return(&models.Account{ID: "other_uid"}, nil)
+
+	// mock request
+	body, _ := json.Marshal(&structs.LinkAccountRequest{Phone: testPhone, SmsCode: testSmsCode})
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", AppID: "wx_app_id", Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPost, urlAccLink, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.LinkAccount(c)
+
+	if w.Code != http.StatusConflict {
+		t.Error("phone conflict check failed")
+	}
+}
@@ -0,0 +1,345 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/middleware"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	maskKeep = 4 // characters of bank card num and tax id shown on read
+)
+
+// GetProfile defines reading the profile of the current user
+func (h *RestHandler) GetProfile(c *gin.Context) {
+	logger.Info("got get profile request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	h.respondProfile(c, claims.UID)
+	logger.Info("response get profile success.")
+}
+
+// UpdateProfile defines updating the profile of the current user, the phone changed must be verified by sms code,
+// the email is not editable as its ownership can not be verified
+func (h *RestHandler) UpdateProfile(c *gin.Context) {
+	logger.Info("got update profile request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.UpdateProfileRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	fields := make(map[string]interface{})
+	if req.NickName != "" {
+		fields["nick_name"] = req.NickName
+	}
+
+	if req.Remark != "" {
+		fields["remark"] = req.Remark
+	}
+
+	if req.Phone != "" {
+		if !phonePattern.MatchString(req.Phone) {
+			e := fmt.Errorf("invalid phone %s", req.Phone)
+			logger.Error(e)
+			c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+			return
+		}
+
+		if !h.checkLoginFree(c, claims.UID, req.Phone, "") || !h.verifySmsCode(c, req.Phone, req.SmsCode) {
+			return
+		}
+		fields["phone"] = req.Phone
+	}
+
+	if len(fields) == 0 && req.Avatar == "" {
+		e := fmt.Errorf("nothing to update")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.MissingParamsErrCode, e.Error()))
+		return
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	if len(fields) > 0 {
+		err := h.srvcContext.DBStorage.UpdateAccount(tx, claims.UID, fields)
+		if err != nil {
+			h.srvcContext.DBStorage.DBTransactionRollback(tx)
+			e := fmt.Errorf("update account error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return
+		}
+	}
+
+	// the avatar of user is kept as image of account, replaced on each update
+	if req.Avatar != "" {
+		err := h.srvcContext.DBStorage.DeleteImages(tx, claims.UID, rest.ImageAvatar)
+		if err == nil {
+			err = h.srvcContext.DBStorage.CreateImages(tx, []*models.Image{
+				{
+					ID:        utils.GenerateUUID(),
+					RelatedID: claims.UID,
+					Type:      rest.ImageAvatar,
+					URL:       req.Avatar,
+				},
+			})
+		}
+		if err != nil {
+			h.srvcContext.DBStorage.DBTransactionRollback(tx)
+			e := fmt.Errorf("update avatar error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return
+		}
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	h.respondProfile(c, claims.UID)
+	logger.Info("response update profile success.")
+}
+
+// GetBank defines reading the bank details of the current user, the card num and tax id are masked
+func (h *RestHandler) GetBank(c *gin.Context) {
+	logger.Info("got get bank details request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	acc, ok := h.queryAccount(c, claims.UID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(bankResp(acc.ID, acc.Bank, acc.BankCardNum, acc.TaxID)))
+	logger.Info("response get bank details success.")
+}
+
+// UpdateBank defines updating the bank details of the current user
+func (h *RestHandler) UpdateBank(c *gin.Context) {
+	logger.Info("got update bank details request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.BankRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if !utils.IsBankCardNum(req.BankCardNum) {
+		e := fmt.Errorf("invalid bank card num")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err := h.srvcContext.DBStorage.UpdateAccount(tx, claims.UID, map[string]interface{}{
+		"bank":          req.Bank,
+		"bank_card_num": req.BankCardNum,
+		"tax_id":        req.TaxID,
+	})
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(bankResp(claims.UID, req.Bank, req.BankCardNum, req.TaxID)))
+	logger.Info("response update bank details success.")
+}
+
+// AccPubList defines the funds and supplies published by the current user, the latest first
+func (h *RestHandler) AccPubList(c *gin.Context) {
+	logger.Info("got account publicity list request")
+
+	claims, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.AccPubRequest{}
+	if err := c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+	logger.Debugf("request params %v", req)
+
+	if req.ChainStatus != "" && !models.IsChainStatus(req.ChainStatus) {
+		e := fmt.Errorf("chain status invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	params := &structs.QueryParams{
+		PageNum:     req.PageNum,
+		PageLimit:   req.PageLimit,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		ChainStatus: req.ChainStatus,
+	}
+
+	result, err := h.srvcContext.DBStorage.QueryPubByUID(claims.UID, req.PubType, params)
+	if err != nil {
+		e := fmt.Errorf("query publicity error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	var fundsNum, suppliesNum int64
+	for _, v := range result {
+		v.ConvertTime()
+		v.Count(&fundsNum, &suppliesNum)
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.PubUserResp{
+		Total:       params.Total,
+		PageNum:     params.PageNum,
+		PageLimit:   params.PageLimit,
+		StartTime:   params.StartTime,
+		EndTime:     params.EndTime,
+		SuppliesNum: suppliesNum,
+		FundsNum:    fundsNum,
+		Results:     result,
+	}))
+	logger.Info("response account publicity list success.")
+}
+
+// respondProfile responses the profile of account with its avatar
+func (h *RestHandler) respondProfile(c *gin.Context, uid string) {
+	acc, ok := h.queryAccount(c, uid)
+	if !ok {
+		return
+	}
+
+	avatars, err := h.srvcContext.DBStorage.QueryImages(uid, rest.ImageAvatar)
+	if err != nil {
+		e := fmt.Errorf("query avatar error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	resp := &structs.ProfileResp{
+		UID:       acc.ID,
+		Access:    acc.Access,
+		NickName:  acc.NickName,
+		UserType:  acc.Type,
+		Phone:     acc.Phone,
+		Email:     acc.Email,
+		Remark:    acc.Remark,
+		KycStatus: acc.KycStatus,
+		DID:       acc.DID,
+		Source:    acc.Source,
+		CreatedAt: acc.CreatedAt.Unix(),
+	}
+
+	if len(avatars) > 0 {
+		resp.Avatar = avatars[len(avatars)-1].URL
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(resp))
+}
+
+// queryAccount returns the account of uid
+func (h *RestHandler) queryAccount(c *gin.Context, uid string) (*models.Account, bool) {
+	acc, err := h.srvcContext.DBStorage.QueryAccount("", "", uid)
+	if err != nil {
+		e := fmt.Errorf("query account error, %s", err.Error())
+		logger.Error(e)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return nil, false
+	}
+
+	return acc, true
+}
+
+// checkLoginFree checks the phone or email is not used to login by another account
+func (h *RestHandler) checkLoginFree(c *gin.Context, uid, phone, email string) bool {
+	other, err := h.srvcContext.DBStorage.QueryAccountByLogin("", phone, email)
+	if err != nil && err != gorm.ErrRecordNotFound && err != models.ErrDuplicated {
+		e := fmt.Errorf("query account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return false
+	}
+
+	if err == models.ErrDuplicated || (err == nil && other.ID != uid) {
+		e := fmt.Errorf("phone or email used by another account")
+		logger.Error(e)
+		c.JSON(http.StatusConflict, rest.ErrorResponse(rest.AccountConflict, e.Error()))
+		return false
+	}
+
+	return true
+}
+
+// currentUser returns the user of access token
+func (h *RestHandler) currentUser(c *gin.Context) (*auth.Claims, bool) {
+	claims, ok := middleware.CurrentUser(c)
+	if !ok {
+		e := fmt.Errorf("missing access token")
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// bankResp returns the bank details with card num and tax id masked
+func bankResp(uid, bank, cardNum, taxID string) *structs.BankResp {
+	return &structs.BankResp{
+		UID:         uid,
+		Bank:        bank,
+		BankCardNum: utils.MaskString(cardNum, maskKeep),
+		TaxID:       utils.MaskString(taxID, maskKeep),
+	}
+}
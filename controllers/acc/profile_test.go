@@ -0,0 +1,218 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package acc
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	urlAccProfile = "api/v1/acc/profile"
+	urlAccBank    = "api/v1/acc/bank"
+	urlAccPubList = "api/v1/acc/pub/list"
+)
+
+// setUser sets the user of access token
+func setUser(c *gin.Context) {
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", UserType: rest.UserTypeNormal, Kind: auth.TokenAccess})
+}
+
+func TestGetProfile(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{
+		ID:       "uid",
+		NickName: "nick name",
+		Type:     rest.UserTypeNormal,
+		Phone:    testPhone,
+		Password: "hash",
+	}, nil)
+	mockDB.EXPECT().QueryImages("uid", rest.ImageAvatar).Return([]*models.Image{
+		{URL: "https://avatar/old.png"},
+		{URL: "https://avatar/new.png"},
+	}, nil)
+
+	// mock request
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodGet, urlAccProfile, nil)
+	handler.GetProfile(c)
+
+	b, _ := ioutil.ReadAll(w.Body)
+	if w.Code != http.StatusOK {
+		t.Fatal(w.Code, string(b))
+	}
+
+	resp := &struct {
+		Data structs.ProfileResp `json:"data"`
+	}{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Data.NickName != "nick name" || resp.Data.Avatar != "https://avatar/new.png" || bytes.Contains(b, []byte("hash")) {
+		t.Errorf("profile check failed, %s", string(b))
+	}
+}
+
+func TestGetProfileUnauthorized(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodGet, urlAccProfile, nil)
+	handler.GetProfile(c)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("missing token check failed")
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, redisCli, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	redisCli.Command(rest.RedisGet, "acc:sms:code:"+testPhone).Expect(testSmsCode)
+	redisCli.GenericCommand(rest.RedisDel).Expect(int64(1))
+	mockDB.EXPECT().QueryAccountByLogin("", testPhone, "").Return(&models.Account{ID: "uid"}, nil)
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateAccount(db, "uid", map[string]interface{}{
+		"nick_name": "new name",
+		"phone":     testPhone,
+	}).Return(nil)
+	mockDB.EXPECT().DeleteImages(db, "uid", rest.ImageAvatar).Return(nil)
+	mockDB.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", NickName: "new name"}, nil)
+	mockDB.EXPECT().QueryImages("uid", rest.ImageAvatar).Return(nil, nil)
+
+	// mock request
+	body, _ := json.Marshal(&structs.UpdateProfileRequest{
+		NickName: "new name",
+		Avatar:   "https://avatar/new.png",
+		Phone:    testPhone,
+		SmsCode:  testSmsCode,
+	})
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlAccProfile, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateProfile(c)
+	CommRespCheck(t, w)
+}
+
+func TestUpdateProfileEmail(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// email is not editable
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlAccProfile, bytes.NewBufferString(`{"email":"aaa@icloud.com"}`))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateProfile(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("email update check failed")
+	}
+}
+
+func TestGetBankMasked(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{
+		ID:          "uid",
+		Bank:        "bank",
+		BankCardNum: "6222020200112233445",
+		TaxID:       "91110000600037341L",
+	}, nil)
+
+	// mock request
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodGet, urlAccBank, nil)
+	handler.GetBank(c)
+
+	b, _ := ioutil.ReadAll(w.Body)
+	resp := &struct {
+		Data structs.BankResp `json:"data"`
+	}{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Data.BankCardNum != "***************3445" || resp.Data.TaxID != "**************341L" {
+		t.Errorf("bank masking check failed, %s", string(b))
+	}
+}
+
+func TestUpdateBank(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockDB.EXPECT().GetDBTransaction().Return(db)
+	mockDB.EXPECT().UpdateAccount(db, "uid", map[string]interface{}{
+		"bank":          "bank",
+		"bank_card_num": "6222020200112233445",
+		"tax_id":        "",
+	}).Return(nil)
+	mockDB.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	body, _ := json.Marshal(&structs.BankRequest{Bank: "bank", BankCardNum: "6222020200112233445"})
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlAccBank, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateBank(c)
+	CommRespCheck(t, w)
+}
+
+func TestUpdateBankInvalidCard(t *testing.T) {
+	mockCtl, handler, _, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	body, _ := json.Marshal(&structs.BankRequest{Bank: "bank", BankCardNum: "6222-0202"})
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlAccBank, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateBank(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("bank card check failed")
+	}
+}
+
+func TestAccPubList(t *testing.T) {
+	mockCtl, handler, mockDB, _, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockDB.EXPECT().QueryPubByUID("uid", rest.PubTypeDonate, gomock.Any()).Return([]*structs.PubUserItem{
+		{ID: "funds_id", Type: rest.DonatedTypeFunds, UID: "uid", Time: time.Now()},
+		{ID: "supplies_id", Type: rest.DonatedTypeSupplies, UID: "uid", Time: time.Now()},
+	}, nil)
+
+	// mock request
+	setUser(c)
+	c.Request, _ = http.NewRequest(http.MethodGet, urlAccPubList+"?pub_type=donate", nil)
+	handler.AccPubList(c)
+	CommRespCheck(t, w)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 // BlockChainCallBack defines call back of block chain
@@ -31,44 +32,174 @@ func (h *RestHandler) BlockChainCallBack(c *gin.Context) {
 	}
 	logger.Debugf("request params, %v", req)
 
-	funds := &models.PubFunds{
-		BlockType:   req.BlockChain,
-		TxID:        req.TxID,
-		BlockHeight: req.BlockNum,
-		BlockTime:   req.Time,
+	// call back is recorded once per block chain id
+	cb, err := h.srvcContext.DBStorage.QueryCallBack(req.ID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		e := fmt.Errorf("query call back error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
 	}
 
-	supplies := &models.PubSupplies{
+	if err == nil {
+		h.recordedCallBack(c, cb, req)
+		return
+	}
+
+	cb = &models.BCCallBack{
+		ID:          req.ID,
 		BlockType:   req.BlockChain,
 		TxID:        req.TxID,
 		BlockHeight: req.BlockNum,
 		BlockTime:   req.Time,
+		Status:      rest.CallBackApplied,
+	}
+
+	// find the publicity published with the block chain id, the late call back of a previous attempt is matched
+	// as well, its donation statistics changes once confirmed
+	var stat *models.DonationStat
+	funds, err := h.srvcContext.DBStorage.QueryFundsByBlockID(req.ID)
+	if err == nil {
+		cb.Type, cb.RelatedID = rest.DonatedTypeFunds, funds.ID
+		stat = models.FundsStat(funds)
+	} else if err == gorm.ErrRecordNotFound {
+		supplies, err := h.srvcContext.DBStorage.QuerySuppliesByBlockID(req.ID)
+		if err == nil {
+			cb.Type, cb.RelatedID = rest.DonatedTypeSupplies, supplies.ID
+			stat = models.SuppliesStat(supplies)
+		} else if err != gorm.ErrRecordNotFound {
+			e := fmt.Errorf("query supplies by block id error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return
+		}
+	} else {
+		e := fmt.Errorf("query funds by block id error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	if cb.RelatedID == "" {
+		e := fmt.Errorf("block chain id %s matches no funds or supplies", req.ID)
+		logger.Error(e)
+		c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.BlockChainIDNotFound, e.Error()))
+		return
+	}
+
+	// donation statistics are kept of charity only
+	if stat != nil {
+		acc, err := h.srvcContext.DBStorage.QueryAccount("", "", stat.UID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			e := fmt.Errorf("query target user error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return
+		}
+
+		if err == gorm.ErrRecordNotFound || acc.Type != rest.UserTypeOrgCharity {
+			stat = nil
+		}
 	}
 
 	tx := h.srvcContext.DBStorage.GetDBTransaction()
-	err := h.srvcContext.DBStorage.UpdateFundsBC(tx, req.ID, funds)
+	err = h.srvcContext.DBStorage.CreateCallBack(tx, cb)
 	if err != nil {
 		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("update bc info to funds error, %s", err.Error())
+		e := fmt.Errorf("create call back error, %s", err.Error())
 		logger.Error(e)
 		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
 		return
 	}
 
-	err = h.srvcContext.DBStorage.UpdateSuppliesBC(tx, req.ID, supplies)
+	switch cb.Type {
+	case rest.DonatedTypeFunds:
+		err = h.srvcContext.DBStorage.UpdateFundsBC(tx, cb.RelatedID, &models.PubFunds{
+			BlockID:     req.ID,
+			BlockType:   req.BlockChain,
+			TxID:        req.TxID,
+			BlockHeight: req.BlockNum,
+			BlockTime:   req.Time,
+			ChainStatus: rest.ChainStatusConfirmed,
+			ConfirmedAt: time.Now().Unix(),
+		})
+	case rest.DonatedTypeSupplies:
+		err = h.srvcContext.DBStorage.UpdateSuppliesBC(tx, cb.RelatedID, &models.PubSupplies{
+			BlockID:     req.ID,
+			BlockType:   req.BlockChain,
+			TxID:        req.TxID,
+			BlockHeight: req.BlockNum,
+			BlockTime:   req.Time,
+			ChainStatus: rest.ChainStatusConfirmed,
+			ConfirmedAt: time.Now().Unix(),
+		})
+	}
+
+	// the publicity confirmed already by the call back of another attempt is not confirmed again, only the
+	// call back is recorded
+	if err == gorm.ErrRecordNotFound {
+		logger.Warningf("%s %s of block chain id %s is not transited to confirmed", cb.Type, cb.RelatedID, req.ID)
+		h.srvcContext.DBStorage.DBTransactionCommit(tx)
+		c.JSON(http.StatusOK, &structs.BCCBResp{Code: "success", Msg: "confirmed already"})
+		return
+	}
+
 	if err != nil {
 		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("update bc info to supplies error, %s", err.Error())
+		e := fmt.Errorf("update bc info to %s error, %s", cb.Type, err.Error())
 		logger.Error(e)
 		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
 		return
 	}
-	h.srvcContext.DBStorage.DBTransactionCommit(tx)
 
-	key := req.ID
-	h.srvcContext.RedisCli.Do(rest.RedisSet, key, req.TxID)
-	h.srvcContext.RedisCli.Do(rest.RedisExpireAt, key, time.Now().Add(1000*60*60))
+	outbox := &models.PubOutbox{
+		Status:      rest.ChainStatusConfirmed,
+		BlockID:     req.ID,
+		TxID:        req.TxID,
+		BlockHeight: req.BlockNum,
+	}
+
+	err = h.srvcContext.DBStorage.UpdateOutboxBC(tx, cb.RelatedID, outbox)
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update bc info to outbox error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	if stat != nil {
+		err = h.srvcContext.DBStorage.AddDonationStat(tx, stat)
+		if err != nil {
+			h.srvcContext.DBStorage.DBTransactionRollback(tx)
+			e := fmt.Errorf("update donation statistics error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return
+		}
+	}
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
 
 	c.JSON(http.StatusOK, &structs.BCCBResp{Code: "success", Msg: ""})
 	logger.Info("response bc call back success.")
 }
+
+// recordedCallBack acknowledges the duplicate call back and flags the conflicting one
+func (h *RestHandler) recordedCallBack(c *gin.Context, cb *models.BCCallBack, req *structs.BCCBReq) {
+	if cb.TxID == req.TxID {
+		logger.Infof("duplicate call back of block chain id %s", req.ID)
+		c.JSON(http.StatusOK, &structs.BCCBResp{Code: "success", Msg: "duplicate call back"})
+		return
+	}
+
+	logger.Warningf("call back of block chain id %s conflicts, recorded tx id %s, got %s", req.ID, cb.TxID, req.TxID)
+	if err := h.srvcContext.DBStorage.FlagCallBackConflict(req.ID, req.TxID); err != nil {
+		e := fmt.Errorf("flag call back conflict error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return
+	}
+
+	e := fmt.Errorf("tx id %s conflicts with the recorded %s, flagged for review", req.TxID, cb.TxID)
+	c.JSON(http.StatusConflict, rest.ErrorResponse(rest.BlockChainTxIDConflict, e.Error()))
+}
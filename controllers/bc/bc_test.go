@@ -17,13 +17,16 @@ import (
 
 	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
 	"github.com/csiabb/donation-service/models/mock_backend"
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
 	"github.com/rafaeljusto/redigomock"
+	"github.com/shopspring/decimal"
 )
 
 const (
@@ -56,19 +59,30 @@ func Init(t *testing.T) (*gomock.Controller, *RestHandler, *mock_backend.MockIDB
 	handler := RestHandler{}
 	handler.srvcContext = &context.Context{}
 	handler.srvcContext.DBStorage = mockBackend
-	handler.srvcContext.RedisCli = redisCli
+	handler.srvcContext.RedisPool = &redis.Pool{Dial: func() (redis.Conn, error) { return redisCli, nil }}
 
 	return mockCtl, &handler, mockBackend, w, c
 }
 
+// expectCharity mocks the target of publicity as account of charity
+func expectCharity(mockBackend *mock_backend.MockIDBBackend, uid string) {
+	mockBackend.EXPECT().QueryAccount("", "", uid).Return(&models.Account{
+		ID:   uid,
+		Type: rest.UserTypeOrgCharity,
+	}, nil)
+}
+
 func TestBlockChainCallBackSucceed(t *testing.T) {
 	mockCtl, handler, mockBackend, w, c := Init(t)
 	defer mockCtl.Finish()
 
 	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(&models.PubFunds{ID: "funds_id"}, nil)
 	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateCallBack(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().UpdateFundsBC(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	mockBackend.EXPECT().UpdateSuppliesBC(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateOutboxBC(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().DBTransactionCommit(gomock.Any())
 
 	// mock request
@@ -78,6 +92,146 @@ func TestBlockChainCallBackSucceed(t *testing.T) {
 	CommRespCheck(t, w)
 }
 
+func TestBlockChainCallBackPreviousAttempt(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// the funds was re-published with another block id, the late call back of the previous attempt still matches
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID("did:axn:da-eecf83b7-2bc9-49f2-8005-e0e39f606450").Return(&models.PubFunds{
+		ID:      "funds_id",
+		BlockID: "block_id_2",
+	}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateCallBack(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateFundsBC(db, "funds_id", gomock.Any()).DoAndReturn(func(tx *gorm.DB, id string, funds *models.PubFunds) error {
+		if funds.BlockID != "did:axn:da-eecf83b7-2bc9-49f2-8005-e0e39f606450" || funds.ChainStatus != rest.ChainStatusConfirmed {
+			t.Errorf("confirmed block id check failed, %v", funds)
+		}
+		return nil
+	})
+	mockBackend.EXPECT().UpdateOutboxBC(db, "funds_id", gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	CommRespCheck(t, w)
+}
+
+func TestBlockChainCallBackDonationStat(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QuerySuppliesByBlockID(gomock.Any()).Return(&models.PubSupplies{
+		ID:        "supplies_id",
+		TargetUID: "charity_uid",
+		PubType:   rest.PubTypeDistribute,
+		Number:    20,
+	}, nil)
+	expectCharity(mockBackend, "charity_uid")
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateCallBack(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateSuppliesBC(db, gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateOutboxBC(db, gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().AddDonationStat(db, &models.DonationStat{UID: "charity_uid", DistributedSupplies: 20}).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	CommRespCheck(t, w)
+}
+
+func TestBlockChainCallBackDonationStatFailed(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(&models.PubFunds{
+		ID:        "funds_id",
+		TargetUID: "charity_uid",
+		PubType:   rest.PubTypeDonate,
+		Amount:    decimal.New(100, 0),
+	}, nil)
+	expectCharity(mockBackend, "charity_uid")
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateCallBack(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateFundsBC(db, gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateOutboxBC(db, gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().AddDonationStat(db, gomock.Any()).Return(errors.New("update donation stat error"))
+	mockBackend.EXPECT().DBTransactionRollback(db)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("donation statistics rollback check failed")
+	}
+}
+
+func TestBlockChainCallBackConfirmedAlready(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// confirmed by the call back of another attempt, the statistics is not changed again
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(&models.PubFunds{
+		ID:        "funds_id",
+		TargetUID: "charity_uid",
+		PubType:   rest.PubTypeDonate,
+		Amount:    decimal.New(100, 0),
+	}, nil)
+	expectCharity(mockBackend, "charity_uid")
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateCallBack(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateFundsBC(db, "funds_id", gomock.Any()).Return(gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	CommRespCheck(t, w)
+}
+
+func TestBlockChainCallBackTargetNotCharity(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// the statistics is kept of charity only
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(&models.PubFunds{
+		ID:        "funds_id",
+		TargetUID: "normal_uid",
+		PubType:   rest.PubTypeDonate,
+		Amount:    decimal.New(100, 0),
+	}, nil)
+	mockBackend.EXPECT().QueryAccount("", "", "normal_uid").Return(&models.Account{ID: "normal_uid", Type: rest.UserTypeNormal}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateCallBack(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateFundsBC(db, "funds_id", gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateOutboxBC(db, "funds_id", gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	CommRespCheck(t, w)
+}
+
 func TestBlockChainCallBackParams(t *testing.T) {
 	mockCtl, handler, _, w, c := Init(t)
 	defer mockCtl.Finish()
@@ -102,8 +256,11 @@ func TestBlockChainCallBackDB(t *testing.T) {
 	defer mockCtl.Finish()
 
 	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QuerySuppliesByBlockID(gomock.Any()).Return(&models.PubSupplies{ID: "supplies_id"}, nil)
 	mockBackend.EXPECT().GetDBTransaction().Return(db)
-	mockBackend.EXPECT().UpdateFundsBC(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateCallBack(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().UpdateSuppliesBC(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("update supplies error"))
 	mockBackend.EXPECT().DBTransactionRollback(gomock.Any())
 
@@ -122,6 +279,72 @@ func TestBlockChainCallBackDB(t *testing.T) {
 	}
 }
 
+func TestBlockChainCallBackDuplicate(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(&models.BCCallBack{
+		ID:     "did:axn:da-eecf83b7-2bc9-49f2-8005-e0e39f606450",
+		TxID:   "kandkalakna9ejdlalajahbabzgzfaftqub",
+		Status: rest.CallBackApplied,
+	}, nil)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	CommRespCheck(t, w)
+}
+
+func TestBlockChainCallBackConflict(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(&models.BCCallBack{
+		ID:     "did:axn:da-eecf83b7-2bc9-49f2-8005-e0e39f606450",
+		TxID:   "tx_id_recorded",
+		Status: rest.CallBackApplied,
+	}, nil)
+	mockBackend.EXPECT().FlagCallBackConflict(gomock.Any(), "kandkalakna9ejdlalajahbabzgzfaftqub").Return(nil)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	_, err := ioutil.ReadAll(w.Body)
+
+	if err != nil {
+		t.Errorf("io read err, %v", err)
+	}
+
+	if w.Code != http.StatusConflict {
+		t.Error("conflict check failed")
+	}
+}
+
+func TestBlockChainCallBackNotFound(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryCallBack(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryFundsByBlockID(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QuerySuppliesByBlockID(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.BlockChainCallBack(c)
+	_, err := ioutil.ReadAll(w.Body)
+
+	if err != nil {
+		t.Errorf("io read err, %v", err)
+	}
+
+	if w.Code != http.StatusNotFound {
+		t.Error("block chain id check failed")
+	}
+}
+
 func CommRespCheck(t *testing.T, w *httptest.ResponseRecorder) {
 	b, err := ioutil.ReadAll(w.Body)
 
@@ -29,11 +29,20 @@ func (h *RestHandler) QueryOrgCharities(c *gin.Context) {
 	}
 	logger.Debugf("request params, %v", req)
 
+	cursor, err := structs.DecodeCursor(req.Cursor)
+	if err != nil {
+		e := fmt.Errorf("cursor invalid, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
 	params := &structs.QueryParams{
 		PageNum:   req.PageNum,
 		PageLimit: req.PageLimit,
 		StartTime: req.StartTime,
 		EndTime:   req.EndTime,
+		Cursor:    cursor,
 	}
 
 	donationStats, err := h.srvcContext.DBStorage.QueryOrgCharities(params)
@@ -49,12 +58,13 @@ func (h *RestHandler) QueryOrgCharities(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.QueryOrgCharitiesResp{
-		Total:     params.Total,
-		PageNum:   params.PageNum,
-		PageLimit: params.PageLimit,
-		StartTime: params.StartTime,
-		EndTime:   params.EndTime,
-		Results:   donationStats,
+		NextCursor: params.NextCursor.Encode(),
+		Total:      params.Total,
+		PageNum:    params.PageNum,
+		PageLimit:  params.PageLimit,
+		StartTime:  params.StartTime,
+		EndTime:    params.EndTime,
+		Results:    donationStats,
 	}))
 	logger.Info("response query charities success.")
 	return
@@ -73,24 +83,7 @@ func (h *RestHandler) QueryOrgCharitiesDetail(c *gin.Context) {
 	}
 	logger.Debugf("request params, %v", req)
 
-	item, err := h.srvcContext.DBStorage.QueryOrgCharitiesDetail(req.UID)
-	if err != nil {
-		e := fmt.Errorf("query charities detail error , %s", err.Error())
-		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
-		return
-	}
-
-	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.OrgCharitiesDetailResp{
-		UID:         item.UID,
-		URL:         item.URL,
-		NickName:    item.NickName,
-		Address:     item.Province + item.City + item.District + item.Address,
-		Phone:       item.Phone,
-		BankCardNum: item.BankCardNum,
-		Remark:      item.Remark,
-	}))
-
+	h.respondCharityDetail(c, req.UID)
 	logger.Info("response query charities detail success.")
 	return
 }
@@ -88,6 +88,7 @@ func TestRestHandler_QueryOrgCharitiesDetail(t *testing.T) {
 		BankCardNum: "bank_card_num",
 		Remark:      "remark",
 	}, nil)
+	mockBackend.EXPECT().QueryImages("uid_test", rest.ImageGallery).Return(nil, nil)
 
 	// mock request
 	c.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/pub/funds/detail?uid=uid_test", nil)
@@ -0,0 +1,103 @@
+/*
+Copyright Lingzhu Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package org
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Leaderboard defines the request of query the leaderboard of charities or donors, served from the snapshots
+// refreshed in background, the charity board ranked by received funds in all time by default
+func (h *RestHandler) Leaderboard(c *gin.Context) {
+	logger.Info("got leaderboard request")
+
+	req := &structs.LeaderboardRequest{}
+	if err := c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+	logger.Debugf("request params, %v", req)
+
+	if req.Board == "" {
+		req.Board = rest.BoardCharity
+	}
+
+	metrics, ok := models.BoardMetrics[req.Board]
+	if !ok {
+		e := fmt.Errorf("board %s invalid", req.Board)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if req.Metric == "" {
+		req.Metric = metrics[0]
+	}
+
+	if !models.HasMetric(req.Board, req.Metric) {
+		e := fmt.Errorf("metric %s of board %s invalid", req.Metric, req.Board)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if req.Period == "" {
+		req.Period = rest.PeriodAll
+	}
+
+	if !models.HasPeriod(req.Period) {
+		e := fmt.Errorf("period %s invalid", req.Period)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	params := &structs.QueryParams{
+		PageNum:   req.PageNum,
+		PageLimit: req.PageLimit,
+	}
+
+	result, err := h.srvcContext.DBStorage.QueryRankSnapshots(req.Board, req.Period, req.Metric, params)
+	if err != nil {
+		e := fmt.Errorf("query leaderboard error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	resp := &structs.LeaderboardResp{
+		Board:     req.Board,
+		Metric:    req.Metric,
+		Period:    req.Period,
+		Total:     params.Total,
+		PageNum:   params.PageNum,
+		PageLimit: params.PageLimit,
+		Results:   []*structs.RankItem{},
+	}
+
+	for _, v := range result {
+		resp.RefreshedAt = v.RefreshedAt
+		resp.Results = append(resp.Results, &structs.RankItem{
+			Rank:  v.Ranking,
+			UID:   v.UID,
+			Name:  v.Name,
+			Value: v.Value,
+		})
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(resp))
+	logger.Info("response leaderboard success.")
+}
@@ -0,0 +1,54 @@
+/*
+Copyright Lingzhu Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package org
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	urlLeaderboard = "/api/v1/org/leaderboard"
+)
+
+// TestRestHandler_Leaderboard test the leaderboard of donors
+func TestRestHandler_Leaderboard(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryRankSnapshots(rest.BoardDonor, rest.PeriodMonth, rest.MetricDonatedFunds, gomock.Any()).Return([]*models.RankSnapshot{
+		{Ranking: 1, UID: "uid_1", Name: "donor 1", Value: decimal.New(200, 0), RefreshedAt: 1588291200},
+		{Ranking: 2, UID: "uid_2", Name: "donor 2", Value: decimal.New(100, 0), RefreshedAt: 1588291200},
+	}, nil)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodGet, urlLeaderboard+"?board=donor&period=month&page_num=1&page_limit=10", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.Leaderboard(c)
+	CommRespCheck(t, w)
+}
+
+// TestRestHandler_LeaderboardInvalidMetric test the metric not ranked on the board
+func TestRestHandler_LeaderboardInvalidMetric(t *testing.T) {
+	mockCtl, handler, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodGet, urlLeaderboard+"?board=donor&metric=donors", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.Leaderboard(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("metric check failed")
+	}
+}
@@ -0,0 +1,351 @@
+/*
+Copyright Lingzhu Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package org
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/middleware"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	maxGallery = 9 // max num of gallery images of charity
+)
+
+// UpdateCharityProfile defines updating the public profile of the current charity, each field changed is kept as history
+func (h *RestHandler) UpdateCharityProfile(c *gin.Context) {
+	logger.Info("got update charity profile request")
+
+	claims, ok := charityUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.UpdateCharityProfileRequest{}
+	if err := c.BindJSON(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+
+	if req.BankCardNum != "" && !utils.IsBankCardNum(req.BankCardNum) {
+		e := fmt.Errorf("bank card num invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if len(req.Gallery) > maxGallery {
+		e := fmt.Errorf("gallery images can not be more than %d", maxGallery)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	old, err := h.srvcContext.DBStorage.QueryOrgCharitiesDetail(claims.UID)
+	if err != nil {
+		e := fmt.Errorf("query charity profile error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	// the phone of profile falls back to the login phone, the contact phone is compared as stored
+	acc, err := h.srvcContext.DBStorage.QueryAccount("", "", claims.UID)
+	if err != nil {
+		e := fmt.Errorf("query account error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	oldGallery, err := h.galleryURLs(claims.UID)
+	if err != nil {
+		e := fmt.Errorf("query gallery error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	// the fields of account changed
+	var changes []*models.ProfileChange
+	fields := make(map[string]interface{})
+	change := func(field, oldValue, newValue string) bool {
+		if newValue == "" || newValue == oldValue {
+			return false
+		}
+
+		changes = append(changes, &models.ProfileChange{UID: claims.UID, Field: field, OldValue: oldValue, NewValue: newValue})
+		return true
+	}
+
+	if change("description", old.Remark, req.Description) {
+		fields["remark"] = req.Description
+	}
+	if change("contact_phone", acc.ContactPhone, req.ContactPhone) {
+		fields["contact_phone"] = req.ContactPhone
+	}
+	if change("bank", old.Bank, req.Bank) {
+		fields["bank"] = req.Bank
+	}
+	if change("bank_card_num", old.BankCardNum, req.BankCardNum) {
+		fields["bank_card_num"] = req.BankCardNum
+	}
+
+	logo := change("logo", old.URL, req.Logo)
+
+	var address bool
+	if req.Address != nil {
+		address = change("address", addressJSON(&structs.CharityAddress{
+			Country:  old.Country,
+			Province: old.Province,
+			City:     old.City,
+			District: old.District,
+			Address:  old.Address,
+			ZipCode:  old.ZipCode,
+		}), addressJSON(req.Address))
+	}
+
+	// the gallery is replaced as a whole, cleared by empty list
+	var gallery bool
+	if req.Gallery != nil {
+		oldValue, newValue := galleryJSON(oldGallery), galleryJSON(req.Gallery)
+		if oldValue != newValue {
+			changes = append(changes, &models.ProfileChange{UID: claims.UID, Field: "gallery", OldValue: oldValue, NewValue: newValue})
+			gallery = true
+		}
+	}
+
+	if len(changes) == 0 {
+		h.respondCharityDetail(c, claims.UID)
+		logger.Info("response update charity profile success, nothing changed.")
+		return
+	}
+
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	if len(fields) > 0 {
+		err = h.srvcContext.DBStorage.UpdateAccount(tx, claims.UID, fields)
+	}
+	if err == nil && address {
+		err = h.replaceAddress(tx, claims.UID, req.Address)
+	}
+	if err == nil && logo {
+		err = h.replaceImages(tx, claims.UID, rest.ImageAvatar, []string{req.Logo})
+	}
+	if err == nil && gallery {
+		err = h.replaceImages(tx, claims.UID, rest.ImageGallery, req.Gallery)
+	}
+	if err == nil {
+		err = h.srvcContext.DBStorage.CreateProfileChanges(tx, changes)
+	}
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("update charity profile error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	h.srvcContext.DBStorage.DBTransactionCommit(tx)
+
+	h.respondCharityDetail(c, claims.UID)
+	logger.Info("response update charity profile success.")
+}
+
+// CharityProfileHistory defines the change history of the profile of the current charity, the latest first
+func (h *RestHandler) CharityProfileHistory(c *gin.Context) {
+	logger.Info("got charity profile history request")
+
+	claims, ok := charityUser(c)
+	if !ok {
+		return
+	}
+
+	req := &structs.ProfileHistoryRequest{}
+	if err := c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.ParseRequestParamsError, e.Error()))
+		return
+	}
+	logger.Debugf("request params, %v", req)
+
+	params := &structs.QueryParams{
+		PageNum:   req.PageNum,
+		PageLimit: req.PageLimit,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+
+	result, err := h.srvcContext.DBStorage.QueryProfileChanges(claims.UID, params)
+	if err != nil {
+		e := fmt.Errorf("query charity profile history error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	var items []*structs.ProfileChangeItem
+	for _, v := range result {
+		items = append(items, &structs.ProfileChangeItem{
+			Field:     v.Field,
+			OldValue:  v.OldValue,
+			NewValue:  v.NewValue,
+			CreatedAt: v.CreatedAt.Unix(),
+		})
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.ProfileHistoryResp{
+		Total:     params.Total,
+		PageNum:   params.PageNum,
+		PageLimit: params.PageLimit,
+		Results:   items,
+	}))
+	logger.Info("response charity profile history success.")
+}
+
+// respondCharityDetail responses the public profile of charity with its gallery and donation statistics
+func (h *RestHandler) respondCharityDetail(c *gin.Context, uid string) {
+	item, err := h.srvcContext.DBStorage.QueryOrgCharitiesDetail(uid)
+	if err != nil {
+		e := fmt.Errorf("query charities detail error , %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	gallery, err := h.galleryURLs(uid)
+	if err != nil {
+		e := fmt.Errorf("query gallery error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.OrgCharitiesDetailResp{
+		UID:                 item.UID,
+		URL:                 item.URL,
+		NickName:            item.NickName,
+		Address:             item.Province + item.City + item.District + item.Address,
+		Phone:               item.Phone,
+		Bank:                item.Bank,
+		BankCardNum:         item.BankCardNum,
+		Remark:              item.Remark,
+		Gallery:             gallery,
+		ReceivedFunds:       item.ReceivedFunds,
+		ReceivedSupplies:    item.ReceivedSupplies,
+		DistributedFunds:    item.DistributedFunds,
+		DistributedSupplies: item.DistributedSupplies,
+	}))
+}
+
+// galleryURLs returns the urls of gallery images of charity in order
+func (h *RestHandler) galleryURLs(uid string) ([]string, error) {
+	images, err := h.srvcContext.DBStorage.QueryImages(uid, rest.ImageGallery)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, len(images))
+	for i, v := range images {
+		urls[i] = v.URL
+	}
+
+	return urls, nil
+}
+
+// replaceAddress replaces the registration address of charity
+func (h *RestHandler) replaceAddress(tx *gorm.DB, uid string, addr *structs.CharityAddress) error {
+	err := h.srvcContext.DBStorage.DeleteAddresses(tx, uid, rest.AddrReg)
+	if err != nil {
+		return err
+	}
+
+	return h.srvcContext.DBStorage.CreateAddresses(tx, []*models.Address{
+		{
+			ID:        utils.GenerateUUID(),
+			UID:       uid,
+			RelatedID: uid,
+			Type:      rest.AddrReg,
+			Country:   addr.Country,
+			Province:  addr.Province,
+			City:      addr.City,
+			District:  addr.District,
+			Address:   addr.Address,
+			ZipCode:   addr.ZipCode,
+		},
+	})
+}
+
+// replaceImages replaces the images of charity by type, the index of image is its position
+func (h *RestHandler) replaceImages(tx *gorm.DB, uid, imageType string, urls []string) error {
+	err := h.srvcContext.DBStorage.DeleteImages(tx, uid, imageType)
+	if err != nil || len(urls) == 0 {
+		return err
+	}
+
+	images := make([]*models.Image, len(urls))
+	for i, v := range urls {
+		images[i] = &models.Image{
+			ID:        utils.GenerateUUID(),
+			RelatedID: uid,
+			Type:      imageType,
+			URL:       v,
+			Index:     strconv.Itoa(i),
+		}
+	}
+
+	return h.srvcContext.DBStorage.CreateImages(tx, images)
+}
+
+// charityUser returns the user of access token, only charity is allowed
+func charityUser(c *gin.Context) (*auth.Claims, bool) {
+	claims, ok := middleware.CurrentUser(c)
+	if !ok {
+		e := fmt.Errorf("missing access token")
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return nil, false
+	}
+
+	if claims.UserType != rest.UserTypeOrgCharity {
+		e := fmt.Errorf("user type %s not allowed", claims.UserType)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// addressJSON encodes the address kept in change history
+func addressJSON(addr *structs.CharityAddress) string {
+	b, _ := json.Marshal(addr)
+	return string(b)
+}
+
+// galleryJSON encodes the gallery urls kept in change history
+func galleryJSON(urls []string) string {
+	if urls == nil {
+		urls = []string{}
+	}
+
+	b, _ := json.Marshal(urls)
+	return string(b)
+}
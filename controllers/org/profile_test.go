@@ -0,0 +1,175 @@
+/*
+Copyright Lingzhu Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package org
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	urlCharityProfile = "/api/v1/org/charity/profile"
+	urlCharityHistory = "/api/v1/org/charity/profile/history"
+)
+
+var (
+	charityClaims = &auth.Claims{UID: "charity_uid", UserType: rest.UserTypeOrgCharity, Kind: auth.TokenAccess}
+	charityDetail = &structs.OrgCharitiesDetailItem{
+		UID:           "charity_uid",
+		URL:           "https://image/logo.png",
+		NickName:      "charity",
+		Province:      "beijing",
+		Address:       "address",
+		Phone:         "13800000000",
+		Bank:          "bank",
+		BankCardNum:   "6222020200112233445",
+		Remark:        "description",
+		ReceivedFunds: decimal.New(100, 0),
+	}
+	charityAccount = &models.Account{
+		ID:    "charity_uid",
+		Type:  rest.UserTypeOrgCharity,
+		Phone: "13800000000",
+	}
+)
+
+// TestRestHandler_UpdateCharityProfile test updating the profile of charity with history
+func TestRestHandler_UpdateCharityProfile(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryOrgCharitiesDetail("charity_uid").Return(charityDetail, nil).Times(2)
+	mockBackend.EXPECT().QueryAccount("", "", "charity_uid").Return(charityAccount, nil)
+	mockBackend.EXPECT().QueryImages("charity_uid", rest.ImageGallery).Return([]*models.Image{
+		{URL: "https://image/gallery_1.png"},
+	}, nil).Times(2)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().UpdateAccount(db, "charity_uid", map[string]interface{}{
+		"remark":        "new description",
+		"contact_phone": "010-12345678",
+	}).Return(nil)
+	mockBackend.EXPECT().DeleteAddresses(db, "charity_uid", rest.AddrReg).Return(nil)
+	mockBackend.EXPECT().CreateAddresses(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DeleteImages(db, "charity_uid", rest.ImageGallery).Return(nil)
+	mockBackend.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateProfileChanges(db, gomock.Any()).DoAndReturn(func(tx *gorm.DB, data []*models.ProfileChange) error {
+		if len(data) != 4 {
+			t.Errorf("changes num %d, expected 4", len(data))
+		}
+		return nil
+	})
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// the logo and bank account are unchanged
+	body, _ := json.Marshal(&structs.UpdateCharityProfileRequest{
+		Description:  "new description",
+		ContactPhone: "010-12345678",
+		Address:      &structs.CharityAddress{Province: "shanghai", Address: "new address"},
+		Logo:         "https://image/logo.png",
+		Gallery:      []string{"https://image/gallery_1.png", "https://image/gallery_2.png"},
+		BankCardNum:  "6222020200112233445",
+	})
+
+	// mock request
+	c.Set(rest.ContextClaims, charityClaims)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlCharityProfile, bytes.NewBuffer(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateCharityProfile(c)
+	CommRespCheck(t, w)
+}
+
+// TestRestHandler_UpdateCharityProfileNotCharity test only charity can update the profile
+func TestRestHandler_UpdateCharityProfileNotCharity(t *testing.T) {
+	mockCtl, handler, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid", UserType: rest.UserTypeNormal, Kind: auth.TokenAccess})
+	c.Request, _ = http.NewRequest(http.MethodPut, urlCharityProfile, bytes.NewBufferString(`{"description": "description"}`))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateCharityProfile(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("charity check failed")
+	}
+}
+
+// TestRestHandler_UpdateCharityProfileUnchanged test nothing is written if nothing changed
+func TestRestHandler_UpdateCharityProfileUnchanged(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryOrgCharitiesDetail("charity_uid").Return(charityDetail, nil).Times(2)
+	mockBackend.EXPECT().QueryAccount("", "", "charity_uid").Return(charityAccount, nil)
+	mockBackend.EXPECT().QueryImages("charity_uid", rest.ImageGallery).Return(nil, nil).Times(2)
+
+	// mock request
+	c.Set(rest.ContextClaims, charityClaims)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlCharityProfile, bytes.NewBufferString(`{"description": "description", "gallery": []}`))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateCharityProfile(c)
+	CommRespCheck(t, w)
+}
+
+// TestRestHandler_UpdateCharityProfileContactPhone test the contact phone is compared as stored instead of the
+// login phone it falls back to
+func TestRestHandler_UpdateCharityProfileContactPhone(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryOrgCharitiesDetail("charity_uid").Return(charityDetail, nil).Times(2)
+	mockBackend.EXPECT().QueryAccount("", "", "charity_uid").Return(charityAccount, nil)
+	mockBackend.EXPECT().QueryImages("charity_uid", rest.ImageGallery).Return(nil, nil).Times(2)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().UpdateAccount(db, "charity_uid", map[string]interface{}{
+		"contact_phone": "13800000000",
+	}).Return(nil)
+	mockBackend.EXPECT().CreateProfileChanges(db, gomock.Any()).DoAndReturn(func(tx *gorm.DB, data []*models.ProfileChange) error {
+		if len(data) != 1 || data[0].OldValue != "" || data[0].NewValue != "13800000000" {
+			t.Errorf("contact phone change check failed, %v", data)
+		}
+		return nil
+	})
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Set(rest.ContextClaims, charityClaims)
+	c.Request, _ = http.NewRequest(http.MethodPut, urlCharityProfile, bytes.NewBufferString(`{"contact_phone": "13800000000"}`))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.UpdateCharityProfile(c)
+	CommRespCheck(t, w)
+}
+
+// TestRestHandler_CharityProfileHistory test the change history of charity profile
+func TestRestHandler_CharityProfileHistory(t *testing.T) {
+	mockCtl, handler, mockBackend, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryProfileChanges("charity_uid", gomock.Any()).Return([]*models.ProfileChange{
+		{UID: "charity_uid", Field: "description", OldValue: "description", NewValue: "new description", CreatedAt: time.Now()},
+	}, nil)
+
+	// mock request
+	c.Set(rest.ContextClaims, charityClaims)
+	c.Request, _ = http.NewRequest(http.MethodGet, urlCharityHistory+"?page_num=1&page_limit=10", nil)
+	handler.CharityProfileHistory(c)
+	CommRespCheck(t, w)
+}
@@ -8,34 +8,26 @@ package pub
 
 import (
 	"fmt"
-	wlog "github.com/csiabb/donation-service/common/log"
 	"net/http"
 	"time"
 
 	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/components/policy"
+	"github.com/csiabb/donation-service/middleware"
 	"github.com/csiabb/donation-service/models"
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gomodule/redigo/redis"
+	"github.com/jinzhu/gorm"
 	"github.com/shopspring/decimal"
 )
 
 const (
-	timeoutOfOneSingleReq = 60 // seconds
+	maxStatusWait = 30 // seconds
 )
 
-func bcCallBackInfoInRedis(redisCli redis.Conn, blockChainID string) (string, error) {
-	s, err := redis.String(redisCli.Do(rest.RedisGet, blockChainID))
-
-	if err != nil {
-		return "", err
-	}
-
-	return s, nil
-}
-
 // ReceiveFunds defines the request of received funds
 func (h *RestHandler) ReceiveFunds(c *gin.Context) {
 	logger.Info("got receive funds request")
@@ -56,6 +48,20 @@ func (h *RestHandler) ReceiveFunds(c *gin.Context) {
 		return
 	}
 
+	// the one who publishes is the user of access token instead of the one in request
+	claims, ok := middleware.CurrentUser(c)
+	if !ok {
+		e := fmt.Errorf("missing access token")
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return
+	}
+	req.SetUIDByFundsReq(claims.UID, claims.UserType)
+
+	if !h.authorize(c, claims, req.PubType, req.TargetUID) {
+		return
+	}
+
 	if req.Amount.LessThanOrEqual(decimal.NewFromInt(0)) {
 		e := fmt.Errorf("amount can not less than 0")
 		logger.Error(e)
@@ -63,6 +69,10 @@ func (h *RestHandler) ReceiveFunds(c *gin.Context) {
 		return
 	}
 
+	if h.srvcContext.Policy.RequireSoter(claims.UserType, req.PubType, req.Amount) && !h.checkSoter(c, claims, req.Soter) {
+		return
+	}
+
 	fundsID := utils.GenerateUUID()
 	funds := &models.PubFunds{
 		ID:                fundsID,
@@ -76,16 +86,7 @@ func (h *RestHandler) ReceiveFunds(c *gin.Context) {
 		PayType:           req.PayType,
 		Amount:            req.Amount,
 		Remark:            req.Remark,
-	}
-
-	tx := h.srvcContext.DBStorage.GetDBTransaction()
-	err := h.srvcContext.DBStorage.CreateFunds(tx, funds)
-	if err != nil {
-		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("create funds error, %s", err.Error())
-		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
-		return
+		ChainStatus:       rest.ChainStatusCreated,
 	}
 
 	images := make([]*models.Image, 0)
@@ -100,24 +101,9 @@ func (h *RestHandler) ReceiveFunds(c *gin.Context) {
 		})
 	}
 
-	err = h.srvcContext.DBStorage.CreateImages(tx, images)
-	if err != nil {
-		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("create images error, %s", err.Error())
-		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
-		return
-	}
-
-	acc, err := h.srvcContext.DBStorage.QueryAccount("", req.GetUIDByFundsReq())
-	if err != nil {
-		e := fmt.Errorf("query user error, %s", err.Error())
-		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
-		return
-	}
-
+	// converted before saved, the schema version and payload hash are kept on funds
 	var bcJSON string
+	var err error
 	switch req.PubType {
 	case rest.PubTypeDonate:
 		bcJSON, err = funds.ConvertFundsDonation(images)
@@ -128,76 +114,202 @@ func (h *RestHandler) ReceiveFunds(c *gin.Context) {
 	}
 
 	if err != nil {
-		h.srvcContext.DBStorage.DBTransactionRollback(tx)
 		e := fmt.Errorf("convert funds data error, %s", err.Error())
 		logger.Error(e)
 		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.PubToBlockChainFailure, e.Error()))
 		return
 	}
 
-	bcJSONs := make([]*string, 0)
-	bcJSONs = append(bcJSONs, &bcJSON)
-
-	bcResults, err := h.srvcContext.IBCAdapter.Pubs(acc.DID, bcJSONs)
+	tx := h.srvcContext.DBStorage.GetDBTransaction()
+	err = h.srvcContext.DBStorage.CreateFunds(tx, funds)
 	if err != nil {
 		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("publicity funds error, %s", err.Error())
+		e := fmt.Errorf("create funds error, %s", err.Error())
 		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.PubToBlockChainFailure, e.Error()))
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
 		return
 	}
 
-	if bcResults[0].Code == rest.PubToBlockChainFailure {
+	err = h.srvcContext.DBStorage.CreateImages(tx, images)
+	if err != nil {
 		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("publicity funds error, %v", bcResults[0].Msg)
+		e := fmt.Errorf("create images error, %s", err.Error())
 		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.PubToBlockChainFailure, e.Error()))
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
 		return
 	}
 
-	blockChainID := bcResults[0].Data.ID
-	err = h.srvcContext.DBStorage.UpdateFunds(tx, fundsID, blockChainID)
+	acc, err := h.srvcContext.DBStorage.QueryAccount("", "", req.GetUIDByFundsReq())
+	if err != nil {
+		h.srvcContext.DBStorage.DBTransactionRollback(tx)
+		e := fmt.Errorf("query user error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
 
+	// published to block chain by the background publisher
+	err = h.srvcContext.DBStorage.CreateOutbox(tx, []*models.PubOutbox{
+		{
+			ID:        utils.GenerateUUID(),
+			RelatedID: fundsID,
+			Type:      rest.DonatedTypeFunds,
+			BCID:      acc.DID,
+			Publicity: bcJSON,
+			Status:    rest.ChainStatusPending,
+		},
+	})
 	if err != nil {
 		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("update funds tx id error, %s", err.Error())
+		e := fmt.Errorf("create outbox error, %s", err.Error())
 		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.PubToBlockChainFailure, e.Error()))
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
 		return
 	}
 
 	h.srvcContext.DBStorage.DBTransactionCommit(tx)
 
-	reqTime := time.Now().Unix()
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.ReceiveFundsResp{FundsID: fundsID, Status: rest.PendingChain}))
+	logger.Infof("response receive funds success.")
+}
 
-	for {
-		time.Sleep(time.Duration(1) * time.Second)
+// authorize checks the publicity against the rules of user type, the target of publicity must
+// match the target rule, it responses permission denied and returns false if not allowed
+func (h *RestHandler) authorize(c *gin.Context, claims *auth.Claims, pubType, targetUID string) bool {
+	target, err := h.srvcContext.Policy.Authorize(claims.UserType, pubType)
+	if err != nil {
+		e := fmt.Errorf("user type %s can not publish %s, %s", claims.UserType, pubType, err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return false
+	}
 
-		respTime := time.Now().Unix()
-		if respTime-reqTime >= timeoutOfOneSingleReq*1 {
-			c.JSON(http.StatusRequestTimeout, rest.ErrorResponse(rest.BlockChainCallBackTimeout, "block chain call back timeout"))
-			logger.Infof("block chain call back timeout")
-			return
+	switch target {
+	case policy.TargetSelf:
+		if targetUID != claims.UID {
+			e := fmt.Errorf("user type %s can only publish %s of self", claims.UserType, pubType)
+			logger.Error(e)
+			c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+			return false
+		}
+	case policy.TargetCharity:
+		acc, err := h.srvcContext.DBStorage.QueryAccount("", "", targetUID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			e := fmt.Errorf("query target user error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return false
 		}
 
-		result, err := bcCallBackInfoInRedis(h.srvcContext.RedisCli, blockChainID)
-		logger.Debug("block chain call back result, %v", result)
+		if err == gorm.ErrRecordNotFound || acc.Type != rest.UserTypeOrgCharity {
+			e := fmt.Errorf("user type %s can only publish %s targeting charity", claims.UserType, pubType)
+			logger.Error(e)
+			c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+			return false
+		}
+	}
 
-		if err != nil {
-			if err == redis.ErrNil {
-				continue
-			}
+	// kyc is required of the charity distributing, which is the one in request when admin distributes
+	// for it, otherwise the one who publishes
+	if pubType == rest.PubTypeDistribute {
+		charityUID := claims.UID
+		if target == policy.TargetAny {
+			charityUID = targetUID
+		}
+		return h.checkCharityKyc(c, charityUID)
+	}
+
+	return true
+}
 
-			e := fmt.Errorf("get block chain call back data from redis error, %v", err)
+// checkCharityKyc checks the target of distribute records is charity approved by kyc
+func (h *RestHandler) checkCharityKyc(c *gin.Context, targetUID string) bool {
+	acc, err := h.srvcContext.DBStorage.QueryAccount("", "", targetUID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		e := fmt.Errorf("query target user error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return false
+	}
+
+	if err == gorm.ErrRecordNotFound || acc.Type != rest.UserTypeOrgCharity {
+		e := fmt.Errorf("%s can only be published for charity", rest.PubTypeDistribute)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.PermissionDenied, e.Error()))
+		return false
+	}
+
+	if acc.KycStatus != rest.KycStatusApproved {
+		e := fmt.Errorf("kyc of charity %s not approved", targetUID)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.KycNotApproved, e.Error()))
+		return false
+	}
+
+	return true
+}
+
+// checkSoter verifies the soter biometric signature of the user with wechat, it responses
+// and returns false if the signature is missing or not verified
+func (h *RestHandler) checkSoter(c *gin.Context, claims *auth.Claims, soter *structs.SoterRequest) bool {
+	if soter == nil {
+		e := fmt.Errorf("soter signature required")
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.SoterRequired, e.Error()))
+		return false
+	}
+
+	wxApp, ok := h.srvcContext.Config.WXCfg.App(claims.AppID)
+	if !ok {
+		e := fmt.Errorf("unknown wechat app %s", claims.AppID)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.SoterVerifyFailed, e.Error()))
+		return false
+	}
+
+	binding, err := h.srvcContext.DBStorage.QueryWXBinding(claims.UID, wxApp.AppID, "", "")
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			e := fmt.Errorf("user not bound to wechat app %s, login again", wxApp.AppID)
 			logger.Error(e)
-			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
-			return
+			c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.SoterVerifyFailed, e.Error()))
+			return false
 		}
 
-		c.JSON(http.StatusOK, rest.SuccessResponse(&structs.ReceiveFundsResp{FundsID: fundsID}))
-		logger.Infof("response receive funds success.")
-		return
+		e := fmt.Errorf("query wechat binding error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return false
 	}
+
+	token, err := h.srvcContext.WXTokens.AccessToken(wxApp.AppID, wxApp.Secret)
+	if err != nil {
+		e := fmt.Errorf("get wechat access token error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return false
+	}
+
+	resp, err := h.srvcContext.WXClient.CheckFinger(structs.FingerRequest{
+		OpenID:        binding.OpenID,
+		JSONString:    soter.JSONString,
+		JSONSignature: soter.JSONSignature,
+	}, token)
+	if err != nil {
+		e := fmt.Errorf("verify soter signature error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+		return false
+	}
+
+	if resp.Errcode != 0 || !resp.IsOK {
+		e := fmt.Errorf("soter signature verified failed, %d %s", resp.Errcode, resp.Errmsg)
+		logger.Error(e)
+		c.JSON(http.StatusForbidden, rest.ErrorResponse(rest.SoterVerifyFailed, e.Error()))
+		return false
+	}
+
+	return true
 }
 
 // QueryFunds defines the request of query funds
@@ -214,11 +326,28 @@ func (h *RestHandler) QueryFunds(c *gin.Context) {
 	}
 	logger.Debugf("request params %v", req)
 
+	if req.ChainStatus != "" && !models.IsChainStatus(req.ChainStatus) {
+		e := fmt.Errorf("chain status invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	cursor, err := structs.DecodeCursor(req.Cursor)
+	if err != nil {
+		e := fmt.Errorf("cursor invalid, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
 	params := &structs.QueryParams{
-		PageNum:   req.PageNum,
-		PageLimit: req.PageLimit,
-		StartTime: req.StartTime,
-		EndTime:   req.EndTime,
+		PageNum:     req.PageNum,
+		PageLimit:   req.PageLimit,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		ChainStatus: req.ChainStatus,
+		Cursor:      cursor,
 	}
 
 	result, err := h.srvcContext.DBStorage.QueryFunds(req.UID, req.TargetUID, req.UserType, req.PubType, params)
@@ -248,17 +377,19 @@ func (h *RestHandler) QueryFunds(c *gin.Context) {
 			BlockType:   v.BlockType,
 			BlockHeight: v.BlockHeight,
 			BlockTime:   v.BlockTime,
+			ChainStatus: v.ChainStatus,
 			CreatedAt:   v.CreatedAt.Unix(),
 		})
 	}
 
 	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.QueryFundsResp{
-		Total:     params.Total,
-		PageNum:   params.PageNum,
-		PageLimit: params.PageLimit,
-		StartTime: params.StartTime,
-		EndTime:   params.EndTime,
-		Results:   payload,
+		NextCursor: params.NextCursor.Encode(),
+		Total:      params.Total,
+		PageNum:    params.PageNum,
+		PageLimit:  params.PageLimit,
+		StartTime:  params.StartTime,
+		EndTime:    params.EndTime,
+		Results:    payload,
 	}))
 	logger.Info("response query funds success.")
 	return
@@ -305,6 +436,7 @@ func (h *RestHandler) QueryFundsDetail(c *gin.Context) {
 		BlockType:         f.Funds.BlockType,
 		BlockHeight:       f.Funds.BlockHeight,
 		BlockTime:         f.Funds.BlockTime,
+		ChainStatus:       f.Funds.ChainStatus,
 		CreatedAt:         f.Funds.CreatedAt.Unix(),
 	}
 
@@ -374,31 +506,46 @@ func (h *RestHandler) ReceiveSupplies(c *gin.Context) {
 		return
 	}
 
+	// the one who publishes is the user of access token instead of the one in request
+	claims, ok := middleware.CurrentUser(c)
+	if !ok {
+		e := fmt.Errorf("missing access token")
+		logger.Error(e)
+		c.JSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+		return
+	}
+	req.SetUIDBySuppliesReq(claims.UID, claims.UserType)
+
+	if !h.authorize(c, claims, req.PubType, req.TargetUID) {
+		return
+	}
+
 	ps := make([]*models.PubSupplies, 0)
 	addrs := make([]*models.Address, 0)
 	images := make([]*models.Image, 0)
-	bcJSONs := make([]*string, 0)
+	bcJSONs := make([]string, 0)
 	ids := make([]*structs.ReceiveSuppliesRespItem, 0)
 
 	for _, v := range req.SuppliesItem {
 		suppliesID := utils.GenerateUUID()
 
 		pubSupplies := &models.PubSupplies{
-			ID:         suppliesID,
-			WayBillNum: req.WayBillNum,
-			UID:        req.UID,
-			DonorName:  req.DonorName,
-			UserType:   req.UserType,
-			TargetUID:  req.TargetUID,
-			TargetName: req.TargetName,
-			PubType:    req.PubType,
-			Name:       v.Name,
-			Number:     v.Number,
-			Unit:       v.Unit,
-			Remark:     req.Remark,
+			ID:          suppliesID,
+			WayBillNum:  req.WayBillNum,
+			UID:         req.UID,
+			DonorName:   req.DonorName,
+			UserType:    req.UserType,
+			TargetUID:   req.TargetUID,
+			TargetName:  req.TargetName,
+			PubType:     req.PubType,
+			Name:        v.Name,
+			Number:      v.Number,
+			Unit:        v.Unit,
+			Remark:      req.Remark,
+			ChainStatus: rest.ChainStatusCreated,
 		}
 		ps = append(ps, pubSupplies)
-		ids = append(ids, &structs.ReceiveSuppliesRespItem{SuppliesID: suppliesID})
+		ids = append(ids, &structs.ReceiveSuppliesRespItem{SuppliesID: suppliesID, Status: rest.PendingChain})
 
 		billingAddr := &models.Address{
 			ID:        utils.GenerateUUID(),
@@ -459,10 +606,10 @@ func (h *RestHandler) ReceiveSupplies(c *gin.Context) {
 			return
 		}
 
-		bcJSONs = append(bcJSONs, &bcJSON)
+		bcJSONs = append(bcJSONs, bcJSON)
 	}
 
-	acc, err := h.srvcContext.DBStorage.QueryAccount("", req.GetUIDBySuppliesReq())
+	acc, err := h.srvcContext.DBStorage.QueryAccount("", "", req.GetUIDBySuppliesReq())
 	if err != nil {
 		e := fmt.Errorf("query user error, %s", err.Error())
 		logger.Error(e)
@@ -498,19 +645,23 @@ func (h *RestHandler) ReceiveSupplies(c *gin.Context) {
 		return
 	}
 
-	bcResults, err := h.srvcContext.IBCAdapter.Pubs(acc.DID, bcJSONs)
-	if err != nil {
-		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("publish supplies data to block chain error, %s", err.Error())
-		logger.Error(e)
-		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.PubToBlockChainFailure, e.Error()))
-		return
+	// published to block chain in one batch request by the background publisher
+	boxes := make([]*models.PubOutbox, 0)
+	for i, v := range ps {
+		boxes = append(boxes, &models.PubOutbox{
+			ID:        utils.GenerateUUID(),
+			RelatedID: v.ID,
+			Type:      rest.DonatedTypeSupplies,
+			BCID:      acc.DID,
+			Publicity: bcJSONs[i],
+			Status:    rest.ChainStatusPending,
+		})
 	}
 
-	err = h.srvcContext.DBStorage.UpdateSuppliesList(tx, ps, bcResults)
+	err = h.srvcContext.DBStorage.CreateOutbox(tx, boxes)
 	if err != nil {
 		h.srvcContext.DBStorage.DBTransactionRollback(tx)
-		e := fmt.Errorf("update supplies tx id error, %s", err.Error())
+		e := fmt.Errorf("create outbox error, %s", err.Error())
 		logger.Error(e)
 		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
 		return
@@ -518,55 +669,8 @@ func (h *RestHandler) ReceiveSupplies(c *gin.Context) {
 
 	h.srvcContext.DBStorage.DBTransactionCommit(tx)
 
-	/* 上区块链
-	bcMap := make(map[string]bool)
-	reqTime := time.Now().Unix()
-
-	for {
-		time.Sleep(time.Duration(1) * time.Second)
-
-		respTime := time.Now().Unix()
-		if respTime-reqTime >= timeoutOfOneSingleReq*3 {
-			c.JSON(http.StatusRequestTimeout, rest.ErrorResponse(rest.BlockChainCallBackTimeout, "block chain call back timeout"))
-			logger.Infof("block chain call back timeout")
-			return
-		}
-
-		done := true
-		for _, v := range bcResults {
-			bcID := v.Data.ID
-			_, err := bcCallBackInfoInRedis(h.srvcContext.RedisCli, bcID)
-
-			if err != nil {
-				if !bcMap[bcID] {
-					done = false
-				}
-
-				if err == redis.ErrNil {
-					continue
-				}
-
-				e := fmt.Errorf("get block chain call back data from redis error, %v", err)
-				logger.Error(e)
-				c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
-				return
-			}
-
-			bcMap[bcID] = true
-			continue
-		}
-
-		if done {
-			c.JSON(http.StatusOK, rest.SuccessResponse(ids))
-			logger.Infof("response receive funds success.")
-			return
-		}
-	}
-	//*/
-
 	c.JSON(http.StatusOK, rest.SuccessResponse(ids))
-	wlog.Debug("完成上链")
-	logger.Infof("response receive funds success.")
+	logger.Infof("response receive supplies success.")
 }
 
 // QuerySupplies defines the request of query supplies
@@ -583,11 +687,28 @@ func (h *RestHandler) QuerySupplies(c *gin.Context) {
 	}
 	logger.Debugf("request params %v", req)
 
+	if req.ChainStatus != "" && !models.IsChainStatus(req.ChainStatus) {
+		e := fmt.Errorf("chain status invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	cursor, err := structs.DecodeCursor(req.Cursor)
+	if err != nil {
+		e := fmt.Errorf("cursor invalid, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
 	params := &structs.QueryParams{
-		PageNum:   req.PageNum,
-		PageLimit: req.PageLimit,
-		StartTime: req.StartTime,
-		EndTime:   req.EndTime,
+		PageNum:     req.PageNum,
+		PageLimit:   req.PageLimit,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		ChainStatus: req.ChainStatus,
+		Cursor:      cursor,
 	}
 
 	result, err := h.srvcContext.DBStorage.QuerySupplies(req.UID, req.TargetUID, req.UserType, req.PubType, params)
@@ -617,17 +738,19 @@ func (h *RestHandler) QuerySupplies(c *gin.Context) {
 			BlockType:   v.BlockType,
 			BlockHeight: v.BlockHeight,
 			BlockTime:   v.BlockTime,
+			ChainStatus: v.ChainStatus,
 			CreatedAt:   v.CreatedAt.Unix(),
 		})
 	}
 
 	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.QuerySuppliesResp{
-		Total:     params.Total,
-		PageNum:   params.PageNum,
-		PageLimit: params.PageLimit,
-		StartTime: params.StartTime,
-		EndTime:   params.EndTime,
-		Results:   payload,
+		NextCursor: params.NextCursor.Encode(),
+		Total:      params.Total,
+		PageNum:    params.PageNum,
+		PageLimit:  params.PageLimit,
+		StartTime:  params.StartTime,
+		EndTime:    params.EndTime,
+		Results:    payload,
 	}))
 	logger.Info("response query supplies success.")
 	return
@@ -674,6 +797,7 @@ func (h *RestHandler) QuerySuppliesDetail(c *gin.Context) {
 		BlockType:   s.Supplies.BlockType,
 		BlockHeight: s.Supplies.BlockHeight,
 		BlockTime:   s.Supplies.BlockTime,
+		ChainStatus: s.Supplies.ChainStatus,
 		CreatedAt:   s.Supplies.CreatedAt.Unix(),
 	}
 
@@ -736,11 +860,28 @@ func (h *RestHandler) PubUserList(c *gin.Context) {
 	}
 	logger.Debugf("request params %v", req)
 
+	if req.ChainStatus != "" && !models.IsChainStatus(req.ChainStatus) {
+		e := fmt.Errorf("chain status invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	cursor, err := structs.DecodeCursor(req.Cursor)
+	if err != nil {
+		e := fmt.Errorf("cursor invalid, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
 	params := &structs.QueryParams{
-		PageNum:   req.PageNum,
-		PageLimit: req.PageLimit,
-		StartTime: req.StartTime,
-		EndTime:   req.EndTime,
+		PageNum:     req.PageNum,
+		PageLimit:   req.PageLimit,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		ChainStatus: req.ChainStatus,
+		Cursor:      cursor,
 	}
 
 	result, err := h.srvcContext.DBStorage.QueryPubByUserType(req.UserType, req.TargetUID, req.PubType, params)
@@ -758,6 +899,7 @@ func (h *RestHandler) PubUserList(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.PubUserResp{
+		NextCursor:  params.NextCursor.Encode(),
 		Total:       params.Total,
 		PageNum:     params.PageNum,
 		PageLimit:   params.PageLimit,
@@ -771,3 +913,189 @@ func (h *RestHandler) PubUserList(c *gin.Context) {
 	logger.Info("response query records success.")
 	return
 }
+
+// PubStatus defines the status of publicity on block chain
+func (h *RestHandler) PubStatus(c *gin.Context) {
+	logger.Info("got query publicity status request")
+
+	req := &structs.PubStatusRequest{}
+	var err error
+	if err = c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+	logger.Debugf("request params %v", req)
+
+	if req.Wait > maxStatusWait {
+		req.Wait = maxStatusWait
+	}
+
+	box, err := h.srvcContext.DBStorage.QueryOutbox(req.ID)
+	deadline := time.Now().Add(time.Duration(req.Wait) * time.Second)
+
+	// long poll until the publicity reaches a final status
+	for err == nil && time.Now().Before(deadline) &&
+		box.Status != rest.ChainStatusConfirmed && box.Status != rest.ChainStatusFailed {
+		time.Sleep(time.Second)
+		box, err = h.srvcContext.DBStorage.QueryOutbox(req.ID)
+	}
+
+	if err != nil {
+		e := fmt.Errorf("query publicity status error, %s", err.Error())
+		logger.Error(e)
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(&structs.PubStatusResp{
+		ID:          box.RelatedID,
+		Type:        box.Type,
+		Status:      box.Status,
+		BlockID:     box.BlockID,
+		TxID:        box.TxID,
+		BlockHeight: box.BlockHeight,
+		Attempts:    box.Attempts,
+		LastError:   box.LastError,
+		UpdatedAt:   box.UpdatedAt.Unix(),
+	}))
+	logger.Info("response query publicity status success.")
+}
+
+// PubVerify defines verifying the publicity recomputed from database against the one on block chain
+func (h *RestHandler) PubVerify(c *gin.Context) {
+	logger.Info("got verify publicity request")
+
+	req := &structs.PubVerifyRequest{}
+	var err error
+	if err = c.Bind(req); err != nil {
+		e := fmt.Errorf("invalid parameters, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+	logger.Debugf("request params %v", req)
+
+	var blockID, storedHash string
+	var version int
+	var publicity func(t int64) (string, error)
+
+	switch req.Type {
+	case rest.DonatedTypeFunds:
+		f, err := h.srvcContext.DBStorage.QueryFundsDetail(req.ID)
+		if err != nil {
+			e := fmt.Errorf("query funds detail error, %s", err.Error())
+			logger.Error(e)
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return
+		}
+
+		blockID = f.Funds.BlockID
+		storedHash = f.Funds.PayloadHash
+		version = f.Funds.SchemaVer
+		publicity = f.Publicity
+	case rest.DonatedTypeSupplies:
+		s, err := h.srvcContext.DBStorage.QuerySuppliesDetail(req.ID)
+		if err != nil {
+			e := fmt.Errorf("query supplies detail error, %s", err.Error())
+			logger.Error(e)
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+				return
+			}
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return
+		}
+
+		// the addresses published are the ones kept with the supplies
+		addrs, err := h.srvcContext.DBStorage.QueryAddresses(req.ID)
+		if err != nil {
+			e := fmt.Errorf("query addresses error, %s", err.Error())
+			logger.Error(e)
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DatabaseOperationFailed, e.Error()))
+			return
+		}
+
+		for _, v := range addrs {
+			switch v.Type {
+			case rest.AddrBilling:
+				s.BillingAddr = *v
+			case rest.AddrShipping:
+				s.ShippingAddr = *v
+			}
+		}
+
+		blockID = s.Supplies.BlockID
+		storedHash = s.Supplies.PayloadHash
+		version = s.Supplies.SchemaVer
+		publicity = s.Publicity
+	default:
+		e := fmt.Errorf("type invalid")
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.InvalidParamsErrCode, e.Error()))
+		return
+	}
+
+	if blockID == "" {
+		e := fmt.Errorf("publicity %s not on block chain yet", req.ID)
+		logger.Error(e)
+		c.JSON(http.StatusBadRequest, rest.ErrorResponse(rest.PubNotOnBlockChain, e.Error()))
+		return
+	}
+
+	chain, err := h.srvcContext.IBCAdapter.QueryPub(blockID)
+	if err != nil {
+		e := fmt.Errorf("query publicity from block chain error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.QueryBlockChainFailure, e.Error()))
+		return
+	}
+
+	// the time of legacy payload is not kept with the record, it is taken from the outbox,
+	// the records published before outbox fall back to the time on block chain
+	var t int64
+	if version < rest.PayloadSchemaVersion {
+		box, err := h.srvcContext.DBStorage.QueryOutbox(req.ID)
+		if err == nil {
+			t, err = models.PublicityTime(box.Publicity)
+		}
+		if err != nil {
+			logger.Warningf("publicity time of %s not found, use the one on block chain, %v", req.ID, err)
+			t, _ = models.PublicityTime(chain.Data.Publicity)
+		}
+	}
+
+	local, err := publicity(t)
+	if err != nil {
+		e := fmt.Errorf("convert publicity error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.SerializeDataFail, e.Error()))
+		return
+	}
+
+	resp, err := models.VerifyPublicity(local, chain.Data.Publicity, storedHash, version)
+	if err != nil {
+		e := fmt.Errorf("compare publicity error, %s", err.Error())
+		logger.Error(e)
+		c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.DeserializeDataFail, e.Error()))
+		return
+	}
+
+	resp.ID = req.ID
+	resp.Type = req.Type
+	resp.BlockID = blockID
+	resp.TxID = chain.Data.TxID
+	resp.BlockHeight = chain.Data.BlockHeight
+
+	c.JSON(http.StatusOK, rest.SuccessResponse(resp))
+	logger.Info("response verify publicity success.")
+}
@@ -13,18 +13,27 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
 	"github.com/csiabb/donation-service/components/bcadapter/mock_bcadapter"
+	"github.com/csiabb/donation-service/components/policy"
+	"github.com/csiabb/donation-service/components/wx"
+	"github.com/csiabb/donation-service/components/wx/mock_wx"
+	"github.com/csiabb/donation-service/config"
 	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/controllers/acc"
+	"github.com/csiabb/donation-service/middleware"
 	"github.com/csiabb/donation-service/models"
 	"github.com/csiabb/donation-service/models/mock_backend"
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
 	"github.com/rafaeljusto/redigomock"
 	"github.com/shopspring/decimal"
@@ -36,12 +45,14 @@ const (
 	urlPubSupplies       = "/api/v1/pub/supplies"
 	urlPubSuppliesDetail = "/api/v1/pub/supplies/detail"
 	urlPubList           = "/api/v1/pub/list"
+	urlPubStatus         = "/api/v1/pub/status"
+	urlPubVerify         = "/api/v1/pub/verify"
+	urlAccLoginWXApp     = "/api/v1/acc/login/wxapp"
 )
 
 const (
 	fundsBodyJSON = `{
   "uid": "uid_test",
-  "donor_uid": "donor_uid_test",
   "donor_name": "donor_name",
   "user_type": "normal",
   "target_uid": "target_uid_test",
@@ -63,7 +74,6 @@ const (
 
 	suppliesBodyJSON = `{
   "uid": "uid_test",
-  "donor_uid": "donor_uid_test",
   "donor_name": "donor_name",
   "user_type": "normal",
   "target_uid": "target_uid_test",
@@ -118,30 +128,53 @@ func Init(t *testing.T) (*gomock.Controller, *RestHandler, *mock_backend.MockIDB
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "uid_test", UserType: "normal", Kind: auth.TokenAccess})
 
 	// init redigo mock connection
 	redisCli := redigomock.NewConn()
-	redisCli.Command(rest.RedisGet, "block_id_1").Expect("tx_id_test")
 
 	// init mock handler
 	handler := RestHandler{}
 	handler.srvcContext = &context.Context{}
 	handler.srvcContext.DBStorage = mockBackend
 	handler.srvcContext.IBCAdapter = mockBCAdapter
-	handler.srvcContext.RedisCli = redisCli
+	handler.srvcContext.RedisPool = &redis.Pool{Dial: func() (redis.Conn, error) { return redisCli, nil }}
+	handler.srvcContext.Policy, _ = policy.NewPolicy(&policy.Config{})
+	handler.srvcContext.WXClient = mock_wx.NewMockIWXClient(mockCtl)
+	handler.srvcContext.WXTokens = mock_wx.NewMockITokenManager(mockCtl)
+	handler.srvcContext.Config = &config.SrvcCfg{}
+	handler.srvcContext.Config.WXCfg = wx.ClientCfg{AppID: "wx_app_id", Secret: "wx_secret"}
 
 	return mockCtl, &handler, mockBackend, mockBCAdapter, w, c
 }
 
+// expectCharity mocks the target of publicity as account of charity
+func expectCharity(mockBackend *mock_backend.MockIDBBackend, uid string) {
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), uid).Return(&models.Account{
+		ID:   uid,
+		Type: rest.UserTypeOrgCharity,
+	}, nil)
+}
+
+// expectKycCharity mocks the target of distribution as charity approved by kyc
+func expectKycCharity(mockBackend *mock_backend.MockIDBBackend, uid string) {
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), uid).Return(&models.Account{
+		ID:        uid,
+		Type:      rest.UserTypeOrgCharity,
+		KycStatus: rest.KycStatusApproved,
+	}, nil)
+}
+
 func TestReceiveFundsSucceed(t *testing.T) {
-	mockCtl, handler, mockBackend, mockBCAdapter, w, c := Init(t)
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
 	defer mockCtl.Finish()
+	expectCharity(mockBackend, "target_uid_test")
 
 	db := &gorm.DB{}
 	mockBackend.EXPECT().GetDBTransaction().Return(db)
 	mockBackend.EXPECT().CreateFunds(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().CreateImages(gomock.Any(), gomock.Any()).Return(nil)
-	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any()).Return(&models.Account{
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(&models.Account{
 		ID:             "account_id",
 		Access:         "access",
 		Password:       "Aa111111",
@@ -161,14 +194,7 @@ func TestReceiveFundsSucceed(t *testing.T) {
 		AppID:          "",
 		CreatedAt:      time.Now(),
 	}, nil)
-	mockBCAdapter.EXPECT().Pubs(gomock.Any(), gomock.Any()).Return([]*structs.PubResp{
-		{
-			Code: 0,
-			Msg:  "",
-			Data: structs.PubRespData{ID: "block_id_1"},
-		},
-	}, nil)
-	mockBackend.EXPECT().UpdateFunds(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateOutbox(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().DBTransactionCommit(gomock.Any())
 
 	// mock request
@@ -178,6 +204,401 @@ func TestReceiveFundsSucceed(t *testing.T) {
 	CommRespCheck(t, w)
 }
 
+func TestReceiveFundsUIDOfToken(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+	expectCharity(mockBackend, "target_uid_test")
+
+	// the uid sent by client is overwritten by the one of access token
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "receive"`, 1)
+	body = strings.Replace(body, `"uid": "uid_test"`, `"uid": "other_uid"`, 1)
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateFunds(db, gomock.Any()).DoAndReturn(func(tx *gorm.DB, funds *models.PubFunds) error {
+		if funds.UID != "uid_test" || funds.TargetUID != "target_uid_test" {
+			t.Errorf("uid of funds check failed, %s", funds.UID)
+		}
+		return nil
+	})
+	mockBackend.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "uid_test").Return(&models.Account{ID: "uid_test", DID: "did"}, nil)
+	mockBackend.EXPECT().CreateOutbox(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+	CommRespCheck(t, w)
+}
+
+func TestReceiveFundsUnauthorized(t *testing.T) {
+	mockCtl, handler, _, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+
+	// mock request without access token claims
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(fundsBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("missing access token check failed")
+	}
+}
+
+func TestReceiveFundsPermissionDenied(t *testing.T) {
+	mockCtl, handler, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	// normal user is not allowed to distribute
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("pub type permission check failed")
+	}
+}
+
+func TestReceiveFundsTargetNotCharity(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "target_uid_test").Return(&models.Account{
+		ID:   "target_uid_test",
+		Type: rest.UserTypeNormal,
+	}, nil)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(fundsBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("target permission check failed")
+	}
+}
+
+func TestReceiveSuppliesDistributeByCharity(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "charity_uid", UserType: rest.UserTypeOrgCharity, Kind: auth.TokenAccess})
+
+	db := &gorm.DB{}
+	expectKycCharity(mockBackend, "charity_uid")
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "charity_uid").Return(&models.Account{
+		ID:   "charity_uid",
+		Type: rest.UserTypeOrgCharity,
+		DID:  "did:axn:charity",
+	}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateSupplies(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateAddresses(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateImages(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateOutbox(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(gomock.Any())
+
+	// the target of distribution is the charity itself, whatever in request
+	body := strings.Replace(suppliesBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubSupplies, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveSupplies(c)
+	CommRespCheck(t, w)
+}
+
+func TestReceiveSuppliesDistributeKycNotApproved(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "charity_uid", UserType: rest.UserTypeOrgCharity, Kind: auth.TokenAccess})
+
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "charity_uid").Return(&models.Account{
+		ID:        "charity_uid",
+		Type:      rest.UserTypeOrgCharity,
+		KycStatus: rest.KycStatusPending,
+	}, nil)
+
+	body := strings.Replace(suppliesBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubSupplies, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveSupplies(c)
+
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "not approved") {
+		t.Error("kyc of charity check failed")
+	}
+}
+
+func TestReceiveSuppliesAdmin(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "admin_uid", UserType: rest.UserTypeAdmin, Kind: auth.TokenAccess})
+
+	// admin is allowed any target, the target is not queried
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "admin_uid").Return(&models.Account{
+		ID:   "admin_uid",
+		Type: rest.UserTypeAdmin,
+		DID:  "did:axn:admin",
+	}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateSupplies(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateAddresses(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateImages(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateOutbox(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(gomock.Any())
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubSupplies, bytes.NewBufferString(suppliesBodyJSON))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveSupplies(c)
+	CommRespCheck(t, w)
+}
+
+func TestReceiveFundsDistributeByAdmin(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "admin_uid", UserType: rest.UserTypeAdmin, Kind: auth.TokenAccess})
+
+	// admin distributes on behalf of the charity in request, published by the charity
+	db := &gorm.DB{}
+	expectKycCharity(mockBackend, "target_uid_test")
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "target_uid_test").Return(&models.Account{
+		ID:   "target_uid_test",
+		Type: rest.UserTypeOrgCharity,
+		DID:  "did:axn:charity",
+	}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateFunds(db, gomock.Any()).DoAndReturn(func(tx *gorm.DB, funds *models.PubFunds) error {
+		if funds.UID != "admin_uid" || funds.TargetUID != "target_uid_test" || funds.UserType != rest.UserTypeAdmin {
+			t.Errorf("distribution of admin check failed, %v", funds)
+		}
+		return nil
+	})
+	mockBackend.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateOutbox(db, gomock.Any()).DoAndReturn(func(tx *gorm.DB, data []*models.PubOutbox) error {
+		if data[0].BCID != "did:axn:charity" {
+			t.Errorf("publisher of distribution check failed, %s", data[0].BCID)
+		}
+		return nil
+	})
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+	CommRespCheck(t, w)
+}
+
+func TestReceiveFundsDistributeByAdminKycNotApproved(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+
+	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "admin_uid", UserType: rest.UserTypeAdmin, Kind: auth.TokenAccess})
+
+	// kyc is checked of the charity in request instead of admin
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "target_uid_test").Return(&models.Account{
+		ID:        "target_uid_test",
+		Type:      rest.UserTypeOrgCharity,
+		KycStatus: rest.KycStatusPending,
+	}, nil)
+
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "target_uid_test") {
+		t.Error("kyc of charity distributed by admin check failed")
+	}
+}
+
+func TestReceiveFundsAfterWXLogin(t *testing.T) {
+	mockCtl, handler, mockBackend, mockBCAdapter, _, _ := Init(t)
+	defer mockCtl.Finish()
+
+	var err error
+	handler.srvcContext.AuthBackend, err = auth.NewAuthBackend(&auth.Config{Secret: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.srvcContext.Config.WXCfg.Apps = []wx.App{{AppID: "xxyyzz", Secret: "xxyyzz_secret"}}
+	redisCli := redigomock.NewConn()
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+	handler.srvcContext.RedisPool = &redis.Pool{Dial: func() (redis.Conn, error) { return redisCli, nil }}
+
+	accHandler, _ := acc.NewRestHandler(handler.srvcContext)
+	router := gin.New()
+	router.POST(urlAccLoginWXApp, accHandler.LoginWXApp)
+	router.POST(urlPubFunds, middleware.TokenAuth(handler.srvcContext), handler.ReceiveFunds)
+
+	// the user signs up from wechat
+	var account *models.Account
+	mockWX := handler.srvcContext.WXClient.(*mock_wx.MockIWXClient)
+	mockWX.EXPECT().WXLogin("xxyyzz", "xxyyzz_secret", "cert_code").Return(wx.LoginResponse{OpenID: "open_id", SessionKey: "session_key"}, nil)
+	mockBackend.EXPECT().QueryWXBinding("", "xxyyzz", "open_id", "").Return(nil, gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().QueryAccount("xxyyzz", "open_id", "").Return(nil, gorm.ErrRecordNotFound)
+	mockBCAdapter.EXPECT().Register(gomock.Any()).Return(&structs.RegisterResp{Data: structs.RegisterRespData{ID: "did:axn:wx"}}, nil)
+	mockBackend.EXPECT().CreateAccount(gomock.Any()).DoAndReturn(func(a *models.Account) error {
+		account = a
+		return nil
+	})
+	mockBackend.EXPECT().CreateWXBinding(gomock.Any()).Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, urlAccLoginWXApp, bytes.NewBufferString(`{"cert_code": "cert_code", "app_id": "xxyyzz"}`))
+	req.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	router.ServeHTTP(w, req)
+
+	login := &structs.LoginResp{}
+	if err := json.Unmarshal(w.Body.Bytes(), &rest.CommonResponse{Data: login}); err != nil || login.AccessToken == "" {
+		t.Fatalf("login from wechat failed, %d %s", w.Code, w.Body.String())
+	}
+
+	// then donates with the access token
+	db := &gorm.DB{}
+	expectCharity(mockBackend, "target_uid_test")
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateFunds(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateImages(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().QueryAccount("", "", login.UID).DoAndReturn(func(appID, openID, uid string) (*models.Account, error) {
+		return account, nil
+	})
+	mockBackend.EXPECT().CreateOutbox(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(fundsBodyJSON))
+	req.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	req.Header.Add(rest.HeaderAuthorization, rest.BearerPrefix+login.AccessToken)
+	router.ServeHTTP(w, req)
+	CommRespCheck(t, w)
+}
+
+// initSoter requires soter signature of charity distributing funds above 50
+func initSoter(t *testing.T, handler *RestHandler, w *httptest.ResponseRecorder) (*mock_wx.MockIWXClient, *mock_wx.MockITokenManager, *gin.Context) {
+	var err error
+	handler.srvcContext.Policy, err = policy.NewPolicy(&policy.Config{SoterThreshold: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := gin.CreateTestContext(w)
+	c.Set(rest.ContextClaims, &auth.Claims{UID: "charity_uid", UserType: rest.UserTypeOrgCharity, Kind: auth.TokenAccess})
+
+	return handler.srvcContext.WXClient.(*mock_wx.MockIWXClient), handler.srvcContext.WXTokens.(*mock_wx.MockITokenManager), c
+}
+
+func TestReceiveFundsSoterRequired(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+	_, _, c := initSoter(t, handler, w)
+	expectKycCharity(mockBackend, "charity_uid")
+
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("soter required check failed")
+	}
+}
+
+func TestReceiveFundsSoterSucceed(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+	mockWX, mockTokens, c := initSoter(t, handler, w)
+
+	db := &gorm.DB{}
+	mockTokens.EXPECT().AccessToken("wx_app_id", "wx_secret").Return("access_token", nil)
+	mockWX.EXPECT().CheckFinger(structs.FingerRequest{
+		OpenID:        "charity_open_id",
+		JSONString:    "soter_json",
+		JSONSignature: "soter_signature",
+	}, "access_token").Return(&structs.FingerResponse{IsOK: true}, nil)
+	expectKycCharity(mockBackend, "charity_uid")
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), "charity_uid").Return(&models.Account{
+		ID:   "charity_uid",
+		Type: rest.UserTypeOrgCharity,
+		DID:  "did:axn:charity",
+	}, nil)
+	mockBackend.EXPECT().QueryWXBinding("charity_uid", "wx_app_id", "", "").Return(&models.WXBinding{
+		UID:    "charity_uid",
+		AppID:  "wx_app_id",
+		OpenID: "charity_open_id",
+	}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateFunds(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateImages(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateOutbox(gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(gomock.Any())
+
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+	body = strings.Replace(body, `"remark": "remark message",`, `"remark": "remark message",
+  "soter": {"json_string": "soter_json", "json_signature": "soter_signature"},`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+	CommRespCheck(t, w)
+}
+
+func TestReceiveFundsSoterFailed(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, _ := Init(t)
+	defer mockCtl.Finish()
+	mockWX, mockTokens, c := initSoter(t, handler, w)
+
+	mockTokens.EXPECT().AccessToken(gomock.Any(), gomock.Any()).Return("access_token", nil)
+	mockWX.EXPECT().CheckFinger(gomock.Any(), "access_token").Return(&structs.FingerResponse{
+		IsOK:    false,
+		Errcode: 90010,
+		Errmsg:  "signature invalid",
+	}, nil)
+	expectKycCharity(mockBackend, "charity_uid")
+	mockBackend.EXPECT().QueryWXBinding("charity_uid", gomock.Any(), "", "").Return(&models.WXBinding{
+		UID:    "charity_uid",
+		OpenID: "charity_open_id",
+	}, nil)
+
+	body := strings.Replace(fundsBodyJSON, `"pub_type": "donate"`, `"pub_type": "distribute"`, 1)
+	body = strings.Replace(body, `"remark": "remark message",`, `"remark": "remark message",
+  "soter": {"json_string": "soter_json", "json_signature": "soter_signature"},`, 1)
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodPost, urlPubFunds, bytes.NewBufferString(body))
+	c.Request.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	handler.ReceiveFunds(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("soter verify check failed")
+	}
+}
+
 func TestReceiveFundsParams(t *testing.T) {
 	mockCtl, handler, _, _, w, c := Init(t)
 	defer mockCtl.Finish()
@@ -203,6 +624,7 @@ func TestReceiveFundsParams(t *testing.T) {
 func TestReceiveFundsDB(t *testing.T) {
 	mockCtl, handler, mockBackend, _, w, c := Init(t)
 	defer mockCtl.Finish()
+	expectCharity(mockBackend, "target_uid_test")
 
 	// post body
 	body := bytes.NewBufferString(fundsBodyJSON)
@@ -262,6 +684,60 @@ func TestQueryFundsSucceed(t *testing.T) {
 	CommRespCheck(t, w)
 }
 
+func TestQueryFundsCursor(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	at := time.Unix(1588291200, 0)
+	cursor := structs.NewCursor(at, "id_2")
+	mockBackend.EXPECT().QueryFunds(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(uid, targetUID, userType, pubType string, params *structs.QueryParams) ([]*models.PubFunds, error) {
+			if params.Cursor == nil || *params.Cursor != *cursor {
+				t.Errorf("cursor check failed, %v", params.Cursor)
+			}
+
+			params.Total = 3
+			params.NextCursor = structs.NewCursor(at, "id_1")
+			return []*models.PubFunds{{ID: "id_1", Amount: decimal.NewFromInt(20), CreatedAt: at}}, nil
+		})
+
+	url := urlPubFunds + "?user_type=normal&page_limit=1&cursor=" + cursor.Encode()
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodGet, url, nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.QueryFunds(c)
+
+	b, _ := ioutil.ReadAll(w.Body)
+	resp := &struct {
+		Data structs.QueryFundsResp `json:"data"`
+	}{}
+	if err := json.Unmarshal(b, resp); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := structs.DecodeCursor(resp.Data.NextCursor)
+	if err != nil || next == nil || next.ID != "id_1" || resp.Data.Total != 3 {
+		t.Errorf("next cursor check failed, %s", string(b))
+	}
+}
+
+func TestQueryFundsCursorInvalid(t *testing.T) {
+	mockCtl, handler, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	url := urlPubFunds + "?user_type=normal&cursor=invalid"
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodGet, url, nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.QueryFunds(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("cursor check failed")
+	}
+}
+
 func TestQueryFundsDB(t *testing.T) {
 	mockCtl, handler, mockBackend, _, w, c := Init(t)
 	defer mockCtl.Finish()
@@ -285,6 +761,27 @@ func TestQueryFundsDB(t *testing.T) {
 	}
 }
 
+func TestQueryFundsChainStatus(t *testing.T) {
+	mockCtl, handler, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	url := urlPubFunds + "?uid=&user_type=normal&chain_status=unknown&page_num=1&page_limit=10"
+
+	// mock request
+	c.Request, _ = http.NewRequest(http.MethodGet, url, nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.QueryFunds(c)
+	_, err := ioutil.ReadAll(w.Body)
+
+	if err != nil {
+		t.Errorf("io read err, %v", err)
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("query funds chain status check failed")
+	}
+}
+
 func TestQueryFundsDetailSucceed(t *testing.T) {
 	mockCtl, handler, mockBackend, _, w, c := Init(t)
 	defer mockCtl.Finish()
@@ -388,11 +885,12 @@ func TestQueryFundsDetailDB(t *testing.T) {
 }
 
 func TestReceiveSuppliesSucceed(t *testing.T) {
-	mockCtl, handler, mockBackend, mockBCAdapter, w, c := Init(t)
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
 	defer mockCtl.Finish()
+	expectCharity(mockBackend, "target_uid_test")
 
 	// mock db
-	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any()).Return(&models.Account{
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(&models.Account{
 		ID:             "account_id",
 		Access:         "access",
 		Password:       "Aa111111",
@@ -417,14 +915,7 @@ func TestReceiveSuppliesSucceed(t *testing.T) {
 	mockBackend.EXPECT().CreateSupplies(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().CreateAddresses(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().CreateImages(gomock.Any(), gomock.Any()).Return(nil)
-	mockBCAdapter.EXPECT().Pubs(gomock.Any(), gomock.Any()).Return([]*structs.PubResp{
-		{
-			Code: 0,
-			Msg:  "",
-			Data: structs.PubRespData{ID: "block_id_1"},
-		},
-	}, nil)
-	mockBackend.EXPECT().UpdateSuppliesList(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockBackend.EXPECT().CreateOutbox(gomock.Any(), gomock.Any()).Return(nil)
 	mockBackend.EXPECT().DBTransactionCommit(gomock.Any())
 
 	// mock request
@@ -463,9 +954,10 @@ func TestReceiveSuppliesParams(t *testing.T) {
 func TestReceiveSuppliesDB(t *testing.T) {
 	mockCtl, handler, mockBackend, _, w, c := Init(t)
 	defer mockCtl.Finish()
+	expectCharity(mockBackend, "target_uid_test")
 
 	// mock db
-	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any()).Return(&models.Account{
+	mockBackend.EXPECT().QueryAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(&models.Account{
 		ID:             "account_id",
 		Access:         "access",
 		Password:       "Aa111111",
@@ -708,6 +1200,258 @@ func TestPubUserListDB(t *testing.T) {
 	}
 }
 
+func TestPubStatusSucceed(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryOutbox(gomock.Any()).Return(&models.PubOutbox{
+		ID:          "outbox_id",
+		RelatedID:   "funds_id",
+		Type:        "funds",
+		Status:      "confirmed",
+		BlockID:     "block_id_1",
+		TxID:        "tx_id_test",
+		BlockHeight: 3322,
+		Attempts:    1,
+		UpdatedAt:   time.Now(),
+	}, nil)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubStatus+"?id=funds_id&wait=10", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubStatus(c)
+	CommRespCheck(t, w)
+}
+
+func TestPubStatusParams(t *testing.T) {
+	mockCtl, handler, _, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubStatus, nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubStatus(c)
+	_, err := ioutil.ReadAll(w.Body)
+
+	if err != nil {
+		t.Errorf("io read err, %v", err)
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("pub status param check failed")
+	}
+}
+
+func TestPubStatusDB(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryOutbox(gomock.Any()).Return(nil, errors.New("record not found"))
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubStatus+"?id=funds_id", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubStatus(c)
+	_, err := ioutil.ReadAll(w.Body)
+
+	if err != nil {
+		t.Errorf("io read err, %v", err)
+	}
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("pub status db check failed")
+	}
+}
+
+func TestPubStatusNotFound(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryOutbox(gomock.Any()).Return(nil, gorm.ErrRecordNotFound)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubStatus+"?id=funds_id", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubStatus(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Error("pub status not found check failed")
+	}
+}
+
+func verifyFundsDetail() *models.FundsDetail {
+	return &models.FundsDetail{
+		Funds: models.PubFunds{
+			ID:                "funds_id",
+			UID:               "uid_test",
+			DonorName:         "donor_name_test",
+			TargetName:        "target_name_test",
+			TargetBankCardNum: "2233-9933-2232-9233",
+			PubType:           rest.PubTypeDonate,
+			Amount:            decimal.NewFromInt(20),
+			BlockID:           "block_id_1",
+		},
+		ProofImages: []*models.Image{
+			{
+				ID:        "image_id",
+				RelatedID: "funds_id",
+				Type:      "proof",
+				URL:       "www.baidu.com",
+				Index:     "adkadkadk",
+			},
+		},
+	}
+}
+
+func TestPubVerifySucceed(t *testing.T) {
+	mockCtl, handler, mockBackend, mockBCAdapter, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	detail := verifyFundsDetail()
+	publicity, _ := detail.Publicity(1584932344)
+
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(detail, nil)
+	mockBCAdapter.EXPECT().QueryPub("block_id_1").Return(&structs.QueryPubResp{
+		Data: structs.QueryPubRespData{
+			ID:          "block_id_1",
+			Publicity:   publicity,
+			TxID:        "tx_id_test",
+			BlockHeight: 3322,
+		},
+	}, nil)
+	mockBackend.EXPECT().QueryOutbox("funds_id").Return(&models.PubOutbox{Publicity: publicity}, nil)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubVerify+"?id=funds_id&type=funds", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubVerify(c)
+
+	resp := &struct {
+		Data structs.PubVerifyResp `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK || !resp.Data.Match || resp.Data.LocalHash != resp.Data.ChainHash {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestPubVerifyCanonical(t *testing.T) {
+	mockCtl, handler, mockBackend, mockBCAdapter, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	detail := verifyFundsDetail()
+	publicity, err := detail.Funds.ConvertFundsDonation(detail.ProofImages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// round trip of database
+	detail.Funds.Amount, _ = decimal.NewFromString("20.0000")
+
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(detail, nil)
+	mockBCAdapter.EXPECT().QueryPub("block_id_1").Return(&structs.QueryPubResp{
+		Data: structs.QueryPubRespData{ID: "block_id_1", Publicity: publicity},
+	}, nil)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubVerify+"?id=funds_id&type=funds", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubVerify(c)
+
+	resp := &struct {
+		Data structs.PubVerifyResp `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK || !resp.Data.Match || resp.Data.StoredHash != resp.Data.ChainHash ||
+		resp.Data.SchemaVersion != rest.PayloadSchemaVersion {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestPubVerifyMismatch(t *testing.T) {
+	mockCtl, handler, mockBackend, mockBCAdapter, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	detail := verifyFundsDetail()
+	publicity, _ := detail.Publicity(1584932344)
+	detail.Funds.Amount = decimal.NewFromInt(2000)
+
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(detail, nil)
+	mockBCAdapter.EXPECT().QueryPub("block_id_1").Return(&structs.QueryPubResp{
+		Data: structs.QueryPubRespData{ID: "block_id_1", Publicity: publicity},
+	}, nil)
+	mockBackend.EXPECT().QueryOutbox("funds_id").Return(nil, gorm.ErrRecordNotFound)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubVerify+"?id=funds_id&type=funds", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubVerify(c)
+
+	resp := &struct {
+		Data structs.PubVerifyResp `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK || resp.Data.Match {
+		t.Error(w.Code, w.Body.String())
+	}
+
+	for _, v := range resp.Data.Fields {
+		if v.Match == (v.Field == "amount") {
+			t.Errorf("field %s match %v", v.Field, v.Match)
+		}
+	}
+}
+
+func TestPubVerifyNotOnChain(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	detail := verifyFundsDetail()
+	detail.Funds.BlockID = ""
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(detail, nil)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubVerify+"?id=funds_id&type=funds", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubVerify(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Error("pub verify block id check failed")
+	}
+}
+
+func TestPubVerifyBlockChain(t *testing.T) {
+	mockCtl, handler, mockBackend, mockBCAdapter, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(verifyFundsDetail(), nil)
+	mockBCAdapter.EXPECT().QueryPub("block_id_1").Return(nil, errors.New("adapter unavailable"))
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubVerify+"?id=funds_id&type=funds", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubVerify(c)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error("pub verify block chain check failed")
+	}
+}
+
+func TestPubVerifyNotFound(t *testing.T) {
+	mockCtl, handler, mockBackend, _, w, c := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QuerySuppliesDetail("supplies_id").Return(nil, gorm.ErrRecordNotFound)
+
+	c.Request, _ = http.NewRequest(http.MethodGet, urlPubVerify+"?id=supplies_id&type=supplies", nil)
+	c.Request.Header.Add(rest.HeaderAccept, rest.HeaderApplicationJSON)
+	handler.PubVerify(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Error("pub verify not found check failed")
+	}
+}
+
 func CommRespCheck(t *testing.T, w *httptest.ResponseRecorder) {
 	b, err := ioutil.ReadAll(w.Body)
 
@@ -25,8 +25,15 @@ var (
 
 	app = kingpin.New(metadata.ProgramName, "rest server for client business integration")
 
-	startCmd   = app.Command("start", fmt.Sprintf("Start the %s server", metadata.ProgramName)).Default()
-	versionCmd = app.Command("version", "Show version information")
+	startCmd     = app.Command("start", fmt.Sprintf("Start the %s server", metadata.ProgramName)).Default()
+	versionCmd   = app.Command("version", "Show version information")
+	reconcileCmd = app.Command("reconcile", "Re-publish the publicities stuck without block chain call back")
+	verifyCmd    = app.Command("verify", "Verify the exported donation records against their block chain receipts offline")
+	verifyBundle = verifyCmd.Arg("bundle", "Exported bundle file of records and chain receipts").Required().ExistingFile()
+	statsCmd     = app.Command("recompute-stats", "Rebuild the donation statistics from the confirmed publicities and report the drift")
+	statsDryRun  = statsCmd.Flag("dry-run", "Report the drift without overwriting the statistics").Bool()
+	fakewxCmd    = app.Command("fakewx", "Serve a local stand-in of wechat api for development")
+	fakewxListen = fakewxCmd.Flag("listen", "Address the stand-in listens on").Default("127.0.0.1:8880").String()
 )
 
 func cleanup() {
@@ -52,6 +59,40 @@ func main() {
 		logger.Infof("Beginning to serve requests")
 
 		server.Start()
+	// "reconcile" command
+	case reconcileCmd.FullCommand():
+		conf := config.GetServiceCfg(metadata.ProgramName)
+		log.InitLogConfig(&conf.Log)
+		if err := service.Reconcile(conf); err != nil {
+			logger.Errorf("Failed to reconcile publicities, %+v", err)
+			os.Exit(1)
+		}
+	// "recompute-stats" command
+	case statsCmd.FullCommand():
+		conf := config.GetServiceCfg(metadata.ProgramName)
+		log.InitLogConfig(&conf.Log)
+		if _, err := service.RecomputeStats(conf, os.Stdout, *statsDryRun); err != nil {
+			logger.Errorf("Failed to recompute donation statistics, %+v", err)
+			os.Exit(1)
+		}
+	// "verify" command
+	case verifyCmd.FullCommand():
+		passed, err := service.Verify(*verifyBundle, os.Stdout)
+		if err != nil {
+			logger.Errorf("Failed to verify bundle, %+v", err)
+			os.Exit(1)
+		}
+		if !passed {
+			os.Exit(1)
+		}
+	// "fakewx" command
+	case fakewxCmd.FullCommand():
+		conf := config.GetServiceCfg(metadata.ProgramName)
+		log.InitLogConfig(&conf.Log)
+		if err := service.FakeWX(conf, *fakewxListen); err != nil {
+			logger.Errorf("Failed to serve fake wechat api, %+v", err)
+			os.Exit(1)
+		}
 	// "version" command
 	case versionCmd.FullCommand():
 		fmt.Println(metadata.ProgramVersion.FullVersion())
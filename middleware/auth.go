@@ -0,0 +1,56 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenAuth validates the access token and keeps its claims for the handlers
+func TokenAuth(srvcContext *context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(rest.HeaderAuthorization)
+		if !strings.HasPrefix(header, rest.BearerPrefix) {
+			unauthorized(c, fmt.Errorf("missing access token"))
+			return
+		}
+
+		claims, err := srvcContext.AuthBackend.ParseToken(strings.TrimPrefix(header, rest.BearerPrefix), auth.TokenAccess)
+		if err != nil {
+			unauthorized(c, err)
+			return
+		}
+
+		c.Set(rest.ContextClaims, claims)
+		c.Next()
+	}
+}
+
+// CurrentUser returns the claims of the access token validated by TokenAuth
+func CurrentUser(c *gin.Context) (*auth.Claims, bool) {
+	v, ok := c.Get(rest.ContextClaims)
+	if !ok {
+		return nil, false
+	}
+
+	claims, ok := v.(*auth.Claims)
+	return claims, ok
+}
+
+func unauthorized(c *gin.Context, err error) {
+	e := fmt.Errorf("unauthorized, %s", err.Error())
+	logger.Error(e)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, rest.ErrorResponse(rest.InvalidToken, e.Error()))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/auth"
+	"github.com/csiabb/donation-service/context"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	urlAuthTest = "/api/v1/auth/test"
+)
+
+func InitAuth(t *testing.T) (*gin.Engine, auth.IAuthBackend) {
+	gin.SetMode(gin.TestMode)
+
+	authBackend, err := auth.NewAuthBackend(&auth.Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srvcContext := &context.Context{}
+	srvcContext.AuthBackend = authBackend
+
+	router := gin.New()
+	router.GET(urlAuthTest, TokenAuth(srvcContext), func(c *gin.Context) {
+		claims, ok := CurrentUser(c)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, "claims not found"))
+			return
+		}
+		c.JSON(http.StatusOK, rest.SuccessResponse(claims.UID))
+	})
+
+	return router, authBackend
+}
+
+func authRequest(token string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, urlAuthTest, nil)
+	if token != "" {
+		req.Header.Add(rest.HeaderAuthorization, rest.BearerPrefix+token)
+	}
+	return req
+}
+
+func TestTokenAuthSucceed(t *testing.T) {
+	router, authBackend := InitAuth(t)
+
+	tokens, err := authBackend.IssueTokens("uid_test", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, authRequest(tokens.AccessToken))
+	if w.Code != http.StatusOK {
+		t.Errorf("expect 200, got %d, %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTokenAuthRejected(t *testing.T) {
+	router, authBackend := InitAuth(t)
+
+	tokens, err := authBackend.IssueTokens("uid_test", "normal", "app_id", "session_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"", "invalid", tokens.RefreshToken} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, authRequest(token))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("token %q expect 401, got %d", token, w.Code)
+		}
+	}
+}
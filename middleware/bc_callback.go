@@ -0,0 +1,162 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/bcadapter"
+	"github.com/csiabb/donation-service/context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	defaultCallBackTTL = 300 // seconds
+	callBackNonceKey   = "bc:cb:nonce:%s"
+)
+
+// BCCallBackAuth verifies the source, signature and freshness of block chain call back
+func BCCallBackAuth(srvcContext *context.Context) gin.HandlerFunc {
+	cfg := srvcContext.Config.BCAdapterCfg
+
+	ttl := cfg.CallBackTTL
+	if ttl <= 0 {
+		ttl = defaultCallBackTTL
+	}
+
+	allowed, err := parseAllowIPs(cfg.CallBackAllowIPs)
+	if err != nil {
+		logger.Panicf("invalid call back allow ips, %v", err)
+	}
+
+	if cfg.CallBackSecret == "" {
+		logger.Error("block chain call back secret is empty, every call back will be rejected")
+	}
+
+	return func(c *gin.Context) {
+		// the forwarded headers are set by client, the source is the peer of connection
+		source, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			source = c.Request.RemoteAddr
+		}
+
+		if len(allowed) > 0 && !ipAllowed(allowed, source) {
+			reject(c, http.StatusForbidden, fmt.Errorf("source %s not allowed", source))
+			return
+		}
+
+		if cfg.CallBackSecret == "" {
+			reject(c, http.StatusUnauthorized, fmt.Errorf("call back secret not configured"))
+			return
+		}
+
+		timestamp := c.GetHeader(bcadapter.HeaderTimestamp)
+		nonce := c.GetHeader(bcadapter.HeaderNonce)
+		signature := c.GetHeader(bcadapter.HeaderSignature)
+		if timestamp == "" || nonce == "" || signature == "" {
+			reject(c, http.StatusUnauthorized, fmt.Errorf("missing signature headers"))
+			return
+		}
+
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			reject(c, http.StatusUnauthorized, fmt.Errorf("invalid timestamp, %s", err.Error()))
+			return
+		}
+
+		if skew := time.Now().Unix() - ts; skew > int64(ttl) || skew < -int64(ttl) {
+			reject(c, http.StatusUnauthorized, fmt.Errorf("timestamp expired"))
+			return
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			reject(c, http.StatusBadRequest, fmt.Errorf("read body error, %s", err.Error()))
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+		if !bcadapter.VerifySign(cfg.CallBackSecret, timestamp, nonce, body, signature) {
+			reject(c, http.StatusUnauthorized, fmt.Errorf("invalid signature"))
+			return
+		}
+
+		// the nonce is remembered for the whole valid period of timestamp
+		conn := srvcContext.RedisPool.Get()
+		_, err = redis.String(conn.Do(rest.RedisSet, fmt.Sprintf(callBackNonceKey, nonce), timestamp, rest.RedisEX, 2*ttl, rest.RedisNX))
+		conn.Close()
+		if err == redis.ErrNil {
+			reject(c, http.StatusUnauthorized, fmt.Errorf("nonce replayed"))
+			return
+		}
+
+		if err != nil {
+			e := fmt.Errorf("save call back nonce error, %s", err.Error())
+			logger.Error(e)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, rest.ErrorResponse(rest.InternalServerFailure, e.Error()))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func reject(c *gin.Context, status int, err error) {
+	e := fmt.Errorf("block chain call back rejected, %s", err.Error())
+	logger.Error(e)
+	c.AbortWithStatusJSON(status, rest.ErrorResponse(rest.BlockChainCallBackDenied, e.Error()))
+}
+
+func parseAllowIPs(ips []string) ([]*net.IPNet, error) {
+	allowed := make([]*net.IPNet, 0)
+	for _, v := range ips {
+		if v == "" {
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %s", v)
+			}
+
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(8*len(ip), 8*len(ip))}
+		}
+
+		allowed = append(allowed, ipNet)
+	}
+
+	return allowed, nil
+}
+
+func ipAllowed(allowed []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, v := range allowed {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
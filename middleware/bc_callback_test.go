@@ -0,0 +1,148 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/bcadapter"
+	"github.com/csiabb/donation-service/config"
+	"github.com/csiabb/donation-service/context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+)
+
+const (
+	urlBCCallBack = "/api/v1/bc/cb"
+	testSecret    = "test-secret"
+	bccbBodyJSON  = `{"blockchain":"cornerstone-chain","id":"block_id_1","block_num":3322,"tx_id":"tx_id_test","time":1584932344}`
+)
+
+func Init(t *testing.T, allowIPs []string) (*gin.Engine, *redigomock.Conn) {
+	return initWithSecret(t, testSecret, allowIPs)
+}
+
+func initWithSecret(t *testing.T, secret string, allowIPs []string) (*gin.Engine, *redigomock.Conn) {
+	gin.SetMode(gin.TestMode)
+
+	redisCli := redigomock.NewConn()
+	srvcContext := &context.Context{}
+	srvcContext.Config = &config.SrvcCfg{}
+	srvcContext.Config.BCAdapterCfg.CallBackSecret = secret
+	srvcContext.Config.BCAdapterCfg.CallBackAllowIPs = allowIPs
+	srvcContext.RedisPool = &redis.Pool{Dial: func() (redis.Conn, error) { return redisCli, nil }}
+
+	router := gin.New()
+	router.POST(urlBCCallBack, BCCallBackAuth(srvcContext), func(c *gin.Context) {
+		c.JSON(http.StatusOK, rest.SuccessResponse(nil))
+	})
+
+	return router, redisCli
+}
+
+func signedRequest(secret string, ts int64) *http.Request {
+	timestamp := strconv.FormatInt(ts, 10)
+	req, _ := http.NewRequest(http.MethodPost, urlBCCallBack, bytes.NewBufferString(bccbBodyJSON))
+	req.Header.Add(rest.HeaderContentType, rest.HeaderApplicationJSON)
+	req.Header.Add(bcadapter.HeaderTimestamp, timestamp)
+	req.Header.Add(bcadapter.HeaderNonce, "nonce_test")
+	req.Header.Add(bcadapter.HeaderSignature, bcadapter.Sign(secret, timestamp, "nonce_test", []byte(bccbBodyJSON)))
+	req.RemoteAddr = "10.0.0.8:3456"
+	return req
+}
+
+func TestBCCallBackAuthSucceed(t *testing.T) {
+	router, redisCli := Init(t, []string{"10.0.0.0/24"})
+	redisCli.GenericCommand(rest.RedisSet).Expect("OK")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(testSecret, time.Now().Unix()))
+
+	if w.Code != http.StatusOK {
+		t.Error(w.Code, w.Body.String())
+	}
+}
+
+func TestBCCallBackAuthSignature(t *testing.T) {
+	router, _ := Init(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest("forged-secret", time.Now().Unix()))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("signature check failed")
+	}
+}
+
+func TestBCCallBackAuthExpired(t *testing.T) {
+	router, _ := Init(t, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(testSecret, time.Now().Add(-time.Hour).Unix()))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("timestamp check failed")
+	}
+}
+
+func TestBCCallBackAuthReplayed(t *testing.T) {
+	router, redisCli := Init(t, nil)
+	redisCli.GenericCommand(rest.RedisSet).Expect(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(testSecret, time.Now().Unix()))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("nonce check failed")
+	}
+}
+
+func TestBCCallBackAuthSource(t *testing.T) {
+	router, _ := Init(t, []string{"192.168.20.90"})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest(testSecret, time.Now().Unix()))
+
+	if w.Code != http.StatusForbidden {
+		t.Error("source check failed")
+	}
+}
+
+func TestBCCallBackAuthForwardedSource(t *testing.T) {
+	router, _ := Init(t, []string{"192.168.20.90"})
+
+	// the forwarded headers set by client do not change the source
+	w := httptest.NewRecorder()
+	req := signedRequest(testSecret, time.Now().Unix())
+	req.Header.Add("X-Forwarded-For", "192.168.20.90")
+	req.Header.Add("X-Real-IP", "192.168.20.90")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Error("forwarded source check failed")
+	}
+}
+
+func TestBCCallBackAuthNoSecret(t *testing.T) {
+	router, _ := initWithSecret(t, "", nil)
+
+	// every call back is rejected without secret configured
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, signedRequest("", time.Now().Unix()))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Error("call back without secret check failed")
+	}
+}
@@ -7,11 +7,17 @@
 package models
 
 import (
+	"errors"
+	"time"
+
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/jinzhu/gorm"
 )
 
+// ErrDuplicated is returned when the record created violates a unique index
+var ErrDuplicated = errors.New("record duplicated")
+
 //go:generate mockgen -destination=mock_backend/mock_backend.go -package=mock_backend github.com/csiabb/donation-service/models IDBBackend
 
 // IDBBackend database operate interface
@@ -22,26 +28,80 @@ type IDBBackend interface {
 	DBTransactionRollback(*gorm.DB)
 
 	// account
-	QueryAccount(openID, uid string) (*Account, error)
+	QueryAccount(appID, openID, uid string) (*Account, error)
 	CreateAccount(*Account) error
+	UpdateAccount(tx *gorm.DB, uid string, fields map[string]interface{}) error
+	QueryAccountByLogin(access, phone, email string) (*Account, error)
+	QueryWXBinding(uid, appID, openID, unionID string) (*WXBinding, error)
+	CreateWXBinding(*WXBinding) error
 
 	// publicity
 	CreateFunds(*gorm.DB, *PubFunds) error
 	UpdateFunds(tx *gorm.DB, fundsID, blockID string) error
-	UpdateFundsBC(tx *gorm.DB, blockID string, funds *PubFunds) error
+	UpdateFundsStatus(tx *gorm.DB, fundsID, status, errMsg string) error
+	UpdateFundsBC(tx *gorm.DB, fundsID string, funds *PubFunds) error
 	QueryFunds(uid, targetUID, userType, pubType string, params *structs.QueryParams) ([]*PubFunds, error)
 	QueryFundsDetail(id string) (*FundsDetail, error)
 	CreateSupplies(*gorm.DB, []*PubSupplies) error
-	UpdateSuppliesList(*gorm.DB, []*PubSupplies, []*structs.PubResp) error
-	UpdateSuppliesBC(tx *gorm.DB, blockID string, supplies *PubSupplies) error
+	UpdateSupplies(tx *gorm.DB, suppliesID, blockID string) error
+	UpdateSuppliesStatus(tx *gorm.DB, suppliesID, status, errMsg string) error
+	UpdateSuppliesBC(tx *gorm.DB, suppliesID string, supplies *PubSupplies) error
 	QuerySupplies(uid, targetUID, userType, pubType string, params *structs.QueryParams) ([]*PubSupplies, error)
 	QuerySuppliesDetail(id string) (*SuppliesDetail, error)
 	QueryPubByUserType(userType, targetUID, pubType string, params *structs.QueryParams) ([]*structs.PubUserItem, error)
+	QueryPubByUID(uid, pubType string, params *structs.QueryParams) ([]*structs.PubUserItem, error)
 	CreateImages(tx *gorm.DB, data []*Image) error
+	DeleteImages(tx *gorm.DB, relatedID, imageType string) error
+	QueryImages(relatedID, imageType string) ([]*Image, error)
 	CreateAddresses(tx *gorm.DB, data []*Address) error
+	DeleteAddresses(tx *gorm.DB, uid, addrType string) error
+	QueryAddresses(relatedID string) ([]*Address, error)
+
+	// publicity outbox
+	CreateOutbox(tx *gorm.DB, data []*PubOutbox) error
+	UpdateOutbox(tx *gorm.DB, id string, data *PubOutbox) error
+	UpdateOutboxBC(tx *gorm.DB, relatedID string, data *PubOutbox) error
+	QueryOutbox(relatedID string) (*PubOutbox, error)
+	QueryOutboxPending(limit int) ([]*PubOutbox, error)
+	ClaimOutbox(tx *gorm.DB, box *PubOutbox) error
+	QueryOutboxStuck(before time.Time, limit int) ([]*PubOutbox, error)
+	CreateAttempt(tx *gorm.DB, data *PubAttempt) error
+	QueryFundsWithoutOutbox(before time.Time, limit int) ([]*PubFunds, error)
+	QuerySuppliesWithoutOutbox(before time.Time, limit int) ([]*PubSupplies, error)
+
+	// block chain call back
+	CreateCallBack(tx *gorm.DB, data *BCCallBack) error
+	QueryCallBack(blockID string) (*BCCallBack, error)
+	FlagCallBackConflict(blockID, txID string) error
+	QueryFundsByBlockID(blockID string) (*PubFunds, error)
+	QuerySuppliesByBlockID(blockID string) (*PubSupplies, error)
+
+	// leaderboard
+	SumRankStats(board string, since time.Time) ([]*structs.RankStat, error)
+	ReplaceRankSnapshots(tx *gorm.DB, board, period string, data []*RankSnapshot) error
+	QueryRankSnapshots(board, period, metric string, params *structs.QueryParams) ([]*RankSnapshot, error)
+
+	// kyc
+	CreatePersonKyc(tx *gorm.DB, data *PersonKyc) error
+	CreateOrgKyc(tx *gorm.DB, data *OrgKyc) error
+	QueryPersonKyc(id, uid string) (*PersonKyc, error)
+	QueryOrgKyc(id, uid string) (*OrgKyc, error)
+	QueryPersonKycList(status string, params *structs.QueryParams) ([]*PersonKyc, error)
+	QueryOrgKycList(status string, params *structs.QueryParams) ([]*OrgKyc, error)
+	UpdateKycStatus(tx *gorm.DB, kycType, id, status, remark string) error
 
 	// org
-	CreateOrganization(*DonationStat) error
+	CreateOrganization(tx *gorm.DB, data *DonationStat) error
+	AddDonationStat(tx *gorm.DB, delta *DonationStat) error
+	SaveDonationStat(tx *gorm.DB, data *DonationStat) error
+	QueryDonationStats() ([]*DonationStat, error)
+	SumDonationStats() ([]*DonationStat, error)
+	CreateCharityApply(tx *gorm.DB, data *CharityApply) error
+	QueryCharityApply(id, uid string) (*CharityApply, error)
+	QueryCharityApplyList(status string, params *structs.QueryParams) ([]*CharityApply, error)
+	UpdateCharityApplyStatus(tx *gorm.DB, id, status, remark string) error
 	QueryOrgCharities(params *structs.QueryParams) ([]*structs.OrgCharitiesItems, error)
 	QueryOrgCharitiesDetail(uid string) (*structs.OrgCharitiesDetailItem, error)
+	CreateProfileChanges(tx *gorm.DB, data []*ProfileChange) error
+	QueryProfileChanges(uid string, params *structs.QueryParams) ([]*ProfileChange, error)
 }
@@ -0,0 +1,32 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package models
+
+import (
+	"github.com/csiabb/donation-service/common/rest"
+)
+
+// chainTransitions defines the allowed previous status of each lifecycle status on block chain,
+// created -> submitted -> confirmed / failed -> retried -> submitted
+var chainTransitions = map[string][]string{
+	rest.ChainStatusSubmitted: {rest.ChainStatusCreated, rest.ChainStatusRetried},
+	// the call back is the proof on block chain, whatever the adapter answered
+	rest.ChainStatusConfirmed: {rest.ChainStatusCreated, rest.ChainStatusSubmitted, rest.ChainStatusFailed, rest.ChainStatusRetried},
+	rest.ChainStatusFailed:    {rest.ChainStatusCreated, rest.ChainStatusSubmitted, rest.ChainStatusRetried},
+	rest.ChainStatusRetried:   {rest.ChainStatusFailed},
+}
+
+// ChainStatusFrom returns the status which is allowed to transit to status
+func ChainStatusFrom(status string) []string {
+	return chainTransitions[status]
+}
+
+// IsChainStatus checks whether status is a lifecycle status on block chain
+func IsChainStatus(status string) bool {
+	_, ok := chainTransitions[status]
+	return ok || status == rest.ChainStatusCreated
+}
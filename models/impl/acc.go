@@ -8,25 +8,35 @@ package impl
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/csiabb/donation-service/models"
+
+	"github.com/jinzhu/gorm"
 )
 
+// loginFields are the unique columns of account to login with
+var loginFields = []string{"access", "phone", "email"}
+
 // CreateAccount implement create user account
 func (b *DbBackendImpl) CreateAccount(data *models.Account) error {
 	if nil == data {
 		return fmt.Errorf("param is nil")
 	}
 
-	return b.GetConn().Create(data).Error
+	err := b.GetConn().Create(data).Error
+	if err != nil && isDuplicated(err) {
+		return models.ErrDuplicated
+	}
+	return err
 }
 
-// QueryAccount implement check user account exist or not
-func (b *DbBackendImpl) QueryAccount(openID, uid string) (*models.Account, error) {
+// QueryAccount implement check user account exist or not, the open id is scoped by app id
+func (b *DbBackendImpl) QueryAccount(appID, openID, uid string) (*models.Account, error) {
 	where := b.GetConn().Model(&models.Account{})
 
 	if openID != "" {
-		where = where.Where("open_id = ?", openID)
+		where = where.Where("app_id = ? and open_id = ?", appID, openID)
 	}
 
 	if uid != "" {
@@ -37,3 +47,95 @@ func (b *DbBackendImpl) QueryAccount(openID, uid string) (*models.Account, error
 	err := where.First(acc).Error
 	return acc, err
 }
+
+// QueryAccountByLogin implement query the account whose user name, phone or email matches any of the not empty ones,
+// models.ErrDuplicated is returned if they match more than one account
+func (b *DbBackendImpl) QueryAccountByLogin(access, phone, email string) (*models.Account, error) {
+	var conds []string
+	var args []interface{}
+
+	if access != "" {
+		conds, args = append(conds, "access = ?"), append(args, access)
+	}
+
+	if phone != "" {
+		conds, args = append(conds, "phone = ?"), append(args, phone)
+	}
+
+	if email != "" {
+		conds, args = append(conds, "email = ?"), append(args, email)
+	}
+
+	if len(conds) == 0 {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	var accs []*models.Account
+	err := b.GetConn().Model(&models.Account{}).Where(strings.Join(conds, " or "), args...).Limit(2).Find(&accs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(accs) {
+	case 0:
+		return nil, gorm.ErrRecordNotFound
+	case 1:
+		return accs[0], nil
+	default:
+		return nil, models.ErrDuplicated
+	}
+}
+
+// UpdateAccount implement update fields of user account
+func (b *DbBackendImpl) UpdateAccount(tx *gorm.DB, uid string, fields map[string]interface{}) error {
+	if uid == "" || len(fields) == 0 {
+		return fmt.Errorf("param is nil")
+	}
+
+	// the login fields are null instead of empty, the unique index ignores the nulls only
+	for _, k := range loginFields {
+		if v, ok := fields[k]; ok && v == "" {
+			fields[k] = nil
+		}
+	}
+
+	err := tx.Model(&models.Account{}).Where("id = ?", uid).Updates(fields).Error
+	if err != nil && isDuplicated(err) {
+		return models.ErrDuplicated
+	}
+	return err
+}
+
+// CreateWXBinding implement bind user of wechat app to account
+func (b *DbBackendImpl) CreateWXBinding(data *models.WXBinding) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	return b.GetConn().Create(data).Error
+}
+
+// QueryWXBinding implement query the binding of wechat app user, the open id is scoped by app id
+func (b *DbBackendImpl) QueryWXBinding(uid, appID, openID, unionID string) (*models.WXBinding, error) {
+	where := b.GetConn().Model(&models.WXBinding{})
+
+	if uid != "" {
+		where = where.Where("uid = ?", uid)
+	}
+
+	if appID != "" {
+		where = where.Where("app_id = ?", appID)
+	}
+
+	if openID != "" {
+		where = where.Where("open_id = ?", openID)
+	}
+
+	if unionID != "" {
+		where = where.Where("union_id = ?", unionID)
+	}
+
+	binding := &models.WXBinding{}
+	err := where.First(binding).Error
+	return binding, err
+}
@@ -0,0 +1,82 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package impl
+
+import (
+	"fmt"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	sqlFundsByBlockID    = "block_id = ? or id in (select related_id from pub_attempt where type = 'funds' and block_id = ? and deleted_at is null)"
+	sqlSuppliesByBlockID = "block_id = ? or id in (select related_id from pub_attempt where type = 'supplies' and block_id = ? and deleted_at is null)"
+)
+
+// CreateCallBack implement record block chain call back
+func (b *DbBackendImpl) CreateCallBack(tx *gorm.DB, data *models.BCCallBack) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	return tx.Model(&models.BCCallBack{}).Create(data).Error
+}
+
+// QueryCallBack implement query block chain call back by block chain id
+func (b *DbBackendImpl) QueryCallBack(blockID string) (*models.BCCallBack, error) {
+	if blockID == "" {
+		return nil, fmt.Errorf("block id can not be \\'\\'")
+	}
+
+	out := &models.BCCallBack{}
+	err := b.GetConn().Where(&models.BCCallBack{ID: blockID}).First(out).Error
+	return out, err
+}
+
+// FlagCallBackConflict implement flag the conflicting call back for review
+func (b *DbBackendImpl) FlagCallBackConflict(blockID, txID string) error {
+	if blockID == "" {
+		return fmt.Errorf("block id can not be \\'\\'")
+	}
+
+	err := b.GetConn().Model(&models.BCCallBack{}).Where("id = ?", blockID).Updates(map[string]interface{}{
+		"status":         rest.CallBackConflict,
+		"conflict_tx_id": txID,
+		"conflicts":      gorm.Expr("conflicts + 1"),
+	}).Error
+	if err != nil {
+		logger.Errorf("flag call back conflict error, %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// QueryFundsByBlockID implement query funds by the block chain id of its latest or previous attempts
+func (b *DbBackendImpl) QueryFundsByBlockID(blockID string) (*models.PubFunds, error) {
+	if blockID == "" {
+		return nil, fmt.Errorf("block id can not be \\'\\'")
+	}
+
+	out := &models.PubFunds{}
+	err := b.GetConn().Where(sqlFundsByBlockID, blockID, blockID).First(out).Error
+	return out, err
+}
+
+// QuerySuppliesByBlockID implement query supplies by the block chain id of its latest or previous attempts
+func (b *DbBackendImpl) QuerySuppliesByBlockID(blockID string) (*models.PubSupplies, error) {
+	if blockID == "" {
+		return nil, fmt.Errorf("block id can not be \\'\\'")
+	}
+
+	out := &models.PubSupplies{}
+	err := b.GetConn().Where(sqlSuppliesByBlockID, blockID, blockID).First(out).Error
+	return out, err
+}
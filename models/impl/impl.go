@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package impl
 
 import (
+	"strings"
+
 	"github.com/csiabb/donation-service/common/log"
+	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/components/database"
 	"github.com/csiabb/donation-service/models"
 
@@ -40,15 +43,72 @@ func NewDBBackend(cfg *database.DBConnectCfg) (*DbBackendImpl, error) {
 // migrateDb
 func migrateDb(d *DbBackendImpl) {
 	// Migrate the schema
+	migrateLoginFields(d)
 	d.Db.AutoMigrate(models.Account{})
 	d.Db.AutoMigrate(models.Address{})
 	d.Db.AutoMigrate(models.DonationStat{})
+	d.Db.AutoMigrate(models.CharityApply{})
+	d.Db.AutoMigrate(models.ProfileChange{})
+	d.Db.AutoMigrate(models.RankSnapshot{})
 	d.Db.AutoMigrate(models.PersonKyc{})
 	d.Db.AutoMigrate(models.OrgKyc{})
 	d.Db.AutoMigrate(models.Image{})
 	d.Db.AutoMigrate(models.PubFunds{})
 	d.Db.AutoMigrate(models.PubSupplies{})
 	d.Db.AutoMigrate(models.Cover{})
+	d.Db.AutoMigrate(models.PubOutbox{})
+	d.Db.AutoMigrate(models.PubAttempt{})
+	d.Db.AutoMigrate(models.BCCallBack{})
+	d.Db.AutoMigrate(models.WXBinding{})
+
+	migrateAccountType(d)
+	migrateChainStatus(d, &models.PubFunds{})
+	migrateChainStatus(d, &models.PubSupplies{})
+	migratePayloadSchema(d, &models.PubFunds{}, rest.DonatedTypeFunds)
+	migratePayloadSchema(d, &models.PubSupplies{}, rest.DonatedTypeSupplies)
+}
+
+// migrateLoginFields clears the empty login fields of accounts as null before the unique indexes are created,
+// the indexes are not created if the accounts share any of them, which has to be solved by hand
+func migrateLoginFields(d *DbBackendImpl) {
+	if !d.Db.HasTable(&models.Account{}) {
+		return
+	}
+
+	for _, v := range loginFields {
+		d.Db.Model(&models.Account{}).Where(v+" = ''").Update(v, gorm.Expr("NULL"))
+	}
+}
+
+// migrateAccountType fills the type of accounts signed up from wechat without type as normal user
+func migrateAccountType(d *DbBackendImpl) {
+	d.Db.Model(&models.Account{}).Where("type = '' or type is null").Update("type", rest.UserTypeNormal)
+}
+
+// migrateChainStatus fills the lifecycle status of records created before it exists
+func migrateChainStatus(d *DbBackendImpl, model interface{}) {
+	d.Db.Model(model).Where("chain_status = '' or chain_status is null").Where("tx_id <> ''").Update("chain_status", rest.ChainStatusConfirmed)
+	d.Db.Model(model).Where("chain_status = '' or chain_status is null").Where("block_id <> ''").Update("chain_status", rest.ChainStatusSubmitted)
+	d.Db.Model(model).Where("chain_status = '' or chain_status is null").Update("chain_status", rest.ChainStatusCreated)
+}
+
+// migratePayloadSchema marks the records published before schema version exists as legacy, they are
+// verified by recomputing the legacy payload, the hash is filled from outbox if the payload is kept there
+func migratePayloadSchema(d *DbBackendImpl, model interface{}, typ string) {
+	d.Db.Model(model).Where("schema_ver = 0 or schema_ver is null").Update("schema_ver", rest.PayloadSchemaLegacy)
+
+	unhashed := d.Db.Model(model).Select("id").Where("payload_hash = '' or payload_hash is null").QueryExpr()
+	boxes := make([]*models.PubOutbox, 0)
+	d.Db.Where("type = ?", typ).Where("related_id in (?)", unhashed).Find(&boxes)
+	for _, v := range boxes {
+		d.Db.Model(model).Where("id = ?", v.RelatedID).Update("payload_hash", models.PublicityHash(v.Publicity))
+	}
+}
+
+// isDuplicated returns whether the error violates a unique index, by the messages of postgres and mysql
+func isDuplicated(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "duplicate key") || strings.Contains(msg, "duplicate entry")
 }
 
 // GetDBTransaction ...
@@ -0,0 +1,174 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package impl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CreatePersonKyc implement create the kyc of single person
+func (b *DbBackendImpl) CreatePersonKyc(tx *gorm.DB, data *models.PersonKyc) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	if data.ID == "" {
+		data.ID = utils.GenerateUUID()
+	}
+
+	return tx.Create(data).Error
+}
+
+// CreateOrgKyc implement create the kyc of organization
+func (b *DbBackendImpl) CreateOrgKyc(tx *gorm.DB, data *models.OrgKyc) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	if data.ID == "" {
+		data.ID = utils.GenerateUUID()
+	}
+
+	return tx.Create(data).Error
+}
+
+// QueryPersonKyc implement query the kyc of single person by id, or the latest one of user
+func (b *DbBackendImpl) QueryPersonKyc(id, uid string) (*models.PersonKyc, error) {
+	where, err := kycWhere(b.GetConn().Model(&models.PersonKyc{}), id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &models.PersonKyc{}
+	err = where.Order("created_at desc").First(out).Error
+	return out, err
+}
+
+// QueryOrgKyc implement query the kyc of organization by id, or the latest one of user
+func (b *DbBackendImpl) QueryOrgKyc(id, uid string) (*models.OrgKyc, error) {
+	where, err := kycWhere(b.GetConn().Model(&models.OrgKyc{}), id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &models.OrgKyc{}
+	err = where.Order("created_at desc").First(out).Error
+	return out, err
+}
+
+// QueryPersonKycList implement query the kyc of single person in status, the earliest first
+func (b *DbBackendImpl) QueryPersonKycList(status string, params *structs.QueryParams) ([]*models.PersonKyc, error) {
+	var out []*models.PersonKyc
+	where := listWhere(b.GetConn().Model(&models.PersonKyc{}), status, params)
+	if err := where.Count(&params.Total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (params.PageNum - 1) * params.PageLimit
+	if err := where.Order("created_at").Offset(offset).Limit(params.PageLimit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// QueryOrgKycList implement query the kyc of organization in status, the earliest first
+func (b *DbBackendImpl) QueryOrgKycList(status string, params *structs.QueryParams) ([]*models.OrgKyc, error) {
+	var out []*models.OrgKyc
+	where := listWhere(b.GetConn().Model(&models.OrgKyc{}), status, params)
+	if err := where.Count(&params.Total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (params.PageNum - 1) * params.PageLimit
+	if err := where.Order("created_at").Offset(offset).Limit(params.PageLimit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// UpdateKycStatus implement review the pending kyc, it fails if the kyc is not pending any more
+func (b *DbBackendImpl) UpdateKycStatus(tx *gorm.DB, kycType, id, status, remark string) error {
+	if id == "" || status == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	var where *gorm.DB
+	switch kycType {
+	case rest.KycTypePerson:
+		where = tx.Model(&models.PersonKyc{})
+	case rest.KycTypeOrg:
+		where = tx.Model(&models.OrgKyc{})
+	default:
+		return fmt.Errorf("kyc type %s invalid", kycType)
+	}
+
+	where = where.Where("id = ? and status = ?", id, rest.KycStatusPending).Updates(map[string]interface{}{
+		"status": status,
+		"remark": remark,
+	})
+	if where.Error != nil {
+		return where.Error
+	}
+
+	if where.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// kycWhere scopes the kyc query by id or user id
+func kycWhere(where *gorm.DB, id, uid string) (*gorm.DB, error) {
+	if id == "" && uid == "" {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	if id != "" {
+		where = where.Where("id = ?", id)
+	}
+
+	if uid != "" {
+		where = where.Where("uid = ?", uid)
+	}
+
+	return where, nil
+}
+
+// listWhere scopes the list by status and created time, the page params are defaulted
+func listWhere(where *gorm.DB, status string, params *structs.QueryParams) *gorm.DB {
+	if params.PageNum < 1 {
+		params.PageNum = rest.PageNum
+	}
+
+	if params.PageLimit < 1 {
+		params.PageLimit = rest.PageLimit
+	}
+
+	if status != "" {
+		where = where.Where("status = ?", status)
+	}
+
+	if params.StartTime > 0 {
+		where = where.Where("created_at >= ?", time.Unix(params.StartTime, 0))
+	}
+
+	if params.EndTime > 0 {
+		where = where.Where("created_at <= ?", time.Unix(params.EndTime, 0))
+	}
+
+	return where
+}
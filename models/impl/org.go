@@ -16,24 +16,232 @@ import (
 	"github.com/csiabb/donation-service/structs"
 
 	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
 )
 
 const (
-	sqlQueryDonationStatAndAccountInfo       = "select temp.id, temp.uid, temp.received_funds, temp.received_supplies, temp.distributed_funds, temp.distributed_supplies, temp.time, temp.nick_name, image.url from (select donation_stat.id, donation_stat.uid, donation_stat.received_funds, donation_stat.received_supplies, donation_stat.distributed_funds, donation_stat.distributed_supplies, donation_stat.created_at as time, account.nick_name from donation_stat full join account on donation_stat.uid = account.id and account.type = ? where donation_stat.created_at >= ? and donation_stat.created_at <= ? order by distributed_funds desc, distributed_supplies desc ) as temp left join image on image.related_id = temp.uid and image.type = ? order by temp.time limit ? offset ? "
-	sqlQueryDetailDonationStatAndAccountInfo = "select temp.nick_name, temp.remark, temp.uid, temp.phone, temp.bank_card_num, temp.country, temp.district, temp.province, temp.city, temp.address,image.url from (select account.nick_name, account.remark, account.id as uid, account.phone, account.bank_card_num, address.country, address.district, address.province, address.city, address.address from account left join address on address.uid = account.id  and address.type = ? where account.type = ?) as temp left join image on image.related_id = temp.uid and image.type = ? where temp.uid = ?"
+	sqlQueryDonationStatAndAccountInfo       = "select temp.id, temp.uid, temp.received_funds, temp.received_supplies, temp.distributed_funds, temp.distributed_supplies, temp.time, temp.nick_name, image.url from (select donation_stat.id, donation_stat.uid, donation_stat.received_funds, donation_stat.received_supplies, donation_stat.distributed_funds, donation_stat.distributed_supplies, donation_stat.created_at as time, account.nick_name from donation_stat join account on donation_stat.uid = account.id and account.type = ? and account.kyc_status = ? where donation_stat.created_at >= ? and donation_stat.created_at <= ? and donation_stat.deleted_at is null) as temp left join image on image.related_id = temp.uid and image.type = ? and image.deleted_at is null where (? = '' or temp.time < ? or (temp.time = ? and temp.id < ?)) order by temp.time desc, temp.id desc limit ? offset ?"
+	sqlCountDonationStatAndAccountInfo       = "select count(*) from donation_stat join account on donation_stat.uid = account.id and account.type = ? and account.kyc_status = ? where donation_stat.created_at >= ? and donation_stat.created_at <= ? and donation_stat.deleted_at is null"
+	sqlSumFundsStat                          = "select target_uid as uid, coalesce(sum(case when pub_type = ? then amount end), 0) as received_funds, coalesce(sum(case when pub_type = ? then amount end), 0) as distributed_funds from pub_funds where target_uid in (select id from account where type = ? and deleted_at is null) and chain_status = ? and deleted_at is null group by target_uid"
+	sqlSumSuppliesStat                       = "select target_uid as uid, coalesce(sum(case when pub_type = ? then number end), 0) as received_supplies, coalesce(sum(case when pub_type = ? then number end), 0) as distributed_supplies from pub_supplies where target_uid in (select id from account where type = ? and deleted_at is null) and chain_status = ? and deleted_at is null group by target_uid"
+	sqlQueryDetailDonationStatAndAccountInfo = "select temp.nick_name, temp.remark, temp.uid, temp.phone, temp.bank, temp.bank_card_num, temp.country, temp.district, temp.province, temp.city, temp.address, temp.zip_code, temp.received_funds, temp.received_supplies, temp.distributed_funds, temp.distributed_supplies, image.url from (select account.nick_name, account.remark, account.id as uid, coalesce(nullif(account.contact_phone, ''), account.phone) as phone, account.bank, account.bank_card_num, address.country, address.district, address.province, address.city, address.address, address.zip_code, coalesce(donation_stat.received_funds, 0) as received_funds, coalesce(donation_stat.received_supplies, 0) as received_supplies, coalesce(donation_stat.distributed_funds, 0) as distributed_funds, coalesce(donation_stat.distributed_supplies, 0) as distributed_supplies from account left join address on address.uid = account.id and address.type = ? and address.deleted_at is null left join donation_stat on donation_stat.uid = account.id and donation_stat.deleted_at is null where account.type = ?) as temp left join image on image.related_id = temp.uid and image.type = ? and image.deleted_at is null where temp.uid = ?"
 )
 
 // CreateOrganization implement create the donation statistics of organization interface
-func (b *DbBackendImpl) CreateOrganization(data *models.DonationStat) error {
+func (b *DbBackendImpl) CreateOrganization(tx *gorm.DB, data *models.DonationStat) error {
 	if nil == data {
 		return fmt.Errorf("param is nil")
 	}
 
 	data.ID = utils.GenerateUUID()
-	return b.GetConn().Create(data).Error
+	return tx.Create(data).Error
 }
 
-// QueryOrgCharities implement query the donation statistics of organization charity interface
+// AddDonationStat implement add the change to the donation statistics of user, the statistics is created if missing
+func (b *DbBackendImpl) AddDonationStat(tx *gorm.DB, delta *models.DonationStat) error {
+	if nil == delta || delta.UID == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	exists, err := donationStatExists(tx, delta.UID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return b.CreateOrganization(tx, delta)
+	}
+
+	return tx.Model(&models.DonationStat{}).Where("uid = ?", delta.UID).Updates(map[string]interface{}{
+		"received_funds":       gorm.Expr("received_funds + ?", delta.ReceivedFunds),
+		"distributed_funds":    gorm.Expr("distributed_funds + ?", delta.DistributedFunds),
+		"received_supplies":    gorm.Expr("received_supplies + ?", delta.ReceivedSupplies),
+		"distributed_supplies": gorm.Expr("distributed_supplies + ?", delta.DistributedSupplies),
+	}).Error
+}
+
+// SaveDonationStat implement overwrite the donation statistics of user, the statistics is created if missing
+func (b *DbBackendImpl) SaveDonationStat(tx *gorm.DB, data *models.DonationStat) error {
+	if nil == data || data.UID == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	exists, err := donationStatExists(tx, data.UID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return b.CreateOrganization(tx, data)
+	}
+
+	return tx.Model(&models.DonationStat{}).Where("uid = ?", data.UID).Updates(map[string]interface{}{
+		"received_funds":       data.ReceivedFunds,
+		"distributed_funds":    data.DistributedFunds,
+		"received_supplies":    data.ReceivedSupplies,
+		"distributed_supplies": data.DistributedSupplies,
+	}).Error
+}
+
+// donationStatExists checks the donation statistics of user by select, the rows affected by update are the changed
+// ones on mysql, which are none if nothing changes
+func donationStatExists(tx *gorm.DB, uid string) (bool, error) {
+	var count int
+	err := tx.Model(&models.DonationStat{}).Where("uid = ?", uid).Count(&count).Error
+	return count > 0, err
+}
+
+// QueryDonationStats implement query the donation statistics of all users
+func (b *DbBackendImpl) QueryDonationStats() ([]*models.DonationStat, error) {
+	var out []*models.DonationStat
+	err := b.GetConn().Model(&models.DonationStat{}).Order("uid").Find(&out).Error
+	return out, err
+}
+
+// SumDonationStats implement rebuild the donation statistics of the charities from the confirmed funds and supplies
+func (b *DbBackendImpl) SumDonationStats() ([]*models.DonationStat, error) {
+	var funds, supplies []*models.DonationStat
+	err := b.GetConn().Raw(sqlSumFundsStat, rest.PubTypeDonate, rest.PubTypeDistribute, rest.UserTypeOrgCharity, rest.ChainStatusConfirmed).Scan(&funds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.GetConn().Raw(sqlSumSuppliesStat, rest.PubTypeDonate, rest.PubTypeDistribute, rest.UserTypeOrgCharity, rest.ChainStatusConfirmed).Scan(&supplies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*models.DonationStat)
+	var out []*models.DonationStat
+	for _, v := range funds {
+		stats[v.UID] = v
+		out = append(out, v)
+	}
+
+	for _, v := range supplies {
+		s, ok := stats[v.UID]
+		if !ok {
+			v.ReceivedFunds, v.DistributedFunds = decimal.Zero, decimal.Zero
+			out = append(out, v)
+			continue
+		}
+
+		s.ReceivedSupplies, s.DistributedSupplies = v.ReceivedSupplies, v.DistributedSupplies
+	}
+
+	return out, nil
+}
+
+// CreateProfileChanges implement create the change history of charity profile
+func (b *DbBackendImpl) CreateProfileChanges(tx *gorm.DB, data []*models.ProfileChange) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	for _, v := range data {
+		if v.ID == "" {
+			v.ID = utils.GenerateUUID()
+		}
+
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryProfileChanges implement query the change history of charity profile, the latest first
+func (b *DbBackendImpl) QueryProfileChanges(uid string, params *structs.QueryParams) ([]*models.ProfileChange, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	var out []*models.ProfileChange
+	where := listWhere(b.GetConn().Model(&models.ProfileChange{}).Where("uid = ?", uid), "", params)
+	if err := where.Count(&params.Total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (params.PageNum - 1) * params.PageLimit
+	if err := where.Order("created_at desc").Offset(offset).Limit(params.PageLimit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// CreateCharityApply implement create the application to become charity
+func (b *DbBackendImpl) CreateCharityApply(tx *gorm.DB, data *models.CharityApply) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	if data.ID == "" {
+		data.ID = utils.GenerateUUID()
+	}
+
+	return tx.Create(data).Error
+}
+
+// QueryCharityApply implement query the application to become charity by id, or the latest one of user
+func (b *DbBackendImpl) QueryCharityApply(id, uid string) (*models.CharityApply, error) {
+	if id == "" && uid == "" {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	where := b.GetConn().Model(&models.CharityApply{})
+	if id != "" {
+		where = where.Where("id = ?", id)
+	}
+
+	if uid != "" {
+		where = where.Where("uid = ?", uid)
+	}
+
+	out := &models.CharityApply{}
+	err := where.Order("created_at desc").First(out).Error
+	return out, err
+}
+
+// QueryCharityApplyList implement query the applications to become charity in status, the earliest first
+func (b *DbBackendImpl) QueryCharityApplyList(status string, params *structs.QueryParams) ([]*models.CharityApply, error) {
+	var out []*models.CharityApply
+	where := listWhere(b.GetConn().Model(&models.CharityApply{}), status, params)
+	if err := where.Count(&params.Total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (params.PageNum - 1) * params.PageLimit
+	if err := where.Order("created_at").Offset(offset).Limit(params.PageLimit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// UpdateCharityApplyStatus implement review the pending application, it fails if the application is not pending any more
+func (b *DbBackendImpl) UpdateCharityApplyStatus(tx *gorm.DB, id, status, remark string) error {
+	if id == "" || status == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	where := tx.Model(&models.CharityApply{}).Where("id = ? and status = ?", id, rest.ApplyStatusPending).Updates(map[string]interface{}{
+		"status": status,
+		"remark": remark,
+	})
+	if where.Error != nil {
+		return where.Error
+	}
+
+	if where.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// QueryOrgCharities implement query the donation statistics of organization charity interface, the latest registered first
 func (b *DbBackendImpl) QueryOrgCharities(params *structs.QueryParams) ([]*structs.OrgCharitiesItems, error) {
 	if params.PageNum < 1 {
 		params.PageNum = rest.PageNum
@@ -53,22 +261,24 @@ func (b *DbBackendImpl) QueryOrgCharities(params *structs.QueryParams) ([]*struc
 		params.StartTime = params.EndTime - rest.TenDayBySecond
 	}
 
-	var out []*structs.OrgCharitiesItems
-	offset := (params.PageNum - 1) * params.PageLimit
-	where := b.GetConn().Model(&structs.OrgCharitiesItems{})
-	if err := where.Raw(sqlQueryDonationStatAndAccountInfo, rest.UserTypeOrgCharity, time.Unix(params.StartTime, 0),
-		time.Unix(params.EndTime, 0), rest.ImageAvatar, params.PageLimit, offset).Scan(&out).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			e := fmt.Errorf("records not found")
-			logger.Error(e)
-			return nil, e
-		}
+	start, end := time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0)
+	err := b.GetConn().Raw(sqlCountDonationStatAndAccountInfo, rest.UserTypeOrgCharity, rest.KycStatusApproved, start, end).Row().Scan(&params.Total)
+	if err != nil {
+		logger.Errorf("count organizations records error , %v", err)
+		return nil, err
+	}
 
+	var out []*structs.OrgCharitiesItems
+	args := append([]interface{}{rest.UserTypeOrgCharity, rest.KycStatusApproved, start, end, rest.ImageAvatar}, pageArgs(params)...)
+	if err := b.GetConn().Raw(sqlQueryDonationStatAndAccountInfo, args...).Scan(&out).Error; err != nil {
 		logger.Errorf("query organizations records error , %v", err)
 		return nil, err
 	}
 
-	params.Total = int64(len(out))
+	if len(out) == params.PageLimit {
+		params.NextCursor = structs.NewCursor(out[len(out)-1].Time, out[len(out)-1].ID)
+	}
+
 	return out, nil
 }
 
@@ -0,0 +1,191 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package impl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	sqlFundsWithoutOutbox    = "pub_funds.tx_id = '' and (pub_funds.block_id = '' or pub_funds.created_at < ?) and not exists (select 1 from pub_outbox where pub_outbox.related_id = pub_funds.id and pub_outbox.deleted_at is null)"
+	sqlSuppliesWithoutOutbox = "pub_supplies.tx_id = '' and (pub_supplies.block_id = '' or pub_supplies.created_at < ?) and not exists (select 1 from pub_outbox where pub_outbox.related_id = pub_supplies.id and pub_outbox.deleted_at is null)"
+)
+
+// CreateOutbox implement create publicity outbox interface
+func (b *DbBackendImpl) CreateOutbox(tx *gorm.DB, data []*models.PubOutbox) error {
+	if nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	for _, v := range data {
+		err := tx.Model(&models.PubOutbox{}).Create(v).Error
+		if err != nil && isDuplicated(err) {
+			return models.ErrDuplicated
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateOutbox implement update publicity outbox by id
+func (b *DbBackendImpl) UpdateOutbox(tx *gorm.DB, id string, data *models.PubOutbox) error {
+	if id == "" || nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	err := tx.Model(&models.PubOutbox{}).Where("id = ?", id).Updates(data).Error
+	if err != nil {
+		logger.Errorf("update outbox error, %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateOutboxBC implement update publicity outbox of funds or supplies by block chain call back
+func (b *DbBackendImpl) UpdateOutboxBC(tx *gorm.DB, relatedID string, data *models.PubOutbox) error {
+	if relatedID == "" || nil == data {
+		return fmt.Errorf("param is nil")
+	}
+
+	err := tx.Model(&models.PubOutbox{}).Where("related_id = ?", relatedID).Updates(data).Error
+	if err != nil {
+		logger.Errorf("update bc cb info to outbox error, %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// QueryOutbox implement query publicity outbox by funds or supplies id
+func (b *DbBackendImpl) QueryOutbox(relatedID string) (*models.PubOutbox, error) {
+	if relatedID == "" {
+		return nil, fmt.Errorf("related id can not be \\'\\'")
+	}
+
+	out := &models.PubOutbox{}
+	if err := b.GetConn().Where(&models.PubOutbox{RelatedID: relatedID}).First(out).Error; err != nil {
+		logger.Errorf("query outbox error, %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// QueryOutboxPending implement query the publicity waiting to be published
+func (b *DbBackendImpl) QueryOutboxPending(limit int) ([]*models.PubOutbox, error) {
+	if limit < 1 {
+		limit = rest.PageLimit
+	}
+
+	var out []*models.PubOutbox
+	err := b.GetConn().Where("status = ?", rest.ChainStatusPending).Order("created_at").Limit(limit).Find(&out).Error
+	if err != nil {
+		logger.Errorf("query pending outbox error, %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ClaimOutbox implement claim the publicity to be published by moving it to publishing, the claim fails with
+// record not found if the box is changed since read, e.g. claimed by another publisher
+func (b *DbBackendImpl) ClaimOutbox(tx *gorm.DB, box *models.PubOutbox) error {
+	if nil == box || box.ID == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	where := tx.Model(&models.PubOutbox{}).Where("id = ? and status = ? and attempts = ?", box.ID, box.Status, box.Attempts)
+	where = where.Update("status", rest.ChainStatusPublishing)
+	if where.Error != nil {
+		logger.Errorf("claim outbox error, %v", where.Error)
+		return where.Error
+	}
+
+	if where.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// QueryOutboxStuck implement query the publicity without block chain call back before the time, the ones
+// claimed but left publishing, e.g. by a crashed publisher, are stuck as well
+func (b *DbBackendImpl) QueryOutboxStuck(before time.Time, limit int) ([]*models.PubOutbox, error) {
+	if limit < 1 {
+		limit = rest.PageLimit
+	}
+
+	var out []*models.PubOutbox
+	where := b.GetConn().Where("(status = ? and tx_id = '') or status in (?)", rest.ChainStatusPublished,
+		[]string{rest.ChainStatusFailed, rest.ChainStatusPublishing})
+	err := where.Where("updated_at < ?", before).Order("updated_at").Limit(limit).Find(&out).Error
+	if err != nil {
+		logger.Errorf("query stuck outbox error, %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// CreateAttempt implement record the block chain id given by one publish attempt
+func (b *DbBackendImpl) CreateAttempt(tx *gorm.DB, data *models.PubAttempt) error {
+	if nil == data || data.BlockID == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	if data.ID == "" {
+		data.ID = utils.GenerateUUID()
+	}
+
+	return tx.Create(data).Error
+}
+
+// QueryFundsWithoutOutbox implement query the funds without block chain call back and without outbox, published
+// before the outbox existed, whose block chain id is set before the time or never set
+func (b *DbBackendImpl) QueryFundsWithoutOutbox(before time.Time, limit int) ([]*models.PubFunds, error) {
+	if limit < 1 {
+		limit = rest.PageLimit
+	}
+
+	var out []*models.PubFunds
+	err := b.GetConn().Where(sqlFundsWithoutOutbox, before).Order("created_at").Limit(limit).Find(&out).Error
+	if err != nil {
+		logger.Errorf("query funds without outbox error, %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// QuerySuppliesWithoutOutbox implement query the supplies without block chain call back and without outbox,
+// published before the outbox existed, whose block chain id is set before the time or never set
+func (b *DbBackendImpl) QuerySuppliesWithoutOutbox(before time.Time, limit int) ([]*models.PubSupplies, error) {
+	if limit < 1 {
+		limit = rest.PageLimit
+	}
+
+	var out []*models.PubSupplies
+	err := b.GetConn().Where(sqlSuppliesWithoutOutbox, before).Order("created_at").Limit(limit).Find(&out).Error
+	if err != nil {
+		logger.Errorf("query supplies without outbox error, %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -9,6 +9,9 @@ package impl
 import (
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/csiabb/donation-service/common/rest"
@@ -19,8 +22,18 @@ import (
 )
 
 const (
-	sqlQueryPublicityByUserType = "select * from (select id, 'funds' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, pay_type, amount, null as name, null as number, null as unit, tx_id, remark, block_type, block_height, block_time, created_at as time from pub_funds where user_type = ? and pub_type = ? and created_at >= ? and created_at <= ? union all select id, 'supplies' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, null as pay_type, null as amount, name, number, unit, tx_id, remark, block_type, block_height, block_time, created_at as time from pub_supplies where user_type = ? and pub_type = ? and created_at >= ? and created_at <= ?) as temp order by temp.time limit ? offset ?"
-	sqlQueryPublicityByCharity  = "select * from (select id, 'funds' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, pay_type, amount, null as name, null as number, null as unit, tx_id, remark, block_type, block_height, block_time, created_at as time from pub_funds where target_uid = ? and pub_type = ? and created_at >= ? and created_at <= ? union all select id, 'supplies' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, null as pay_type, null as amount, name, number, unit, tx_id, remark, block_type, block_height, block_time, created_at as time from pub_supplies where target_uid = ? and pub_type = ? and created_at >= ? and created_at <= ?) as temp order by temp.time limit ? offset ?"
+	sqlPublicityByUserType = "select id, 'funds' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, pay_type, amount, null as name, null as number, null as unit, tx_id, remark, block_type, block_height, block_time, chain_status, created_at as time from pub_funds where user_type = ? and pub_type = ? and created_at >= ? and created_at <= ? and (? = '' or chain_status = ?) union all select id, 'supplies' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, null as pay_type, null as amount, name, number, unit, tx_id, remark, block_type, block_height, block_time, chain_status, created_at as time from pub_supplies where user_type = ? and pub_type = ? and created_at >= ? and created_at <= ? and (? = '' or chain_status = ?)"
+	sqlPublicityByCharity  = "select id, 'funds' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, pay_type, amount, null as name, null as number, null as unit, tx_id, remark, block_type, block_height, block_time, chain_status, created_at as time from pub_funds where target_uid = ? and pub_type = ? and created_at >= ? and created_at <= ? and (? = '' or chain_status = ?) union all select id, 'supplies' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, null as pay_type, null as amount, name, number, unit, tx_id, remark, block_type, block_height, block_time, chain_status, created_at as time from pub_supplies where target_uid = ? and pub_type = ? and created_at >= ? and created_at <= ? and (? = '' or chain_status = ?)"
+	sqlPublicityByUID      = "select id, 'funds' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, pay_type, amount, null as name, null as number, null as unit, tx_id, remark, block_type, block_height, block_time, chain_status, created_at as time from pub_funds where uid = ? and (? = '' or pub_type = ?) and created_at >= ? and created_at <= ? and (? = '' or chain_status = ?) union all select id, 'supplies' as type, uid, donor_name, user_type, aid_uid, aid_name, target_uid, target_name, pub_type, null as pay_type, null as amount, name, number, unit, tx_id, remark, block_type, block_height, block_time, chain_status, created_at as time from pub_supplies where uid = ? and (? = '' or pub_type = ?) and created_at >= ? and created_at <= ? and (? = '' or chain_status = ?)"
+
+	sqlPageOfPublicity = ") as temp where (? = '' or temp.time < ? or (temp.time = ? and temp.id < ?)) order by temp.time desc, temp.id desc limit ? offset ?"
+
+	sqlQueryPublicityByUserType = "select * from (" + sqlPublicityByUserType + sqlPageOfPublicity
+	sqlCountPublicityByUserType = "select count(*) from (" + sqlPublicityByUserType + ") as temp"
+	sqlQueryPublicityByCharity  = "select * from (" + sqlPublicityByCharity + sqlPageOfPublicity
+	sqlCountPublicityByCharity  = "select count(*) from (" + sqlPublicityByCharity + ") as temp"
+	sqlQueryPublicityByUID      = "select * from (" + sqlPublicityByUID + sqlPageOfPublicity
+	sqlCountPublicityByUID      = "select count(*) from (" + sqlPublicityByUID + ") as temp"
 )
 
 // CreateFunds implement receive funds interface
@@ -47,6 +60,15 @@ func (b *DbBackendImpl) UpdateFunds(tx *gorm.DB, fundsID, blockID string) error
 	return nil
 }
 
+// UpdateFundsStatus implement transit the lifecycle status of funds on block chain
+func (b *DbBackendImpl) UpdateFundsStatus(tx *gorm.DB, fundsID, status, errMsg string) error {
+	if fundsID == "" {
+		return errors.New("funds id is \\'\\'")
+	}
+
+	return transitChainStatus(tx.Model(&models.PubFunds{}).Where("id = ?", fundsID), status, errMsg)
+}
+
 // CreateImages implement create images interface
 func (b *DbBackendImpl) CreateImages(tx *gorm.DB, data []*models.Image) error {
 	if nil == data {
@@ -63,6 +85,48 @@ func (b *DbBackendImpl) CreateImages(tx *gorm.DB, data []*models.Image) error {
 	return nil
 }
 
+// DeleteImages implement delete images of the related id by image type
+func (b *DbBackendImpl) DeleteImages(tx *gorm.DB, relatedID, imageType string) error {
+	if relatedID == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	return tx.Where("related_id = ? and type = ?", relatedID, imageType).Delete(&models.Image{}).Error
+}
+
+// QueryImages implement query images of the related id by type, all types if image type is empty, ordered by the
+// numeric index as the images created in one transaction share the created time
+func (b *DbBackendImpl) QueryImages(relatedID, imageType string) ([]*models.Image, error) {
+	if relatedID == "" {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	where := b.GetConn().Where("related_id = ?", relatedID)
+	if imageType != "" {
+		where = where.Where("type = ?", imageType)
+	}
+
+	var out []*models.Image
+	if err := where.Order("created_at").Order("id").Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	// the index is a string column, sorted here instead of casting it in the dialect of database
+	sort.SliceStable(out, func(i, j int) bool {
+		return imageIndex(out[i]) < imageIndex(out[j])
+	})
+	return out, nil
+}
+
+// imageIndex returns the numeric index of image, the ones without index are put last
+func imageIndex(image *models.Image) int {
+	index, err := strconv.Atoi(image.Index)
+	if err != nil {
+		return math.MaxInt32
+	}
+	return index
+}
+
 // QueryFunds implement query funds interface
 func (b *DbBackendImpl) QueryFunds(uid, targetUID, userType, pubType string, params *structs.QueryParams) ([]*models.PubFunds, error) {
 	if params.PageNum < 1 {
@@ -103,10 +167,17 @@ func (b *DbBackendImpl) QueryFunds(uid, targetUID, userType, pubType string, par
 		where = where.Where("target_uid = ?", targetUID)
 	}
 
-	var out []*models.PubFunds
-	offset := (params.PageNum - 1) * params.PageLimit
+	if params.ChainStatus != "" {
+		where = where.Where("chain_status = ?", params.ChainStatus)
+	}
+
+	if err := where.Count(&params.Total).Error; err != nil {
+		logger.Errorf("count funds error: %v", err)
+		return nil, err
+	}
 
-	if err := where.Count(&params.Total).Offset(offset).Limit(params.PageLimit).Find(&out).Order("created_at desc").Error; err != nil {
+	var out []*models.PubFunds
+	if err := pageWhere(where, params).Find(&out).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			e := fmt.Errorf("records not found")
 			logger.Error(e)
@@ -117,6 +188,10 @@ func (b *DbBackendImpl) QueryFunds(uid, targetUID, userType, pubType string, par
 		return nil, err
 	}
 
+	if len(out) == params.PageLimit {
+		params.NextCursor = structs.NewCursor(out[len(out)-1].CreatedAt, out[len(out)-1].ID)
+	}
+
 	return out, nil
 }
 
@@ -136,17 +211,61 @@ func (b *DbBackendImpl) CreateSupplies(tx *gorm.DB, data []*models.PubSupplies)
 	return nil
 }
 
-// UpdateSuppliesList update supplies information
-func (b *DbBackendImpl) UpdateSuppliesList(tx *gorm.DB, ps []*models.PubSupplies, data []*structs.PubResp) error {
-	if data == nil {
-		return errors.New("param is nil")
+// UpdateSupplies update supplies information
+func (b *DbBackendImpl) UpdateSupplies(tx *gorm.DB, suppliesID, blockID string) error {
+	if suppliesID == "" || blockID == "" {
+		return errors.New("supplies or block id is \\'\\'")
 	}
 
-	for i := 0; i < len(data); i++ {
-		err := tx.Model(&models.PubSupplies{}).Where("id = ?", ps[i].ID).Updates(models.PubSupplies{BlockID: data[i].Data.ID}).Error
-		if err != nil {
-			return err
-		}
+	err := tx.Model(&models.PubSupplies{}).Where("id = ?", suppliesID).Update("block_id", blockID).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSuppliesStatus implement transit the lifecycle status of supplies on block chain
+func (b *DbBackendImpl) UpdateSuppliesStatus(tx *gorm.DB, suppliesID, status, errMsg string) error {
+	if suppliesID == "" {
+		return errors.New("supplies id is \\'\\'")
+	}
+
+	return transitChainStatus(tx.Model(&models.PubSupplies{}).Where("id = ?", suppliesID), status, errMsg)
+}
+
+// transitChainStatus updates the lifecycle status only if transition is allowed
+func transitChainStatus(where *gorm.DB, status, errMsg string) error {
+	from := models.ChainStatusFrom(status)
+	if len(from) == 0 {
+		return fmt.Errorf("invalid chain status %s", status)
+	}
+
+	updates := map[string]interface{}{"chain_status": status}
+	now := time.Now().Unix()
+	switch status {
+	case rest.ChainStatusSubmitted:
+		updates["submitted_at"] = now
+	case rest.ChainStatusConfirmed:
+		updates["confirmed_at"] = now
+	case rest.ChainStatusFailed:
+		updates["failed_at"] = now
+	case rest.ChainStatusRetried:
+		updates["retried_at"] = now
+	}
+
+	if errMsg != "" {
+		updates["chain_error"] = errMsg
+	}
+
+	result := where.Where("chain_status in (?)", from).Updates(updates)
+	if result.Error != nil {
+		logger.Errorf("update chain status error, %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("can not transit chain status to %s", status)
 	}
 
 	return nil
@@ -168,6 +287,30 @@ func (b *DbBackendImpl) CreateAddresses(tx *gorm.DB, data []*models.Address) err
 	return nil
 }
 
+// DeleteAddresses implement delete addresses of user by address type
+func (b *DbBackendImpl) DeleteAddresses(tx *gorm.DB, uid, addrType string) error {
+	if uid == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	return tx.Where("uid = ? and type = ?", uid, addrType).Delete(&models.Address{}).Error
+}
+
+// QueryAddresses implement query addresses by funds or supplies id
+func (b *DbBackendImpl) QueryAddresses(relatedID string) ([]*models.Address, error) {
+	if relatedID == "" {
+		return nil, fmt.Errorf("related id can not be \\'\\'")
+	}
+
+	out := make([]*models.Address, 0)
+	if err := b.GetConn().Where(&models.Address{RelatedID: relatedID}).Find(&out).Error; err != nil {
+		logger.Errorf("query addresses error, %v", err)
+		return nil, err
+	}
+
+	return out, nil
+}
+
 // QuerySupplies defines the query supplies
 func (b *DbBackendImpl) QuerySupplies(uid, targetUID, userType, pubType string, params *structs.QueryParams) ([]*models.PubSupplies, error) {
 	if params.PageNum < 1 {
@@ -188,7 +331,7 @@ func (b *DbBackendImpl) QuerySupplies(uid, targetUID, userType, pubType string,
 		params.StartTime = params.EndTime - rest.TenDayBySecond
 	}
 
-	where := b.GetConn().Model(&models.PubFunds{})
+	where := b.GetConn().Model(&models.PubSupplies{})
 	where = where.Where("created_at >= ?", time.Unix(params.StartTime, 0))
 	where = where.Where("created_at <= ?", time.Unix(params.EndTime, 0))
 
@@ -208,25 +351,44 @@ func (b *DbBackendImpl) QuerySupplies(uid, targetUID, userType, pubType string,
 		where = where.Where("target_uid = ?", targetUID)
 	}
 
-	var out []*models.PubSupplies
-	offset := (params.PageNum - 1) * params.PageLimit
+	if params.ChainStatus != "" {
+		where = where.Where("chain_status = ?", params.ChainStatus)
+	}
+
+	if err := where.Count(&params.Total).Error; err != nil {
+		logger.Errorf("count supplies error: %v", err)
+		return nil, err
+	}
 
-	if err := where.Count(&params.Total).Offset(offset).Limit(params.PageLimit).Find(&out).Order("created_at desc").Error; err != nil {
+	var out []*models.PubSupplies
+	if err := pageWhere(where, params).Find(&out).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			e := fmt.Errorf("records not found")
 			logger.Error(e)
 			return nil, e
 		}
 
-		logger.Errorf("query funds record error: %v", err)
+		logger.Errorf("query supplies records error: %v", err)
 		return nil, err
 	}
 
+	if len(out) == params.PageLimit {
+		params.NextCursor = structs.NewCursor(out[len(out)-1].CreatedAt, out[len(out)-1].ID)
+	}
+
 	return out, nil
 }
 
-// QueryPubByUserType defines the query of publicity by user type
+// QueryPubByUserType defines the query of publicity by user type, the latest first
 func (b *DbBackendImpl) QueryPubByUserType(userType, targetUID, pubType string, params *structs.QueryParams) ([]*structs.PubUserItem, error) {
+	if params.PageNum < 1 {
+		params.PageNum = rest.PageNum
+	}
+
+	if params.PageLimit < 1 {
+		params.PageLimit = rest.PageLimit
+	}
+
 	if params.StartTime > 0 && params.EndTime > 0 {
 		if params.EndTime < params.StartTime {
 			return nil, fmt.Errorf("end time can not less than start time")
@@ -237,10 +399,6 @@ func (b *DbBackendImpl) QueryPubByUserType(userType, targetUID, pubType string,
 		params.StartTime = params.EndTime - rest.TenDayBySecond
 	}
 
-	offset := (params.PageNum - 1) * params.PageLimit
-	var out []*structs.PubUserItem
-	var err error
-
 	if pubType == "" {
 		return nil, fmt.Errorf("pub type can not be \\'\\'")
 	}
@@ -249,28 +407,92 @@ func (b *DbBackendImpl) QueryPubByUserType(userType, targetUID, pubType string,
 		return nil, fmt.Errorf("user type and target id can not be \\'\\' the same time")
 	}
 
+	start, end := time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0)
 	if userType != "" {
-		err = b.GetConn().Raw(sqlQueryPublicityByUserType, userType, pubType, time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0), userType, pubType, time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0), params.PageLimit, offset).Scan(&out).Error
-	} else if targetUID != "" {
-		err = b.GetConn().Raw(sqlQueryPublicityByCharity, targetUID, pubType, time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0), targetUID, pubType, time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0), params.PageLimit, offset).Scan(&out).Error
+		return b.queryPublicity(sqlQueryPublicityByUserType, sqlCountPublicityByUserType, params, userType, pubType, start, end,
+			params.ChainStatus, params.ChainStatus, userType, pubType, start, end, params.ChainStatus, params.ChainStatus)
 	}
 
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			e := fmt.Errorf("records not found")
-			logger.Error(e)
-			return nil, e
+	return b.queryPublicity(sqlQueryPublicityByCharity, sqlCountPublicityByCharity, params, targetUID, pubType, start, end,
+		params.ChainStatus, params.ChainStatus, targetUID, pubType, start, end, params.ChainStatus, params.ChainStatus)
+}
+
+// QueryPubByUID implement query the funds and supplies published by the user, the latest first
+func (b *DbBackendImpl) QueryPubByUID(uid, pubType string, params *structs.QueryParams) ([]*structs.PubUserItem, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("uid can not be \\'\\'")
+	}
+
+	if params.PageNum < 1 {
+		params.PageNum = rest.PageNum
+	}
+
+	if params.PageLimit < 1 {
+		params.PageLimit = rest.PageLimit
+	}
+
+	if params.StartTime > 0 && params.EndTime > 0 {
+		if params.EndTime < params.StartTime {
+			return nil, fmt.Errorf("end time can not less than start time")
 		}
+	} else {
+		now := time.Now()
+		params.EndTime = now.Unix()
+		params.StartTime = params.EndTime - rest.TenDayBySecond
+	}
 
+	start, end := time.Unix(params.StartTime, 0), time.Unix(params.EndTime, 0)
+	return b.queryPublicity(sqlQueryPublicityByUID, sqlCountPublicityByUID, params, uid, pubType, pubType, start, end,
+		params.ChainStatus, params.ChainStatus, uid, pubType, pubType, start, end, params.ChainStatus, params.ChainStatus)
+}
+
+// queryPublicity counts the funds and supplies matched and queries the page of them, the args are of the
+// union before the page
+func (b *DbBackendImpl) queryPublicity(sqlQuery, sqlCount string, params *structs.QueryParams, args ...interface{}) ([]*structs.PubUserItem, error) {
+	if err := b.GetConn().Raw(sqlCount, args...).Row().Scan(&params.Total); err != nil {
+		logger.Errorf("count records error: %v", err)
+		return nil, err
+	}
+
+	var out []*structs.PubUserItem
+	err := b.GetConn().Raw(sqlQuery, append(args, pageArgs(params)...)...).Scan(&out).Error
+	if err != nil {
 		logger.Errorf("query records error: %v", err)
 		return nil, err
 	}
 
-	params.Total = int64(len(out))
+	if len(out) == params.PageLimit {
+		params.NextCursor = structs.NewCursor(out[len(out)-1].Time, out[len(out)-1].ID)
+	}
+
 	return out, nil
 }
 
-// QueryFundsDetail defines query publicity funds detail
+// pageWhere scopes the page after the cursor or by page num, the latest first and the greater id first
+// at the same time
+func pageWhere(where *gorm.DB, params *structs.QueryParams) *gorm.DB {
+	if params.Cursor != nil {
+		at := params.Cursor.At()
+		where = where.Where("created_at < ? or (created_at = ? and id < ?)", at, at, params.Cursor.ID)
+	} else {
+		where = where.Offset((params.PageNum - 1) * params.PageLimit)
+	}
+
+	return where.Order("created_at desc, id desc").Limit(params.PageLimit)
+}
+
+// pageArgs returns the args of the cursor condition, limit and offset of the page in raw sql, the cursor
+// condition is skipped without cursor and the offset is zero with cursor
+func pageArgs(params *structs.QueryParams) []interface{} {
+	id, at, offset := "", time.Unix(0, 0), (params.PageNum-1)*params.PageLimit
+	if params.Cursor != nil {
+		id, at, offset = params.Cursor.ID, params.Cursor.At(), 0
+	}
+
+	return []interface{}{id, at, at, id, params.PageLimit, offset}
+}
+
+// QueryFundsDetail defines query publicity funds detail, gorm.ErrRecordNotFound is returned if the funds not exists
 func (b *DbBackendImpl) QueryFundsDetail(id string) (*models.FundsDetail, error) {
 	if id == "" {
 		e := fmt.Errorf("id can not be \\'\\'")
@@ -280,6 +502,10 @@ func (b *DbBackendImpl) QueryFundsDetail(id string) (*models.FundsDetail, error)
 
 	detail := models.FundsDetail{}
 	if err := b.GetConn().Where(&models.PubFunds{ID: id}).First(&detail.Funds).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Debugf("funds %s not found", id)
+			return nil, err
+		}
 		e := fmt.Errorf("query funds error, %v", err)
 		logger.Error(e)
 		return nil, e
@@ -316,7 +542,7 @@ func (b *DbBackendImpl) QueryFundsDetail(id string) (*models.FundsDetail, error)
 	return &detail, nil
 }
 
-// QuerySuppliesDetail defines query publicity funds detail
+// QuerySuppliesDetail defines query publicity supplies detail, gorm.ErrRecordNotFound is returned if the supplies not exists
 func (b *DbBackendImpl) QuerySuppliesDetail(id string) (*models.SuppliesDetail, error) {
 	if id == "" {
 		e := fmt.Errorf("id can not be \\'\\'")
@@ -326,6 +552,10 @@ func (b *DbBackendImpl) QuerySuppliesDetail(id string) (*models.SuppliesDetail,
 
 	detail := models.SuppliesDetail{}
 	if err := b.GetConn().Where(&models.PubSupplies{ID: id}).First(&detail.Supplies).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Debugf("supplies %s not found", id)
+			return nil, err
+		}
 		e := fmt.Errorf("query supplies error, %v", err)
 		logger.Error(e)
 		return nil, e
@@ -362,31 +592,51 @@ func (b *DbBackendImpl) QuerySuppliesDetail(id string) (*models.SuppliesDetail,
 	return &detail, nil
 }
 
-// UpdateFundsBC implement update funds block chain call back
-func (b *DbBackendImpl) UpdateFundsBC(tx *gorm.DB, blockID string, funds *models.PubFunds) error {
-	if nil == funds {
+// UpdateFundsBC implement update funds block chain call back, the block chain id confirmed replaces the one
+// of the latest attempt, gorm.ErrRecordNotFound is returned if the funds is not transited, e.g. confirmed already
+func (b *DbBackendImpl) UpdateFundsBC(tx *gorm.DB, fundsID string, funds *models.PubFunds) error {
+	if fundsID == "" || nil == funds {
 		return fmt.Errorf("param is nil")
 	}
 
-	err := tx.Model(&models.PubFunds{}).Where("block_id = ?", blockID).Updates(funds).Error
-	if err != nil {
-		logger.Errorf("update bc cb info to funds error, %v", err)
-		return err
+	where := tx.Model(&models.PubFunds{}).Where("id = ?", fundsID)
+	if funds.ChainStatus != "" {
+		where = where.Where("chain_status in (?)", models.ChainStatusFrom(funds.ChainStatus))
+	}
+
+	result := where.Updates(funds)
+	if result.Error != nil {
+		logger.Errorf("update bc cb info to funds error, %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
 
 	return nil
 }
 
-// UpdateSuppliesBC implement update supplies block chain call back
-func (b *DbBackendImpl) UpdateSuppliesBC(tx *gorm.DB, blockID string, supplies *models.PubSupplies) error {
-	if nil == supplies {
+// UpdateSuppliesBC implement update supplies block chain call back, the block chain id confirmed replaces the one
+// of the latest attempt, gorm.ErrRecordNotFound is returned if the supplies is not transited, e.g. confirmed already
+func (b *DbBackendImpl) UpdateSuppliesBC(tx *gorm.DB, suppliesID string, supplies *models.PubSupplies) error {
+	if suppliesID == "" || nil == supplies {
 		return fmt.Errorf("param is nil")
 	}
 
-	err := tx.Model(&models.PubSupplies{}).Where("block_id = ?", blockID).Updates(supplies).Error
-	if err != nil {
-		logger.Errorf("update bc cb info to supplies error, %v", err)
-		return err
+	where := tx.Model(&models.PubSupplies{}).Where("id = ?", suppliesID)
+	if supplies.ChainStatus != "" {
+		where = where.Where("chain_status in (?)", models.ChainStatusFrom(supplies.ChainStatus))
+	}
+
+	result := where.Updates(supplies)
+	if result.Error != nil {
+		logger.Errorf("update bc cb info to supplies error, %v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
 	}
 
 	return nil
@@ -0,0 +1,171 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package impl
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	sqlSumCharityFundsRank    = "select account.id as uid, account.nick_name as name, coalesce(sum(case when pub_funds.pub_type = ? then pub_funds.amount end), 0) as received_funds, coalesce(sum(case when pub_funds.pub_type = ? then pub_funds.amount end), 0) as distributed_funds from pub_funds join account on account.id = pub_funds.target_uid and account.type = ? and account.kyc_status = ? where pub_funds.chain_status = ? and pub_funds.created_at >= ? and pub_funds.deleted_at is null group by account.id, account.nick_name"
+	sqlSumCharitySuppliesRank = "select account.id as uid, account.nick_name as name, coalesce(sum(case when pub_supplies.pub_type = ? then pub_supplies.number end), 0) as received_supplies, coalesce(sum(case when pub_supplies.pub_type = ? then pub_supplies.number end), 0) as distributed_supplies from pub_supplies join account on account.id = pub_supplies.target_uid and account.type = ? and account.kyc_status = ? where pub_supplies.chain_status = ? and pub_supplies.created_at >= ? and pub_supplies.deleted_at is null group by account.id, account.nick_name"
+	sqlCountCharityDonorsRank = "select temp.target_uid as uid, count(distinct temp.uid) as donors from (select target_uid, uid from pub_funds where pub_type = ? and uid <> '' and chain_status = ? and created_at >= ? and deleted_at is null union select target_uid, uid from pub_supplies where pub_type = ? and uid <> '' and chain_status = ? and created_at >= ? and deleted_at is null) as temp group by temp.target_uid"
+	sqlSumDonorFundsRank      = "select account.id as uid, account.nick_name as name, coalesce(sum(pub_funds.amount), 0) as donated_funds from pub_funds join account on account.id = pub_funds.uid where pub_funds.pub_type = ? and pub_funds.chain_status = ? and pub_funds.created_at >= ? and pub_funds.deleted_at is null group by account.id, account.nick_name"
+	sqlSumDonorSuppliesRank   = "select account.id as uid, account.nick_name as name, coalesce(sum(pub_supplies.number), 0) as donated_supplies from pub_supplies join account on account.id = pub_supplies.uid where pub_supplies.pub_type = ? and pub_supplies.chain_status = ? and pub_supplies.created_at >= ? and pub_supplies.deleted_at is null group by account.id, account.nick_name"
+)
+
+// SumRankStats implement sum the confirmed publicities since the time by charity or by donor, only the charities
+// approved by kyc are ranked
+func (b *DbBackendImpl) SumRankStats(board string, since time.Time) ([]*structs.RankStat, error) {
+	switch board {
+	case rest.BoardCharity:
+		return b.sumCharityRankStats(since)
+	case rest.BoardDonor:
+		return b.sumDonorRankStats(since)
+	}
+
+	return nil, fmt.Errorf("board %s invalid", board)
+}
+
+// sumCharityRankStats sums the funds, supplies and donors received by charities
+func (b *DbBackendImpl) sumCharityRankStats(since time.Time) ([]*structs.RankStat, error) {
+	var funds, supplies, donors []*structs.RankStat
+	err := b.GetConn().Raw(sqlSumCharityFundsRank, rest.PubTypeDonate, rest.PubTypeDistribute, rest.UserTypeOrgCharity,
+		rest.KycStatusApproved, rest.ChainStatusConfirmed, since).Scan(&funds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.GetConn().Raw(sqlSumCharitySuppliesRank, rest.PubTypeDonate, rest.PubTypeDistribute, rest.UserTypeOrgCharity,
+		rest.KycStatusApproved, rest.ChainStatusConfirmed, since).Scan(&supplies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.GetConn().Raw(sqlCountCharityDonorsRank, rest.PubTypeDonate, rest.ChainStatusConfirmed, since,
+		rest.PubTypeDonate, rest.ChainStatusConfirmed, since).Scan(&donors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*structs.RankStat)
+	out := mergeRankStats(stats, nil, funds, func(s, v *structs.RankStat) {
+		s.ReceivedFunds, s.DistributedFunds = v.ReceivedFunds, v.DistributedFunds
+	})
+	out = mergeRankStats(stats, out, supplies, func(s, v *structs.RankStat) {
+		s.ReceivedSupplies, s.DistributedSupplies = v.ReceivedSupplies, v.DistributedSupplies
+	})
+
+	// the donors are counted for the charities summed only, the others are not approved
+	for _, v := range donors {
+		if s, ok := stats[v.UID]; ok {
+			s.Donors = v.Donors
+		}
+	}
+
+	return out, nil
+}
+
+// sumDonorRankStats sums the funds and supplies donated by donors
+func (b *DbBackendImpl) sumDonorRankStats(since time.Time) ([]*structs.RankStat, error) {
+	var funds, supplies []*structs.RankStat
+	err := b.GetConn().Raw(sqlSumDonorFundsRank, rest.PubTypeDonate, rest.ChainStatusConfirmed, since).Scan(&funds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.GetConn().Raw(sqlSumDonorSuppliesRank, rest.PubTypeDonate, rest.ChainStatusConfirmed, since).Scan(&supplies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*structs.RankStat)
+	out := mergeRankStats(stats, nil, funds, func(s, v *structs.RankStat) {
+		s.DonatedFunds = v.DonatedFunds
+	})
+	out = mergeRankStats(stats, out, supplies, func(s, v *structs.RankStat) {
+		s.DonatedSupplies = v.DonatedSupplies
+	})
+
+	return out, nil
+}
+
+// mergeRankStats merges the rows into the aggregates by user id, the missing ones are appended with zero funds
+func mergeRankStats(stats map[string]*structs.RankStat, out, rows []*structs.RankStat, merge func(s, v *structs.RankStat)) []*structs.RankStat {
+	for _, v := range rows {
+		s, ok := stats[v.UID]
+		if !ok {
+			s = &structs.RankStat{
+				UID:              v.UID,
+				Name:             v.Name,
+				ReceivedFunds:    decimal.Zero,
+				DistributedFunds: decimal.Zero,
+				DonatedFunds:     decimal.Zero,
+			}
+			stats[v.UID] = s
+			out = append(out, s)
+		}
+
+		merge(s, v)
+	}
+
+	return out
+}
+
+// ReplaceRankSnapshots implement replace the snapshots of leaderboard in the period as a whole
+func (b *DbBackendImpl) ReplaceRankSnapshots(tx *gorm.DB, board, period string, data []*models.RankSnapshot) error {
+	if board == "" || period == "" {
+		return fmt.Errorf("param is nil")
+	}
+
+	err := tx.Unscoped().Where("board = ? and period = ?", board, period).Delete(&models.RankSnapshot{}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, v := range data {
+		v.ID = utils.GenerateUUID()
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryRankSnapshots implement query the snapshots of leaderboard in rank order
+func (b *DbBackendImpl) QueryRankSnapshots(board, period, metric string, params *structs.QueryParams) ([]*models.RankSnapshot, error) {
+	if params.PageNum < 1 {
+		params.PageNum = rest.PageNum
+	}
+
+	if params.PageLimit < 1 {
+		params.PageLimit = rest.PageLimit
+	}
+
+	var out []*models.RankSnapshot
+	where := b.GetConn().Model(&models.RankSnapshot{}).Where("board = ? and period = ? and metric = ?", board, period, metric)
+	if err := where.Count(&params.Total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (params.PageNum - 1) * params.PageLimit
+	if err := where.Order("ranking, uid").Offset(offset).Limit(params.PageLimit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
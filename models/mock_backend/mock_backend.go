@@ -10,6 +10,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	gorm "github.com/jinzhu/gorm"
 	reflect "reflect"
+	time "time"
 )
 
 // MockIDBBackend is a mock of IDBBackend interface
@@ -35,6 +36,34 @@ func (m *MockIDBBackend) EXPECT() *MockIDBBackendMockRecorder {
 	return m.recorder
 }
 
+// AddDonationStat mocks base method
+func (m *MockIDBBackend) AddDonationStat(arg0 *gorm.DB, arg1 *models.DonationStat) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDonationStat", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDonationStat indicates an expected call of AddDonationStat
+func (mr *MockIDBBackendMockRecorder) AddDonationStat(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDonationStat", reflect.TypeOf((*MockIDBBackend)(nil).AddDonationStat), arg0, arg1)
+}
+
+// ClaimOutbox mocks base method
+func (m *MockIDBBackend) ClaimOutbox(arg0 *gorm.DB, arg1 *models.PubOutbox) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutbox", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClaimOutbox indicates an expected call of ClaimOutbox
+func (mr *MockIDBBackendMockRecorder) ClaimOutbox(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutbox", reflect.TypeOf((*MockIDBBackend)(nil).ClaimOutbox), arg0, arg1)
+}
+
 // CreateAccount mocks base method
 func (m *MockIDBBackend) CreateAccount(arg0 *models.Account) error {
 	m.ctrl.T.Helper()
@@ -63,6 +92,48 @@ func (mr *MockIDBBackendMockRecorder) CreateAddresses(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAddresses", reflect.TypeOf((*MockIDBBackend)(nil).CreateAddresses), arg0, arg1)
 }
 
+// CreateAttempt mocks base method
+func (m *MockIDBBackend) CreateAttempt(arg0 *gorm.DB, arg1 *models.PubAttempt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAttempt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAttempt indicates an expected call of CreateAttempt
+func (mr *MockIDBBackendMockRecorder) CreateAttempt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAttempt", reflect.TypeOf((*MockIDBBackend)(nil).CreateAttempt), arg0, arg1)
+}
+
+// CreateCallBack mocks base method
+func (m *MockIDBBackend) CreateCallBack(arg0 *gorm.DB, arg1 *models.BCCallBack) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCallBack", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCallBack indicates an expected call of CreateCallBack
+func (mr *MockIDBBackendMockRecorder) CreateCallBack(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCallBack", reflect.TypeOf((*MockIDBBackend)(nil).CreateCallBack), arg0, arg1)
+}
+
+// CreateCharityApply mocks base method
+func (m *MockIDBBackend) CreateCharityApply(arg0 *gorm.DB, arg1 *models.CharityApply) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCharityApply", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCharityApply indicates an expected call of CreateCharityApply
+func (mr *MockIDBBackendMockRecorder) CreateCharityApply(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCharityApply", reflect.TypeOf((*MockIDBBackend)(nil).CreateCharityApply), arg0, arg1)
+}
+
 // CreateFunds mocks base method
 func (m *MockIDBBackend) CreateFunds(arg0 *gorm.DB, arg1 *models.PubFunds) error {
 	m.ctrl.T.Helper()
@@ -91,18 +162,74 @@ func (mr *MockIDBBackendMockRecorder) CreateImages(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImages", reflect.TypeOf((*MockIDBBackend)(nil).CreateImages), arg0, arg1)
 }
 
+// CreateOrgKyc mocks base method
+func (m *MockIDBBackend) CreateOrgKyc(arg0 *gorm.DB, arg1 *models.OrgKyc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrgKyc", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrgKyc indicates an expected call of CreateOrgKyc
+func (mr *MockIDBBackendMockRecorder) CreateOrgKyc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrgKyc", reflect.TypeOf((*MockIDBBackend)(nil).CreateOrgKyc), arg0, arg1)
+}
+
 // CreateOrganization mocks base method
-func (m *MockIDBBackend) CreateOrganization(arg0 *models.DonationStat) error {
+func (m *MockIDBBackend) CreateOrganization(arg0 *gorm.DB, arg1 *models.DonationStat) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrganization", arg0)
+	ret := m.ctrl.Call(m, "CreateOrganization", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateOrganization indicates an expected call of CreateOrganization
-func (mr *MockIDBBackendMockRecorder) CreateOrganization(arg0 interface{}) *gomock.Call {
+func (mr *MockIDBBackendMockRecorder) CreateOrganization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganization", reflect.TypeOf((*MockIDBBackend)(nil).CreateOrganization), arg0, arg1)
+}
+
+// CreateOutbox mocks base method
+func (m *MockIDBBackend) CreateOutbox(arg0 *gorm.DB, arg1 []*models.PubOutbox) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOutbox", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOutbox indicates an expected call of CreateOutbox
+func (mr *MockIDBBackendMockRecorder) CreateOutbox(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOutbox", reflect.TypeOf((*MockIDBBackend)(nil).CreateOutbox), arg0, arg1)
+}
+
+// CreatePersonKyc mocks base method
+func (m *MockIDBBackend) CreatePersonKyc(arg0 *gorm.DB, arg1 *models.PersonKyc) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePersonKyc", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePersonKyc indicates an expected call of CreatePersonKyc
+func (mr *MockIDBBackendMockRecorder) CreatePersonKyc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePersonKyc", reflect.TypeOf((*MockIDBBackend)(nil).CreatePersonKyc), arg0, arg1)
+}
+
+// CreateProfileChanges mocks base method
+func (m *MockIDBBackend) CreateProfileChanges(arg0 *gorm.DB, arg1 []*models.ProfileChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateProfileChanges", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateProfileChanges indicates an expected call of CreateProfileChanges
+func (mr *MockIDBBackendMockRecorder) CreateProfileChanges(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrganization", reflect.TypeOf((*MockIDBBackend)(nil).CreateOrganization), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateProfileChanges", reflect.TypeOf((*MockIDBBackend)(nil).CreateProfileChanges), arg0, arg1)
 }
 
 // CreateSupplies mocks base method
@@ -119,6 +246,20 @@ func (mr *MockIDBBackendMockRecorder) CreateSupplies(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSupplies", reflect.TypeOf((*MockIDBBackend)(nil).CreateSupplies), arg0, arg1)
 }
 
+// CreateWXBinding mocks base method
+func (m *MockIDBBackend) CreateWXBinding(arg0 *models.WXBinding) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWXBinding", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWXBinding indicates an expected call of CreateWXBinding
+func (mr *MockIDBBackendMockRecorder) CreateWXBinding(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWXBinding", reflect.TypeOf((*MockIDBBackend)(nil).CreateWXBinding), arg0)
+}
+
 // DBTransactionCommit mocks base method
 func (m *MockIDBBackend) DBTransactionCommit(arg0 *gorm.DB) {
 	m.ctrl.T.Helper()
@@ -143,6 +284,48 @@ func (mr *MockIDBBackendMockRecorder) DBTransactionRollback(arg0 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DBTransactionRollback", reflect.TypeOf((*MockIDBBackend)(nil).DBTransactionRollback), arg0)
 }
 
+// DeleteAddresses mocks base method
+func (m *MockIDBBackend) DeleteAddresses(arg0 *gorm.DB, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAddresses", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAddresses indicates an expected call of DeleteAddresses
+func (mr *MockIDBBackendMockRecorder) DeleteAddresses(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAddresses", reflect.TypeOf((*MockIDBBackend)(nil).DeleteAddresses), arg0, arg1, arg2)
+}
+
+// DeleteImages mocks base method
+func (m *MockIDBBackend) DeleteImages(arg0 *gorm.DB, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImages", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImages indicates an expected call of DeleteImages
+func (mr *MockIDBBackendMockRecorder) DeleteImages(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImages", reflect.TypeOf((*MockIDBBackend)(nil).DeleteImages), arg0, arg1, arg2)
+}
+
+// FlagCallBackConflict mocks base method
+func (m *MockIDBBackend) FlagCallBackConflict(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlagCallBackConflict", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FlagCallBackConflict indicates an expected call of FlagCallBackConflict
+func (mr *MockIDBBackendMockRecorder) FlagCallBackConflict(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlagCallBackConflict", reflect.TypeOf((*MockIDBBackend)(nil).FlagCallBackConflict), arg0, arg1)
+}
+
 // GetDBTransaction mocks base method
 func (m *MockIDBBackend) GetDBTransaction() *gorm.DB {
 	m.ctrl.T.Helper()
@@ -158,18 +341,108 @@ func (mr *MockIDBBackendMockRecorder) GetDBTransaction() *gomock.Call {
 }
 
 // QueryAccount mocks base method
-func (m *MockIDBBackend) QueryAccount(arg0, arg1 string) (*models.Account, error) {
+func (m *MockIDBBackend) QueryAccount(arg0, arg1, arg2 string) (*models.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryAccount", arg0, arg1)
+	ret := m.ctrl.Call(m, "QueryAccount", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryAccount indicates an expected call of QueryAccount
-func (mr *MockIDBBackendMockRecorder) QueryAccount(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockIDBBackendMockRecorder) QueryAccount(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAccount", reflect.TypeOf((*MockIDBBackend)(nil).QueryAccount), arg0, arg1, arg2)
+}
+
+// QueryAccountByLogin mocks base method
+func (m *MockIDBBackend) QueryAccountByLogin(arg0, arg1, arg2 string) (*models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryAccountByLogin", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryAccountByLogin indicates an expected call of QueryAccountByLogin
+func (mr *MockIDBBackendMockRecorder) QueryAccountByLogin(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAccount", reflect.TypeOf((*MockIDBBackend)(nil).QueryAccount), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAccountByLogin", reflect.TypeOf((*MockIDBBackend)(nil).QueryAccountByLogin), arg0, arg1, arg2)
+}
+
+// QueryAddresses mocks base method
+func (m *MockIDBBackend) QueryAddresses(arg0 string) ([]*models.Address, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryAddresses", arg0)
+	ret0, _ := ret[0].([]*models.Address)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryAddresses indicates an expected call of QueryAddresses
+func (mr *MockIDBBackendMockRecorder) QueryAddresses(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAddresses", reflect.TypeOf((*MockIDBBackend)(nil).QueryAddresses), arg0)
+}
+
+// QueryCallBack mocks base method
+func (m *MockIDBBackend) QueryCallBack(arg0 string) (*models.BCCallBack, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryCallBack", arg0)
+	ret0, _ := ret[0].(*models.BCCallBack)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryCallBack indicates an expected call of QueryCallBack
+func (mr *MockIDBBackendMockRecorder) QueryCallBack(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryCallBack", reflect.TypeOf((*MockIDBBackend)(nil).QueryCallBack), arg0)
+}
+
+// QueryCharityApply mocks base method
+func (m *MockIDBBackend) QueryCharityApply(arg0, arg1 string) (*models.CharityApply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryCharityApply", arg0, arg1)
+	ret0, _ := ret[0].(*models.CharityApply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryCharityApply indicates an expected call of QueryCharityApply
+func (mr *MockIDBBackendMockRecorder) QueryCharityApply(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryCharityApply", reflect.TypeOf((*MockIDBBackend)(nil).QueryCharityApply), arg0, arg1)
+}
+
+// QueryCharityApplyList mocks base method
+func (m *MockIDBBackend) QueryCharityApplyList(arg0 string, arg1 *structs.QueryParams) ([]*models.CharityApply, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryCharityApplyList", arg0, arg1)
+	ret0, _ := ret[0].([]*models.CharityApply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryCharityApplyList indicates an expected call of QueryCharityApplyList
+func (mr *MockIDBBackendMockRecorder) QueryCharityApplyList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryCharityApplyList", reflect.TypeOf((*MockIDBBackend)(nil).QueryCharityApplyList), arg0, arg1)
+}
+
+// QueryDonationStats mocks base method
+func (m *MockIDBBackend) QueryDonationStats() ([]*models.DonationStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDonationStats")
+	ret0, _ := ret[0].([]*models.DonationStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDonationStats indicates an expected call of QueryDonationStats
+func (mr *MockIDBBackendMockRecorder) QueryDonationStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDonationStats", reflect.TypeOf((*MockIDBBackend)(nil).QueryDonationStats))
 }
 
 // QueryFunds mocks base method
@@ -187,6 +460,21 @@ func (mr *MockIDBBackendMockRecorder) QueryFunds(arg0, arg1, arg2, arg3, arg4 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryFunds", reflect.TypeOf((*MockIDBBackend)(nil).QueryFunds), arg0, arg1, arg2, arg3, arg4)
 }
 
+// QueryFundsByBlockID mocks base method
+func (m *MockIDBBackend) QueryFundsByBlockID(arg0 string) (*models.PubFunds, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryFundsByBlockID", arg0)
+	ret0, _ := ret[0].(*models.PubFunds)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryFundsByBlockID indicates an expected call of QueryFundsByBlockID
+func (mr *MockIDBBackendMockRecorder) QueryFundsByBlockID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryFundsByBlockID", reflect.TypeOf((*MockIDBBackend)(nil).QueryFundsByBlockID), arg0)
+}
+
 // QueryFundsDetail mocks base method
 func (m *MockIDBBackend) QueryFundsDetail(arg0 string) (*models.FundsDetail, error) {
 	m.ctrl.T.Helper()
@@ -202,6 +490,36 @@ func (mr *MockIDBBackendMockRecorder) QueryFundsDetail(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryFundsDetail", reflect.TypeOf((*MockIDBBackend)(nil).QueryFundsDetail), arg0)
 }
 
+// QueryFundsWithoutOutbox mocks base method
+func (m *MockIDBBackend) QueryFundsWithoutOutbox(arg0 time.Time, arg1 int) ([]*models.PubFunds, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryFundsWithoutOutbox", arg0, arg1)
+	ret0, _ := ret[0].([]*models.PubFunds)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryFundsWithoutOutbox indicates an expected call of QueryFundsWithoutOutbox
+func (mr *MockIDBBackendMockRecorder) QueryFundsWithoutOutbox(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryFundsWithoutOutbox", reflect.TypeOf((*MockIDBBackend)(nil).QueryFundsWithoutOutbox), arg0, arg1)
+}
+
+// QueryImages mocks base method
+func (m *MockIDBBackend) QueryImages(arg0, arg1 string) ([]*models.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryImages", arg0, arg1)
+	ret0, _ := ret[0].([]*models.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryImages indicates an expected call of QueryImages
+func (mr *MockIDBBackendMockRecorder) QueryImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryImages", reflect.TypeOf((*MockIDBBackend)(nil).QueryImages), arg0, arg1)
+}
+
 // QueryOrgCharities mocks base method
 func (m *MockIDBBackend) QueryOrgCharities(arg0 *structs.QueryParams) ([]*structs.OrgCharitiesItems, error) {
 	m.ctrl.T.Helper()
@@ -232,6 +550,141 @@ func (mr *MockIDBBackendMockRecorder) QueryOrgCharitiesDetail(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOrgCharitiesDetail", reflect.TypeOf((*MockIDBBackend)(nil).QueryOrgCharitiesDetail), arg0)
 }
 
+// QueryOrgKyc mocks base method
+func (m *MockIDBBackend) QueryOrgKyc(arg0, arg1 string) (*models.OrgKyc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryOrgKyc", arg0, arg1)
+	ret0, _ := ret[0].(*models.OrgKyc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryOrgKyc indicates an expected call of QueryOrgKyc
+func (mr *MockIDBBackendMockRecorder) QueryOrgKyc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOrgKyc", reflect.TypeOf((*MockIDBBackend)(nil).QueryOrgKyc), arg0, arg1)
+}
+
+// QueryOrgKycList mocks base method
+func (m *MockIDBBackend) QueryOrgKycList(arg0 string, arg1 *structs.QueryParams) ([]*models.OrgKyc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryOrgKycList", arg0, arg1)
+	ret0, _ := ret[0].([]*models.OrgKyc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryOrgKycList indicates an expected call of QueryOrgKycList
+func (mr *MockIDBBackendMockRecorder) QueryOrgKycList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOrgKycList", reflect.TypeOf((*MockIDBBackend)(nil).QueryOrgKycList), arg0, arg1)
+}
+
+// QueryOutbox mocks base method
+func (m *MockIDBBackend) QueryOutbox(arg0 string) (*models.PubOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryOutbox", arg0)
+	ret0, _ := ret[0].(*models.PubOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryOutbox indicates an expected call of QueryOutbox
+func (mr *MockIDBBackendMockRecorder) QueryOutbox(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOutbox", reflect.TypeOf((*MockIDBBackend)(nil).QueryOutbox), arg0)
+}
+
+// QueryOutboxPending mocks base method
+func (m *MockIDBBackend) QueryOutboxPending(arg0 int) ([]*models.PubOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryOutboxPending", arg0)
+	ret0, _ := ret[0].([]*models.PubOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryOutboxPending indicates an expected call of QueryOutboxPending
+func (mr *MockIDBBackendMockRecorder) QueryOutboxPending(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOutboxPending", reflect.TypeOf((*MockIDBBackend)(nil).QueryOutboxPending), arg0)
+}
+
+// QueryOutboxStuck mocks base method
+func (m *MockIDBBackend) QueryOutboxStuck(arg0 time.Time, arg1 int) ([]*models.PubOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryOutboxStuck", arg0, arg1)
+	ret0, _ := ret[0].([]*models.PubOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryOutboxStuck indicates an expected call of QueryOutboxStuck
+func (mr *MockIDBBackendMockRecorder) QueryOutboxStuck(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryOutboxStuck", reflect.TypeOf((*MockIDBBackend)(nil).QueryOutboxStuck), arg0, arg1)
+}
+
+// QueryPersonKyc mocks base method
+func (m *MockIDBBackend) QueryPersonKyc(arg0, arg1 string) (*models.PersonKyc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryPersonKyc", arg0, arg1)
+	ret0, _ := ret[0].(*models.PersonKyc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryPersonKyc indicates an expected call of QueryPersonKyc
+func (mr *MockIDBBackendMockRecorder) QueryPersonKyc(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryPersonKyc", reflect.TypeOf((*MockIDBBackend)(nil).QueryPersonKyc), arg0, arg1)
+}
+
+// QueryPersonKycList mocks base method
+func (m *MockIDBBackend) QueryPersonKycList(arg0 string, arg1 *structs.QueryParams) ([]*models.PersonKyc, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryPersonKycList", arg0, arg1)
+	ret0, _ := ret[0].([]*models.PersonKyc)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryPersonKycList indicates an expected call of QueryPersonKycList
+func (mr *MockIDBBackendMockRecorder) QueryPersonKycList(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryPersonKycList", reflect.TypeOf((*MockIDBBackend)(nil).QueryPersonKycList), arg0, arg1)
+}
+
+// QueryProfileChanges mocks base method
+func (m *MockIDBBackend) QueryProfileChanges(arg0 string, arg1 *structs.QueryParams) ([]*models.ProfileChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryProfileChanges", arg0, arg1)
+	ret0, _ := ret[0].([]*models.ProfileChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryProfileChanges indicates an expected call of QueryProfileChanges
+func (mr *MockIDBBackendMockRecorder) QueryProfileChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryProfileChanges", reflect.TypeOf((*MockIDBBackend)(nil).QueryProfileChanges), arg0, arg1)
+}
+
+// QueryPubByUID mocks base method
+func (m *MockIDBBackend) QueryPubByUID(arg0, arg1 string, arg2 *structs.QueryParams) ([]*structs.PubUserItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryPubByUID", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*structs.PubUserItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryPubByUID indicates an expected call of QueryPubByUID
+func (mr *MockIDBBackendMockRecorder) QueryPubByUID(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryPubByUID", reflect.TypeOf((*MockIDBBackend)(nil).QueryPubByUID), arg0, arg1, arg2)
+}
+
 // QueryPubByUserType mocks base method
 func (m *MockIDBBackend) QueryPubByUserType(arg0, arg1, arg2 string, arg3 *structs.QueryParams) ([]*structs.PubUserItem, error) {
 	m.ctrl.T.Helper()
@@ -247,6 +700,21 @@ func (mr *MockIDBBackendMockRecorder) QueryPubByUserType(arg0, arg1, arg2, arg3
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryPubByUserType", reflect.TypeOf((*MockIDBBackend)(nil).QueryPubByUserType), arg0, arg1, arg2, arg3)
 }
 
+// QueryRankSnapshots mocks base method
+func (m *MockIDBBackend) QueryRankSnapshots(arg0, arg1, arg2 string, arg3 *structs.QueryParams) ([]*models.RankSnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryRankSnapshots", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]*models.RankSnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryRankSnapshots indicates an expected call of QueryRankSnapshots
+func (mr *MockIDBBackendMockRecorder) QueryRankSnapshots(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRankSnapshots", reflect.TypeOf((*MockIDBBackend)(nil).QueryRankSnapshots), arg0, arg1, arg2, arg3)
+}
+
 // QuerySupplies mocks base method
 func (m *MockIDBBackend) QuerySupplies(arg0, arg1, arg2, arg3 string, arg4 *structs.QueryParams) ([]*models.PubSupplies, error) {
 	m.ctrl.T.Helper()
@@ -262,6 +730,21 @@ func (mr *MockIDBBackendMockRecorder) QuerySupplies(arg0, arg1, arg2, arg3, arg4
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuerySupplies", reflect.TypeOf((*MockIDBBackend)(nil).QuerySupplies), arg0, arg1, arg2, arg3, arg4)
 }
 
+// QuerySuppliesByBlockID mocks base method
+func (m *MockIDBBackend) QuerySuppliesByBlockID(arg0 string) (*models.PubSupplies, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuerySuppliesByBlockID", arg0)
+	ret0, _ := ret[0].(*models.PubSupplies)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuerySuppliesByBlockID indicates an expected call of QuerySuppliesByBlockID
+func (mr *MockIDBBackendMockRecorder) QuerySuppliesByBlockID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuerySuppliesByBlockID", reflect.TypeOf((*MockIDBBackend)(nil).QuerySuppliesByBlockID), arg0)
+}
+
 // QuerySuppliesDetail mocks base method
 func (m *MockIDBBackend) QuerySuppliesDetail(arg0 string) (*models.SuppliesDetail, error) {
 	m.ctrl.T.Helper()
@@ -277,6 +760,122 @@ func (mr *MockIDBBackendMockRecorder) QuerySuppliesDetail(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuerySuppliesDetail", reflect.TypeOf((*MockIDBBackend)(nil).QuerySuppliesDetail), arg0)
 }
 
+// QuerySuppliesWithoutOutbox mocks base method
+func (m *MockIDBBackend) QuerySuppliesWithoutOutbox(arg0 time.Time, arg1 int) ([]*models.PubSupplies, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuerySuppliesWithoutOutbox", arg0, arg1)
+	ret0, _ := ret[0].([]*models.PubSupplies)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuerySuppliesWithoutOutbox indicates an expected call of QuerySuppliesWithoutOutbox
+func (mr *MockIDBBackendMockRecorder) QuerySuppliesWithoutOutbox(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuerySuppliesWithoutOutbox", reflect.TypeOf((*MockIDBBackend)(nil).QuerySuppliesWithoutOutbox), arg0, arg1)
+}
+
+// QueryWXBinding mocks base method
+func (m *MockIDBBackend) QueryWXBinding(arg0, arg1, arg2, arg3 string) (*models.WXBinding, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryWXBinding", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*models.WXBinding)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryWXBinding indicates an expected call of QueryWXBinding
+func (mr *MockIDBBackendMockRecorder) QueryWXBinding(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryWXBinding", reflect.TypeOf((*MockIDBBackend)(nil).QueryWXBinding), arg0, arg1, arg2, arg3)
+}
+
+// ReplaceRankSnapshots mocks base method
+func (m *MockIDBBackend) ReplaceRankSnapshots(arg0 *gorm.DB, arg1, arg2 string, arg3 []*models.RankSnapshot) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRankSnapshots", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRankSnapshots indicates an expected call of ReplaceRankSnapshots
+func (mr *MockIDBBackendMockRecorder) ReplaceRankSnapshots(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRankSnapshots", reflect.TypeOf((*MockIDBBackend)(nil).ReplaceRankSnapshots), arg0, arg1, arg2, arg3)
+}
+
+// SaveDonationStat mocks base method
+func (m *MockIDBBackend) SaveDonationStat(arg0 *gorm.DB, arg1 *models.DonationStat) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDonationStat", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDonationStat indicates an expected call of SaveDonationStat
+func (mr *MockIDBBackendMockRecorder) SaveDonationStat(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDonationStat", reflect.TypeOf((*MockIDBBackend)(nil).SaveDonationStat), arg0, arg1)
+}
+
+// SumDonationStats mocks base method
+func (m *MockIDBBackend) SumDonationStats() ([]*models.DonationStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumDonationStats")
+	ret0, _ := ret[0].([]*models.DonationStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumDonationStats indicates an expected call of SumDonationStats
+func (mr *MockIDBBackendMockRecorder) SumDonationStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumDonationStats", reflect.TypeOf((*MockIDBBackend)(nil).SumDonationStats))
+}
+
+// SumRankStats mocks base method
+func (m *MockIDBBackend) SumRankStats(arg0 string, arg1 time.Time) ([]*structs.RankStat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SumRankStats", arg0, arg1)
+	ret0, _ := ret[0].([]*structs.RankStat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SumRankStats indicates an expected call of SumRankStats
+func (mr *MockIDBBackendMockRecorder) SumRankStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumRankStats", reflect.TypeOf((*MockIDBBackend)(nil).SumRankStats), arg0, arg1)
+}
+
+// UpdateAccount mocks base method
+func (m *MockIDBBackend) UpdateAccount(arg0 *gorm.DB, arg1 string, arg2 map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccount", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAccount indicates an expected call of UpdateAccount
+func (mr *MockIDBBackendMockRecorder) UpdateAccount(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockIDBBackend)(nil).UpdateAccount), arg0, arg1, arg2)
+}
+
+// UpdateCharityApplyStatus mocks base method
+func (m *MockIDBBackend) UpdateCharityApplyStatus(arg0 *gorm.DB, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCharityApplyStatus", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateCharityApplyStatus indicates an expected call of UpdateCharityApplyStatus
+func (mr *MockIDBBackendMockRecorder) UpdateCharityApplyStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCharityApplyStatus", reflect.TypeOf((*MockIDBBackend)(nil).UpdateCharityApplyStatus), arg0, arg1, arg2, arg3)
+}
+
 // UpdateFunds mocks base method
 func (m *MockIDBBackend) UpdateFunds(arg0 *gorm.DB, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
@@ -305,6 +904,76 @@ func (mr *MockIDBBackendMockRecorder) UpdateFundsBC(arg0, arg1, arg2 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFundsBC", reflect.TypeOf((*MockIDBBackend)(nil).UpdateFundsBC), arg0, arg1, arg2)
 }
 
+// UpdateFundsStatus mocks base method
+func (m *MockIDBBackend) UpdateFundsStatus(arg0 *gorm.DB, arg1, arg2, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFundsStatus", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFundsStatus indicates an expected call of UpdateFundsStatus
+func (mr *MockIDBBackendMockRecorder) UpdateFundsStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFundsStatus", reflect.TypeOf((*MockIDBBackend)(nil).UpdateFundsStatus), arg0, arg1, arg2, arg3)
+}
+
+// UpdateKycStatus mocks base method
+func (m *MockIDBBackend) UpdateKycStatus(arg0 *gorm.DB, arg1, arg2, arg3, arg4 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateKycStatus", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateKycStatus indicates an expected call of UpdateKycStatus
+func (mr *MockIDBBackendMockRecorder) UpdateKycStatus(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateKycStatus", reflect.TypeOf((*MockIDBBackend)(nil).UpdateKycStatus), arg0, arg1, arg2, arg3, arg4)
+}
+
+// UpdateOutbox mocks base method
+func (m *MockIDBBackend) UpdateOutbox(arg0 *gorm.DB, arg1 string, arg2 *models.PubOutbox) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOutbox", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOutbox indicates an expected call of UpdateOutbox
+func (mr *MockIDBBackendMockRecorder) UpdateOutbox(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutbox", reflect.TypeOf((*MockIDBBackend)(nil).UpdateOutbox), arg0, arg1, arg2)
+}
+
+// UpdateOutboxBC mocks base method
+func (m *MockIDBBackend) UpdateOutboxBC(arg0 *gorm.DB, arg1 string, arg2 *models.PubOutbox) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOutboxBC", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOutboxBC indicates an expected call of UpdateOutboxBC
+func (mr *MockIDBBackendMockRecorder) UpdateOutboxBC(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOutboxBC", reflect.TypeOf((*MockIDBBackend)(nil).UpdateOutboxBC), arg0, arg1, arg2)
+}
+
+// UpdateSupplies mocks base method
+func (m *MockIDBBackend) UpdateSupplies(arg0 *gorm.DB, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSupplies", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSupplies indicates an expected call of UpdateSupplies
+func (mr *MockIDBBackendMockRecorder) UpdateSupplies(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSupplies", reflect.TypeOf((*MockIDBBackend)(nil).UpdateSupplies), arg0, arg1, arg2)
+}
+
 // UpdateSuppliesBC mocks base method
 func (m *MockIDBBackend) UpdateSuppliesBC(arg0 *gorm.DB, arg1 string, arg2 *models.PubSupplies) error {
 	m.ctrl.T.Helper()
@@ -319,16 +988,16 @@ func (mr *MockIDBBackendMockRecorder) UpdateSuppliesBC(arg0, arg1, arg2 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSuppliesBC", reflect.TypeOf((*MockIDBBackend)(nil).UpdateSuppliesBC), arg0, arg1, arg2)
 }
 
-// UpdateSuppliesList mocks base method
-func (m *MockIDBBackend) UpdateSuppliesList(arg0 *gorm.DB, arg1 []*models.PubSupplies, arg2 []*structs.PubResp) error {
+// UpdateSuppliesStatus mocks base method
+func (m *MockIDBBackend) UpdateSuppliesStatus(arg0 *gorm.DB, arg1, arg2, arg3 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateSuppliesList", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "UpdateSuppliesStatus", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateSuppliesList indicates an expected call of UpdateSuppliesList
-func (mr *MockIDBBackendMockRecorder) UpdateSuppliesList(arg0, arg1, arg2 interface{}) *gomock.Call {
+// UpdateSuppliesStatus indicates an expected call of UpdateSuppliesStatus
+func (mr *MockIDBBackendMockRecorder) UpdateSuppliesStatus(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSuppliesList", reflect.TypeOf((*MockIDBBackend)(nil).UpdateSuppliesList), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSuppliesStatus", reflect.TypeOf((*MockIDBBackend)(nil).UpdateSuppliesStatus), arg0, arg1, arg2, arg3)
 }
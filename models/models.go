@@ -14,23 +14,25 @@ import (
 
 // Account defines the common information of user
 type Account struct {
-	ID             string `gorm:"type:varchar(256);primary_key"` // user id
-	Access         string `gorm:"type:varchar(256)"`             // user name
-	Password       string `gorm:"type:varchar(256)"`             // password
-	NickName       string `gorm:"type:varchar(64)"`              // nick name
-	Type           string `gorm:"type:varchar(16)"`              // user type
-	Phone          string `gorm:"type:varchar(32)"`              // phone num
-	Email          string `gorm:"type:varchar(128)"`             // email
-	KycStatus      string `gorm:"type:varchar(16)"`              // kyc status
-	Bank           string `gorm:"type:varchar(64)"`              // bank name
-	BankCardNum    string `gorm:"type:varchar(64)"`              // bank card num
-	TaxID          string `gorm:"type:varchar(128)"`             // tax id
-	ShippingAddrID string `gorm:"type:varchar(256)"`             // shipping address id
-	DID            string `gorm:"type:varchar(128)"`             // did
-	Remark         string `gorm:"type:text"`                     // description
-	OpenID         string `gorm:"type:varchar(256)"`             // open id of wechat app
-	UnionID        string `gorm:"type:varchar(256)"`             // id of wechat app
-	AppID          string `gorm:"type:varchar(256)"`             // app id
+	ID             string `gorm:"type:varchar(256);primary_key"`               // user id
+	Access         string `gorm:"type:varchar(256);unique_index;default:null"` // user name
+	Password       string `gorm:"type:varchar(256)"`                           // password
+	NickName       string `gorm:"type:varchar(64)"`                            // nick name
+	Type           string `gorm:"type:varchar(16)"`                            // user type
+	Phone          string `gorm:"type:varchar(32);unique_index;default:null"`  // phone num
+	Email          string `gorm:"type:varchar(128);unique_index;default:null"` // email
+	KycStatus      string `gorm:"type:varchar(16)"`                            // kyc status
+	Bank           string `gorm:"type:varchar(64)"`                            // bank name
+	BankCardNum    string `gorm:"type:varchar(64)"`                            // bank card num
+	TaxID          string `gorm:"type:varchar(128)"`                           // tax id
+	ShippingAddrID string `gorm:"type:varchar(256)"`                           // shipping address id
+	DID            string `gorm:"type:varchar(128)"`                           // did
+	Remark         string `gorm:"type:text"`                                   // description
+	OpenID         string `gorm:"type:varchar(256)"`                           // open id of wechat app
+	UnionID        string `gorm:"type:varchar(256)"`                           // id of wechat app
+	AppID          string `gorm:"type:varchar(256)"`                           // app id
+	Source         string `gorm:"type:varchar(16)"`                            // where the account registered, wx, app or web
+	ContactPhone   string `gorm:"type:varchar(32)"`                            // public contact phone of charity
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 	DeletedAt      *time.Time `sql:"index"`
@@ -55,8 +57,8 @@ type Address struct {
 
 // DonationStat defines the donation statistics of organization
 type DonationStat struct {
-	ID                  string          `gorm:"type:varchar(256);primary_key"` // donation statistics id
-	UID                 string          `gorm:"type:varchar(256);not null"`    // user id
+	ID                  string          `gorm:"type:varchar(256);primary_key"`           // donation statistics id
+	UID                 string          `gorm:"type:varchar(256);not null;unique_index"` // user id, one statistics each
 	ReceivedSupplies    int64           // receiving supply
 	DistributedSupplies int64           // distribute supply
 	ReceivedFunds       decimal.Decimal `gorm:"type:decimal(30,4)"` // receiving funds
@@ -66,6 +68,57 @@ type DonationStat struct {
 	DeletedAt           *time.Time `sql:"index"`
 }
 
+// CharityApply defines the application of account to become charity, the registration address,
+// logo and bank details are kept on the account once approved
+type CharityApply struct {
+	ID          string `gorm:"type:varchar(256);primary_key"` // application id
+	UID         string `gorm:"type:varchar(256);index"`       // user id
+	Name        string `gorm:"type:varchar(64)"`              // the name of charity
+	Logo        string `gorm:"type:varchar(512)"`             // logo url
+	Bank        string `gorm:"type:varchar(64)"`              // bank name
+	BankCardNum string `gorm:"type:varchar(64)"`              // bank card num
+	Description string `gorm:"type:text"`                     // description of charity
+	Country     string `gorm:"type:varchar(32)"`              // country of registration address
+	Province    string `gorm:"type:varchar(32)"`              // province of registration address
+	City        string `gorm:"type:varchar(32)"`              // city of registration address
+	District    string `gorm:"type:varchar(32)"`              // district of registration address
+	Address     string `gorm:"type:varchar(256)"`             // detail registration address
+	ZipCode     string `gorm:"type:varchar(256)"`             // zip code of registration address
+	Status      string `gorm:"type:varchar(16)"`              // the status of application
+	Remark      string `gorm:"size:1024"`                     // remark of review
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time `sql:"index"`
+}
+
+// ProfileChange defines the change of one field of charity profile, kept as history
+type ProfileChange struct {
+	ID        string `gorm:"type:varchar(256);primary_key"` // change id
+	UID       string `gorm:"type:varchar(256);index"`       // user id of charity
+	Field     string `gorm:"type:varchar(32)"`              // the field changed
+	OldValue  string `gorm:"type:text"`                     // value before changed
+	NewValue  string `gorm:"type:text"`                     // value after changed
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time `sql:"index"`
+}
+
+// RankSnapshot defines one item ranked on leaderboard, the snapshots of board and period are refreshed as a whole
+type RankSnapshot struct {
+	ID          string          `gorm:"type:varchar(256);primary_key"`   // snapshot id
+	Board       string          `gorm:"type:varchar(16);index:idx_rank"` // the type of leaderboard
+	Period      string          `gorm:"type:varchar(16);index:idx_rank"` // the period ranked
+	Metric      string          `gorm:"type:varchar(32);index:idx_rank"` // the metric ranked
+	Ranking     int             // rank, the same for the same value, not named rank which is a window function of sql
+	UID         string          `gorm:"type:varchar(256)"`  // user id of charity or donor
+	Name        string          `gorm:"type:varchar(256)"`  // nick name of charity or donor
+	Value       decimal.Decimal `gorm:"type:decimal(30,4)"` // value of metric
+	RefreshedAt int64           // time of snapshot refreshed
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time `sql:"index"`
+}
+
 // PersonKyc defines the kyc information of single person
 type PersonKyc struct {
 	ID          string `gorm:"type:varchar(256);primary_key"` // person kyc id
@@ -135,6 +188,14 @@ type PubFunds struct {
 	TxID              string          `gorm:"type:varchar(256)"`             // block chain tx id
 	BlockHeight       int64           // block height
 	BlockTime         int64           // block time
+	ChainStatus       string          `gorm:"type:varchar(16);index"` // lifecycle status on block chain
+	ChainError        string          `gorm:"size:1024"`              // last error of block chain adapter
+	SubmittedAt       int64           // time of submitted to block chain
+	ConfirmedAt       int64           // time of block chain call back received
+	FailedAt          int64           // time of last failure
+	RetriedAt         int64           // time of last retry
+	SchemaVer         int             // schema version of payload on block chain
+	PayloadHash       string          `gorm:"type:varchar(64)"` // sha256 of payload on block chain
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 	DeletedAt         *time.Time `sql:"index"`
@@ -161,11 +222,80 @@ type PubSupplies struct {
 	BlockType   string `gorm:"type:varchar(32)"`  // block type
 	BlockHeight int64  // block height
 	BlockTime   int64  // block time
+	ChainStatus string `gorm:"type:varchar(16);index"` // lifecycle status on block chain
+	ChainError  string `gorm:"size:1024"`              // last error of block chain adapter
+	SubmittedAt int64  // time of submitted to block chain
+	ConfirmedAt int64  // time of block chain call back received
+	FailedAt    int64  // time of last failure
+	RetriedAt   int64  // time of last retry
+	SchemaVer   int    // schema version of payload on block chain
+	PayloadHash string `gorm:"type:varchar(64)"` // sha256 of payload on block chain
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   *time.Time `sql:"index"`
 }
 
+// PubOutbox defines the publicity waiting to be published to block chain
+type PubOutbox struct {
+	ID          string `gorm:"type:varchar(256);primary_key"`  // outbox id
+	RelatedID   string `gorm:"type:varchar(256);unique_index"` // funds or supplies id, one outbox each
+	Type        string `gorm:"type:varchar(16)"`               // funds or supplies
+	BCID        string `gorm:"type:varchar(128)"`              // did of the one who publish
+	Publicity   string `gorm:"type:text"`                      // publicity data on block chain
+	Status      string `gorm:"type:varchar(16);index"`         // status of publicity on block chain
+	BlockID     string `gorm:"type:varchar(256)"`              // block chain id
+	TxID        string `gorm:"type:varchar(256)"`              // block chain tx id
+	BlockHeight int64  // block height
+	Attempts    int    // publish attempts
+	LastError   string `gorm:"size:1024"` // error of last publish attempt
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time `sql:"index"`
+}
+
+// PubAttempt defines the block chain id given to funds or supplies by one publish attempt, the previous ones are
+// kept so that their late call backs are still matched
+type PubAttempt struct {
+	ID        string `gorm:"type:varchar(256);primary_key"` // attempt id
+	RelatedID string `gorm:"type:varchar(256);index"`       // funds or supplies id
+	Type      string `gorm:"type:varchar(16)"`              // funds or supplies
+	BlockID   string `gorm:"type:varchar(256);index"`       // block chain id given by adapter
+	Attempt   int    // publish attempt
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time `sql:"index"`
+}
+
+// BCCallBack defines the call back of block chain, one per block chain id
+type BCCallBack struct {
+	ID           string `gorm:"type:varchar(256);primary_key"` // block chain id
+	RelatedID    string `gorm:"type:varchar(256);index"`       // funds or supplies id
+	Type         string `gorm:"type:varchar(16)"`              // funds or supplies
+	BlockType    string `gorm:"type:varchar(32)"`              // block type
+	TxID         string `gorm:"type:varchar(256)"`             // block chain tx id
+	BlockHeight  int64  // block height
+	BlockTime    int64  // block time
+	Status       string `gorm:"type:varchar(16);index"` // applied or conflict
+	ConflictTxID string `gorm:"type:varchar(256)"`      // tx id of the last conflicting call back
+	Conflicts    int    // number of conflicting call back
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    *time.Time `sql:"index"`
+}
+
+// WXBinding defines the user of wechat app bound to account, the users of apps are linked
+// to one account by union id
+type WXBinding struct {
+	ID        string `gorm:"type:varchar(256);primary_key"`                      // binding id
+	UID       string `gorm:"type:varchar(256);index"`                            // user id
+	AppID     string `gorm:"type:varchar(256);unique_index:idx_wx_binding_open"` // app id
+	OpenID    string `gorm:"type:varchar(256);unique_index:idx_wx_binding_open"` // open id of wechat app
+	UnionID   string `gorm:"type:varchar(256);index"`                            // union id across wechat apps
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time `sql:"index"`
+}
+
 // Cover defines the introduction information
 type Cover struct {
 	ID          string `gorm:"type:varchar(256);primary_key"` // cover id
@@ -0,0 +1,67 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	amountScale = 4 // same as the scale of amount column
+)
+
+// formatAmount formats amount of payload, fixed to amount scale since schema version 2
+func formatAmount(amount decimal.Decimal, version int) string {
+	if version < rest.PayloadSchemaVersion {
+		return amount.String()
+	}
+
+	return amount.StringFixed(amountScale)
+}
+
+// marshalPayload encodes payload in its schema version, the legacy payload is plain json in field order,
+// the others are canonical json with schema version and record type
+func marshalPayload(v interface{}, recordType string, version int) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	if version < rest.PayloadSchemaVersion {
+		return string(b), nil
+	}
+
+	m := make(map[string]interface{})
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&m); err != nil {
+		return "", err
+	}
+
+	m["schema_version"] = version
+	m["record_type"] = recordType
+
+	return CanonicalJSON(m)
+}
+
+// CanonicalJSON encodes v as compact json with keys sorted at every level and html characters unescaped
+func CanonicalJSON(v interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
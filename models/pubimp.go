@@ -7,100 +7,106 @@
 package models
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/csiabb/donation-service/common/rest"
 	"github.com/csiabb/donation-service/structs"
 )
 
-// ConvertFundsDonation ...
+// ConvertFundsDonation converts the funds donated to payload on block chain in current schema
 func (funds *PubFunds) ConvertFundsDonation(images []*Image) (string, error) {
 	if funds == nil {
 		return "", errors.New("para m is nil")
 	}
 
+	funds.seal()
+	return funds.hash(funds.fundsDonation(images, funds.CreatedAt.Unix(), funds.SchemaVer))
+}
+
+func (funds *PubFunds) fundsDonation(images []*Image, t int64, version int) (string, error) {
 	fd := &structs.FundsDonation{
 		ID:                funds.ID,
 		UID:               funds.UID,
 		DonorName:         funds.DonorName,
-		Time:              time.Now().Unix(),
-		Amount:            funds.Amount.String(),
+		Time:              t,
+		Amount:            formatAmount(funds.Amount, version),
 		TargetName:        funds.TargetName,
 		TargetBankCardNum: funds.TargetBankCardNum,
 		DonationImages:    convertImages(images),
 	}
 
-	byte, err := json.Marshal(fd)
-	if err != nil {
-		return "", err
-	}
-
-	return string(byte), nil
+	return marshalPayload(fd, rest.RecordFundsDonation, version)
 }
 
-// ConvertFundsReceived ...
+// ConvertFundsReceived converts the funds received to payload on block chain in current schema
 func (funds *PubFunds) ConvertFundsReceived(images []*Image) (string, error) {
 	if funds == nil {
 		return "", errors.New("para m is nil")
 	}
 
+	funds.seal()
+	return funds.hash(funds.fundsReceived(images, funds.CreatedAt.Unix(), funds.SchemaVer))
+}
+
+func (funds *PubFunds) fundsReceived(images []*Image, t int64, version int) (string, error) {
 	fd := &structs.FundsReceived{
 		ID:                funds.ID,
 		TargetUID:         funds.TargetUID,
 		TargetName:        funds.TargetName,
 		DonorName:         funds.DonorName,
-		Time:              time.Now().Unix(),
-		Amount:            funds.Amount.String(),
+		Time:              t,
+		Amount:            formatAmount(funds.Amount, version),
 		TargetBankCardNum: funds.TargetBankCardNum,
 		DonationImages:    convertImages(images),
 	}
 
-	byte, err := json.Marshal(fd)
-	if err != nil {
-		return "", err
-	}
-
-	return string(byte), nil
+	return marshalPayload(fd, rest.RecordFundsReceived, version)
 }
 
-// ConvertFundsDistributed ...
+// ConvertFundsDistributed converts the funds distributed to payload on block chain in current schema
 func (funds *PubFunds) ConvertFundsDistributed(images []*Image) (string, error) {
 	if funds == nil {
 		return "", errors.New("para m is nil")
 	}
 
+	funds.seal()
+	return funds.hash(funds.fundsDistributed(images, funds.CreatedAt.Unix(), funds.SchemaVer))
+}
+
+func (funds *PubFunds) fundsDistributed(images []*Image, t int64, version int) (string, error) {
 	fd := &structs.FundsDistributed{
 		ID:                funds.ID,
 		TargetUID:         funds.TargetUID,
 		TargetName:        funds.TargetName,
 		TargetBankCardNum: funds.TargetBankCardNum,
 		AidName:           funds.AidName,
-		Time:              time.Now().Unix(),
-		Amount:            funds.Amount.String(),
+		Time:              t,
+		Amount:            formatAmount(funds.Amount, version),
 		DonationImages:    convertImages(images),
 	}
 
-	byte, err := json.Marshal(fd)
-	if err != nil {
-		return "", err
-	}
-
-	return string(byte), nil
+	return marshalPayload(fd, rest.RecordFundsDistributed, version)
 }
 
-// ConvertSuppliesDonation ...
+// ConvertSuppliesDonation converts the supplies donated to payload on block chain in current schema
 func (supplies *PubSupplies) ConvertSuppliesDonation(billingAddr *Address, shippingAddr *Address, images []*Image) (string, error) {
 	if supplies == nil {
 		return "", errors.New("para m is nil")
 	}
 
+	supplies.seal()
+	return supplies.hash(supplies.suppliesDonation(billingAddr, shippingAddr, images, supplies.CreatedAt.Unix(), supplies.SchemaVer))
+}
+
+func (supplies *PubSupplies) suppliesDonation(billingAddr *Address, shippingAddr *Address, images []*Image, t int64, version int) (string, error) {
 	sp := &structs.SuppliesDonation{
 		ID:              supplies.ID,
 		UID:             supplies.UID,
 		DonorName:       supplies.DonorName,
 		BillingAddress:  billingAddr.FullAddress(),
-		Time:            time.Now().Unix(),
+		Time:            t,
 		Name:            supplies.Name,
 		Number:          supplies.Number,
 		Unit:            supplies.Unit,
@@ -110,26 +116,26 @@ func (supplies *PubSupplies) ConvertSuppliesDonation(billingAddr *Address, shipp
 		DonationImages:  convertImages(images),
 	}
 
-	byte, err := json.Marshal(sp)
-	if err != nil {
-		return "", err
-	}
-
-	return string(byte), nil
+	return marshalPayload(sp, rest.RecordSuppliesDonation, version)
 }
 
-// ConvertSuppliesReceived ...
+// ConvertSuppliesReceived converts the supplies received to payload on block chain in current schema
 func (supplies *PubSupplies) ConvertSuppliesReceived(billingAddr *Address, shippingAddr *Address, images []*Image) (string, error) {
 	if supplies == nil {
 		return "", errors.New("para m is nil")
 	}
 
+	supplies.seal()
+	return supplies.hash(supplies.suppliesReceived(billingAddr, shippingAddr, images, supplies.CreatedAt.Unix(), supplies.SchemaVer))
+}
+
+func (supplies *PubSupplies) suppliesReceived(billingAddr *Address, shippingAddr *Address, images []*Image, t int64, version int) (string, error) {
 	sp := &structs.SuppliesReceived{
 		ID:              supplies.ID,
 		TargetUID:       supplies.TargetUID,
 		DonorName:       supplies.DonorName,
 		BillingAddress:  billingAddr.FullAddress(),
-		Time:            time.Now().Unix(),
+		Time:            t,
 		Name:            supplies.Name,
 		Number:          supplies.Number,
 		Unit:            supplies.Unit,
@@ -139,20 +145,20 @@ func (supplies *PubSupplies) ConvertSuppliesReceived(billingAddr *Address, shipp
 		DonationImages:  convertImages(images),
 	}
 
-	byte, err := json.Marshal(sp)
-	if err != nil {
-		return "", err
-	}
-
-	return string(byte), nil
+	return marshalPayload(sp, rest.RecordSuppliesReceived, version)
 }
 
-// SuppliesDistributed ...
+// SuppliesDistributed converts the supplies distributed to payload on block chain in current schema
 func (supplies *PubSupplies) SuppliesDistributed(billingAddr *Address, shippingAddr *Address, images []*Image) (string, error) {
 	if supplies == nil {
 		return "", errors.New("para m is nil")
 	}
 
+	supplies.seal()
+	return supplies.hash(supplies.suppliesDistributed(billingAddr, shippingAddr, images, supplies.CreatedAt.Unix(), supplies.SchemaVer))
+}
+
+func (supplies *PubSupplies) suppliesDistributed(billingAddr *Address, shippingAddr *Address, images []*Image, t int64, version int) (string, error) {
 	sp := &structs.SuppliesDistributed{
 		ID:              supplies.ID,
 		TargetUID:       supplies.TargetUID,
@@ -161,19 +167,114 @@ func (supplies *PubSupplies) SuppliesDistributed(billingAddr *Address, shippingA
 		Name:            supplies.Name,
 		Number:          supplies.Number,
 		Unit:            supplies.Unit,
-		Time:            time.Now().Unix(),
+		Time:            t,
 		AidName:         supplies.AidName,
 		ShippingAddress: shippingAddr.FullAddress(),
 		WayBillNum:      supplies.WayBillNum,
 		DonationImages:  convertImages(images),
 	}
 
-	byte, err := json.Marshal(sp)
+	return marshalPayload(sp, rest.RecordSuppliesDistributed, version)
+}
+
+// seal stamps funds with the current payload schema, the created time is the time of payload
+func (funds *PubFunds) seal() {
+	if funds.CreatedAt.IsZero() {
+		funds.CreatedAt = time.Now()
+	}
+
+	// kept in seconds, same as the time of payload
+	funds.CreatedAt = funds.CreatedAt.Truncate(time.Second)
+	funds.SchemaVer = rest.PayloadSchemaVersion
+}
+
+// hash keeps the hash of payload on funds
+func (funds *PubFunds) hash(payload string, err error) (string, error) {
 	if err != nil {
 		return "", err
 	}
 
-	return string(byte), nil
+	funds.PayloadHash = PublicityHash(payload)
+	return payload, nil
+}
+
+// seal stamps supplies with the current payload schema, the created time is the time of payload
+func (supplies *PubSupplies) seal() {
+	if supplies.CreatedAt.IsZero() {
+		supplies.CreatedAt = time.Now()
+	}
+
+	// kept in seconds, same as the time of payload
+	supplies.CreatedAt = supplies.CreatedAt.Truncate(time.Second)
+	supplies.SchemaVer = rest.PayloadSchemaVersion
+}
+
+// hash keeps the hash of payload on supplies
+func (supplies *PubSupplies) hash(payload string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	supplies.PayloadHash = PublicityHash(payload)
+	return payload, nil
+}
+
+// Publicity recomputes the payload of funds published to block chain in its schema version,
+// t is the time of legacy payload which is not kept with funds
+func (detail *FundsDetail) Publicity(t int64) (string, error) {
+	f := &detail.Funds
+	if f.SchemaVer >= rest.PayloadSchemaVersion {
+		t = f.CreatedAt.Unix()
+	}
+
+	switch f.PubType {
+	case rest.PubTypeDonate:
+		return f.fundsDonation(detail.ProofImages, t, f.SchemaVer)
+	case rest.PubTypeReceive:
+		return f.fundsReceived(detail.ProofImages, t, f.SchemaVer)
+	case rest.PubTypeDistribute:
+		return f.fundsDistributed(detail.ProofImages, t, f.SchemaVer)
+	}
+
+	return "", fmt.Errorf("invalid pub type %s", f.PubType)
+}
+
+// Publicity recomputes the payload of supplies published to block chain in its schema version,
+// t is the time of legacy payload which is not kept with supplies
+func (detail *SuppliesDetail) Publicity(t int64) (string, error) {
+	s := &detail.Supplies
+	if s.SchemaVer >= rest.PayloadSchemaVersion {
+		t = s.CreatedAt.Unix()
+	}
+
+	switch s.PubType {
+	case rest.PubTypeDonate:
+		return s.suppliesDonation(&detail.BillingAddr, &detail.ShippingAddr, detail.ProofImages, t, s.SchemaVer)
+	case rest.PubTypeReceive:
+		return s.suppliesReceived(&detail.BillingAddr, &detail.ShippingAddr, detail.ProofImages, t, s.SchemaVer)
+	case rest.PubTypeDistribute:
+		return s.suppliesDistributed(&detail.BillingAddr, &detail.ShippingAddr, detail.ProofImages, t, s.SchemaVer)
+	}
+
+	return "", fmt.Errorf("invalid pub type %s", s.PubType)
+}
+
+// PublisherUID returns the user id of the one who published the funds, on behalf of whom it is on block chain
+func (funds *PubFunds) PublisherUID() string {
+	if funds.PubType == rest.PubTypeDistribute {
+		return funds.TargetUID
+	}
+
+	return funds.UID
+}
+
+// PublisherUID returns the user id of the one who published the supplies, on behalf of whom it is on block chain
+func (supplies *PubSupplies) PublisherUID() string {
+	if supplies.PubType == rest.PubTypeDistribute {
+		return supplies.TargetUID
+	}
+
+	return supplies.UID
 }
 
 // FullAddress ...
@@ -0,0 +1,150 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/shopspring/decimal"
+)
+
+// BoardMetrics defines the metrics ranked on each leaderboard
+var BoardMetrics = map[string][]string{
+	rest.BoardCharity: {
+		rest.MetricReceivedFunds,
+		rest.MetricDistributedFunds,
+		rest.MetricDistributionRatio,
+		rest.MetricReceivedSupplies,
+		rest.MetricDistributedSupplies,
+		rest.MetricDonors,
+	},
+	rest.BoardDonor: {
+		rest.MetricDonatedFunds,
+		rest.MetricDonatedSupplies,
+	},
+}
+
+// Periods defines the periods ranked on leaderboard
+var Periods = []string{rest.PeriodWeek, rest.PeriodMonth, rest.PeriodYear, rest.PeriodAll}
+
+// periodDays defines the days counted back of each period, all time if missing
+var periodDays = map[string]int{
+	rest.PeriodWeek:  7,
+	rest.PeriodMonth: 30,
+	rest.PeriodYear:  365,
+}
+
+// HasMetric checks the metric is ranked on the leaderboard
+func HasMetric(board, metric string) bool {
+	for _, v := range BoardMetrics[board] {
+		if v == metric {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPeriod checks the period is ranked on leaderboard
+func HasPeriod(period string) bool {
+	for _, v := range Periods {
+		if v == period {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PeriodSince returns the start time of the period counted back from now, the unix epoch for all time
+func PeriodSince(period string, now time.Time) time.Time {
+	days, ok := periodDays[period]
+	if !ok {
+		return time.Unix(0, 0)
+	}
+
+	return now.AddDate(0, 0, -days)
+}
+
+// MetricValue returns the value of metric of the aggregates, the distribution ratio is zero if nothing received
+func MetricValue(stat *structs.RankStat, metric string) decimal.Decimal {
+	switch metric {
+	case rest.MetricReceivedFunds:
+		return stat.ReceivedFunds
+	case rest.MetricDistributedFunds:
+		return stat.DistributedFunds
+	case rest.MetricDistributionRatio:
+		if !stat.ReceivedFunds.IsPositive() {
+			return decimal.Zero
+		}
+		return stat.DistributedFunds.DivRound(stat.ReceivedFunds, amountScale)
+	case rest.MetricReceivedSupplies:
+		return decimal.New(stat.ReceivedSupplies, 0)
+	case rest.MetricDistributedSupplies:
+		return decimal.New(stat.DistributedSupplies, 0)
+	case rest.MetricDonors:
+		return decimal.New(stat.Donors, 0)
+	case rest.MetricDonatedFunds:
+		return stat.DonatedFunds
+	case rest.MetricDonatedSupplies:
+		return decimal.New(stat.DonatedSupplies, 0)
+	}
+
+	return decimal.Zero
+}
+
+// RankSnapshots ranks the aggregates by every metric of the leaderboard, the greater value first and the
+// same value the same rank, the zero values are not ranked and at most topN items are kept for each metric
+func RankSnapshots(board, period string, stats []*structs.RankStat, refreshedAt int64, topN int) []*RankSnapshot {
+	var out []*RankSnapshot
+	for _, metric := range BoardMetrics[board] {
+		var items []*RankSnapshot
+		for _, v := range stats {
+			value := MetricValue(v, metric)
+			if !value.IsPositive() {
+				continue
+			}
+
+			items = append(items, &RankSnapshot{
+				Board:       board,
+				Period:      period,
+				Metric:      metric,
+				UID:         v.UID,
+				Name:        v.Name,
+				Value:       value,
+				RefreshedAt: refreshedAt,
+			})
+		}
+
+		sort.Slice(items, func(i, j int) bool {
+			if c := items[i].Value.Cmp(items[j].Value); c != 0 {
+				return c > 0
+			}
+			return items[i].UID < items[j].UID
+		})
+
+		for i, v := range items {
+			if topN > 0 && i >= topN {
+				items = items[:i]
+				break
+			}
+
+			v.Ranking = i + 1
+			if i > 0 && v.Value.Equal(items[i-1].Value) {
+				v.Ranking = items[i-1].Ranking
+			}
+		}
+
+		out = append(out, items...)
+	}
+
+	return out
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package models
+
+import (
+	"sort"
+
+	"github.com/csiabb/donation-service/common/rest"
+
+	"github.com/shopspring/decimal"
+)
+
+// StatDrift defines the donation statistics stored differing from the one rebuilt from publicities
+type StatDrift struct {
+	UID      string        // user id of charity
+	Stored   *DonationStat // stored statistics, nil if missing
+	Computed *DonationStat // statistics rebuilt from the confirmed publicities
+}
+
+// FundsStat returns the change of donation statistics of the target by the confirmed funds, the donated funds
+// are received and the distributed ones are distributed, nil if the funds do not count
+func FundsStat(f *PubFunds) *DonationStat {
+	if f.TargetUID == "" {
+		return nil
+	}
+
+	stat := &DonationStat{UID: f.TargetUID}
+	switch f.PubType {
+	case rest.PubTypeDonate:
+		stat.ReceivedFunds = f.Amount
+	case rest.PubTypeDistribute:
+		stat.DistributedFunds = f.Amount
+	default:
+		return nil
+	}
+
+	return stat
+}
+
+// SuppliesStat returns the change of donation statistics of the target by the confirmed supplies, nil if
+// the supplies do not count
+func SuppliesStat(s *PubSupplies) *DonationStat {
+	if s.TargetUID == "" {
+		return nil
+	}
+
+	stat := &DonationStat{UID: s.TargetUID}
+	switch s.PubType {
+	case rest.PubTypeDonate:
+		stat.ReceivedSupplies = s.Number
+	case rest.PubTypeDistribute:
+		stat.DistributedSupplies = s.Number
+	default:
+		return nil
+	}
+
+	return stat
+}
+
+// Equal checks the aggregates of the donation statistics are the same
+func (s *DonationStat) Equal(o *DonationStat) bool {
+	return s.ReceivedFunds.Equal(o.ReceivedFunds) && s.DistributedFunds.Equal(o.DistributedFunds) &&
+		s.ReceivedSupplies == o.ReceivedSupplies && s.DistributedSupplies == o.DistributedSupplies
+}
+
+// StatDrifts compares the stored donation statistics with the rebuilt ones by user id, the stored ones
+// missing in computed are expected all zero, the result is sorted by user id
+func StatDrifts(stored, computed []*DonationStat) []*StatDrift {
+	stats := make(map[string]*DonationStat)
+	for _, v := range stored {
+		stats[v.UID] = v
+	}
+
+	zero := &DonationStat{ReceivedFunds: decimal.Zero, DistributedFunds: decimal.Zero}
+	seen := make(map[string]bool)
+	var drifts []*StatDrift
+	for _, v := range computed {
+		seen[v.UID] = true
+		s, ok := stats[v.UID]
+		if !ok {
+			drifts = append(drifts, &StatDrift{UID: v.UID, Computed: v})
+			continue
+		}
+
+		if !s.Equal(v) {
+			drifts = append(drifts, &StatDrift{UID: v.UID, Stored: s, Computed: v})
+		}
+	}
+
+	for _, v := range stored {
+		if !seen[v.UID] && !v.Equal(zero) {
+			drifts = append(drifts, &StatDrift{
+				UID:      v.UID,
+				Stored:   v,
+				Computed: &DonationStat{UID: v.UID, ReceivedFunds: decimal.Zero, DistributedFunds: decimal.Zero},
+			})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].UID < drifts[j].UID })
+	return drifts
+}
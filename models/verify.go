@@ -0,0 +1,207 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/shopspring/decimal"
+)
+
+// PublicityHash returns the sha256 hash of publicity in hex
+func PublicityHash(publicity string) string {
+	h := sha256.Sum256([]byte(publicity))
+	return hex.EncodeToString(h[:])
+}
+
+// PublicityTime returns the time field of publicity
+func PublicityTime(publicity string) (int64, error) {
+	v := struct {
+		Time int64 `json:"time"`
+	}{}
+
+	if err := json.Unmarshal([]byte(publicity), &v); err != nil {
+		return 0, err
+	}
+
+	return v.Time, nil
+}
+
+// ComparePublicity compares the publicity recomputed from database with the one on block chain field by field
+func ComparePublicity(local, chain string) ([]*structs.PubVerifyField, bool, error) {
+	lm := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(local), &lm); err != nil {
+		return nil, false, fmt.Errorf("decode local publicity error, %v", err)
+	}
+
+	cm := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(chain), &cm); err != nil {
+		return nil, false, fmt.Errorf("decode block chain publicity error, %v", err)
+	}
+
+	keys := make([]string, 0)
+	for k := range lm {
+		keys = append(keys, k)
+	}
+	for k := range cm {
+		if _, ok := lm[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	match := true
+	fields := make([]*structs.PubVerifyField, 0)
+	for _, k := range keys {
+		lv, lok := lm[k]
+		cv, cok := cm[k]
+		f := &structs.PubVerifyField{
+			Field: k,
+			Match: lok && cok && reflect.DeepEqual(lv, cv),
+			Local: lv,
+			Chain: cv,
+		}
+		match = match && f.Match
+		fields = append(fields, f)
+	}
+
+	return fields, match, nil
+}
+
+// VerifyPublicity verifies the payload recomputed from record against the one on block chain,
+// the canonical payload must be identical, the hash kept with record must match the one on block chain
+func VerifyPublicity(local, chain, storedHash string, version int) (*structs.PubVerifyResp, error) {
+	fields, match, err := ComparePublicity(local, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &structs.PubVerifyResp{
+		SchemaVersion: version,
+		LocalHash:     PublicityHash(local),
+		ChainHash:     PublicityHash(chain),
+		StoredHash:    storedHash,
+		Fields:        fields,
+	}
+
+	if version >= rest.PayloadSchemaVersion && resp.LocalHash != resp.ChainHash {
+		match = false
+	}
+
+	if storedHash != "" && storedHash != resp.ChainHash {
+		match = false
+	}
+
+	resp.Match = match
+	return resp, nil
+}
+
+// VerifyBundle verifies the records exported in bundle without database or block chain
+func VerifyBundle(bundle *structs.VerifyBundle) *structs.VerifyBundleReport {
+	report := &structs.VerifyBundleReport{Results: make([]*structs.PubVerifyResp, 0)}
+	for _, v := range bundle.Records {
+		resp, err := verifyRecord(v)
+		if err != nil {
+			resp = &structs.PubVerifyResp{SchemaVersion: v.SchemaVersion, Error: err.Error()}
+		}
+
+		resp.ID = v.ID
+		resp.Type = v.Type
+		resp.BlockID = v.Receipt.BlockID
+		resp.TxID = v.Receipt.TxID
+		resp.BlockHeight = v.Receipt.BlockHeight
+
+		report.Total++
+		if resp.Match {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, resp)
+	}
+
+	return report
+}
+
+// verifyRecord recomputes the payload of record exported and verifies it against the chain receipt
+func verifyRecord(r *structs.BundleRecord) (*structs.PubVerifyResp, error) {
+	if r.Receipt.Publicity == "" {
+		return nil, fmt.Errorf("chain receipt is empty")
+	}
+
+	images := make([]*Image, 0)
+	for _, v := range r.ProofImages {
+		images = append(images, &Image{URL: v.URL, Hash: v.Hash})
+	}
+
+	var local string
+	var err error
+	switch r.Type {
+	case rest.DonatedTypeFunds:
+		var amount decimal.Decimal
+		if amount, err = decimal.NewFromString(r.Amount); err != nil {
+			return nil, fmt.Errorf("invalid amount %s, %v", r.Amount, err)
+		}
+
+		detail := &FundsDetail{
+			Funds: PubFunds{
+				ID:                r.ID,
+				UID:               r.UID,
+				DonorName:         r.DonorName,
+				AidName:           r.AidName,
+				TargetUID:         r.TargetUID,
+				TargetName:        r.TargetName,
+				TargetBankCardNum: r.TargetBankCardNum,
+				PubType:           r.PubType,
+				Amount:            amount,
+				SchemaVer:         r.SchemaVersion,
+				CreatedAt:         time.Unix(r.Time, 0),
+			},
+			ProofImages: images,
+		}
+		local, err = detail.Publicity(r.Time)
+	case rest.DonatedTypeSupplies:
+		detail := &SuppliesDetail{
+			Supplies: PubSupplies{
+				ID:         r.ID,
+				WayBillNum: r.WayBillNum,
+				UID:        r.UID,
+				DonorName:  r.DonorName,
+				AidName:    r.AidName,
+				TargetUID:  r.TargetUID,
+				TargetName: r.TargetName,
+				PubType:    r.PubType,
+				Name:       r.Name,
+				Number:     r.Number,
+				Unit:       r.Unit,
+				SchemaVer:  r.SchemaVersion,
+				CreatedAt:  time.Unix(r.Time, 0),
+			},
+			BillingAddr:  Address{Address: r.BillingAddress},
+			ShippingAddr: Address{Address: r.ShippingAddress},
+			ProofImages:  images,
+		}
+		local, err = detail.Publicity(r.Time)
+	default:
+		return nil, fmt.Errorf("invalid type %s", r.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyPublicity(local, r.Receipt.Publicity, r.PayloadHash, r.SchemaVersion)
+}
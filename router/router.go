@@ -40,7 +40,25 @@ var (
 
 const (
 	// acc
-	urlAccLoginWXApp = "acc/login/wxapp"
+	urlAccLoginWXApp   = "acc/login/wxapp"
+	urlAccTokenRefresh = "acc/token/refresh"
+	urlAccWXUserInfo   = "acc/wx/userinfo"
+	urlAccWXPhone      = "acc/wx/phone"
+	urlAccSmsCode      = "acc/sms/code"
+	urlAccRegister     = "acc/register"
+	urlAccLogin        = "acc/login"
+	urlAccLink         = "acc/link"
+	urlAccProfile      = "acc/profile"
+	urlAccBank         = "acc/bank"
+	urlAccPubList      = "acc/pub/list"
+	urlAccKyc          = "acc/kyc"
+	urlAccKycPerson    = "acc/kyc/person"
+	urlAccKycOrg       = "acc/kyc/org"
+	urlAccKycList      = "acc/kyc/list"
+	urlAccKycReview    = "acc/kyc/review"
+	urlAccApply        = "acc/charity/apply"
+	urlAccApplyList    = "acc/charity/apply/list"
+	urlAccApplyReview  = "acc/charity/apply/review"
 
 	// block chain
 	urlBCCallBack = "bc/cb"
@@ -51,10 +69,15 @@ const (
 	urlPubSupplies       = "pub/supplies"
 	urlPubSuppliesDetail = "pub/supplies/detail"
 	urlPubList           = "pub/list"
+	urlPubStatus         = "pub/status"
+	urlPubVerify         = "pub/verify"
 
 	// org
 	urlOrgCharities       = "org/charities"
 	urlOrgCharitiesDetail = "org/charities/detail"
+	urlOrgCharityProfile  = "org/charity/profile"
+	urlOrgCharityHistory  = "org/charity/profile/history"
+	urlOrgLeaderboard     = "org/leaderboard"
 
 	// image
 	urlImageUpload = "image/upload"
@@ -138,9 +161,46 @@ func (r *Router) SetupRouter() *gin.Engine {
 
 		// account
 		apiPrefix.POST(urlAccLoginWXApp, r.accHandler.LoginWXApp) // 微信登录
+		apiPrefix.POST(urlAccTokenRefresh, r.accHandler.RefreshToken)
+		apiPrefix.POST(urlAccSmsCode, r.accHandler.SendSmsCode)
+		apiPrefix.POST(urlAccRegister, r.accHandler.Register)
+		apiPrefix.POST(urlAccLogin, r.accHandler.Login)
 
 		// block chain
-		apiPrefix.POST(urlBCCallBack, r.bcHandler.BlockChainCallBack)
+		apiPrefix.POST(urlBCCallBack, middleware.BCCallBackAuth(r.context), r.bcHandler.BlockChainCallBack)
+
+		// public proof of publicity
+		apiPrefix.GET(urlPubVerify, r.pubHandler.PubVerify)
+
+		// the routes below require the access token issued on login
+		apiPrefix.Use(middleware.TokenAuth(r.context))
+
+		// account of wechat, decrypted with the session key kept on login
+		apiPrefix.POST(urlAccWXUserInfo, r.accHandler.BindWXUserInfo)
+		apiPrefix.POST(urlAccWXPhone, r.accHandler.BindWXPhone)
+
+		// login of app or web for the account
+		apiPrefix.POST(urlAccLink, r.accHandler.LinkAccount)
+
+		// profile of the account
+		apiPrefix.GET(urlAccProfile, r.accHandler.GetProfile)
+		apiPrefix.PUT(urlAccProfile, r.accHandler.UpdateProfile)
+		apiPrefix.GET(urlAccBank, r.accHandler.GetBank)
+		apiPrefix.PUT(urlAccBank, r.accHandler.UpdateBank)
+		apiPrefix.GET(urlAccPubList, r.accHandler.AccPubList)
+
+		// kyc of the account, reviewed by admin
+		apiPrefix.GET(urlAccKyc, r.accHandler.GetKyc)
+		apiPrefix.POST(urlAccKycPerson, r.accHandler.SubmitPersonKyc)
+		apiPrefix.POST(urlAccKycOrg, r.accHandler.SubmitOrgKyc)
+		apiPrefix.GET(urlAccKycList, r.accHandler.KycList)
+		apiPrefix.POST(urlAccKycReview, r.accHandler.ReviewKyc)
+
+		// application of the account to become charity, reviewed by admin
+		apiPrefix.GET(urlAccApply, r.accHandler.GetCharityApply)
+		apiPrefix.POST(urlAccApply, r.accHandler.ApplyCharity)
+		apiPrefix.GET(urlAccApplyList, r.accHandler.CharityApplyList)
+		apiPrefix.POST(urlAccApplyReview, r.accHandler.ReviewCharityApply)
 
 		// publicity
 		apiPrefix.POST(urlPubFunds, r.pubHandler.ReceiveFunds)
@@ -150,10 +210,14 @@ func (r *Router) SetupRouter() *gin.Engine {
 		apiPrefix.GET(urlPubSupplies, r.pubHandler.QuerySupplies)
 		apiPrefix.GET(urlPubSuppliesDetail, r.pubHandler.QuerySuppliesDetail)
 		apiPrefix.GET(urlPubList, r.pubHandler.PubUserList)
+		apiPrefix.GET(urlPubStatus, r.pubHandler.PubStatus)
 
 		// org
 		apiPrefix.GET(urlOrgCharities, r.orgHandler.QueryOrgCharities)
 		apiPrefix.GET(urlOrgCharitiesDetail, r.orgHandler.QueryOrgCharitiesDetail)
+		apiPrefix.PUT(urlOrgCharityProfile, r.orgHandler.UpdateCharityProfile)
+		apiPrefix.GET(urlOrgCharityHistory, r.orgHandler.CharityProfileHistory)
+		apiPrefix.GET(urlOrgLeaderboard, r.orgHandler.Leaderboard)
 
 		// image
 		apiPrefix.POST(urlImageUpload, r.imageHandler.Upload)
@@ -19,6 +19,7 @@ import (
 	"github.com/csiabb/donation-service/config"
 	srvctx "github.com/csiabb/donation-service/context"
 	"github.com/csiabb/donation-service/router"
+	"github.com/csiabb/donation-service/worker"
 
 	"github.com/gin-gonic/gin"
 )
@@ -39,6 +40,9 @@ type ServerImpl struct {
 	version    *metadata.Version
 	httpSrv    *gin.Engine
 	httpRouter *router.Router
+	publisher  *worker.Publisher
+	reconciler *worker.Reconciler
+	ranker     *worker.Ranker
 	ShutdownCh <-chan struct{}
 	myName     string
 	serviceID  string
@@ -48,6 +52,17 @@ type ServerImpl struct {
 func (s *ServerImpl) Start() (err error) {
 	logger.Infof("Starting %s server ...", s.version.ProgramName)
 
+	// start to publish the outbox to block chain
+	if s.publisher != nil {
+		s.publisher.Start()
+		s.reconciler.Start()
+	}
+
+	// start to refresh the leaderboard snapshots
+	if s.ranker != nil {
+		s.ranker.Start()
+	}
+
 	// start to serve http connections
 	address := fmt.Sprintf("%s:%d", s.config.ServerGeneral.Host, s.config.ServerGeneral.Port)
 	logger.Infof("starting server on %s", address)
@@ -62,6 +77,17 @@ func (s *ServerImpl) Shutdown() {
 	_, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// stop refreshing the leaderboard snapshots
+	if s.ranker != nil {
+		s.ranker.Stop()
+	}
+
+	// stop publishing to block chain
+	if s.publisher != nil {
+		s.reconciler.Stop()
+		s.publisher.Stop()
+	}
+
 	logger.Info("service shutted down successfully.")
 }
 
@@ -90,6 +116,29 @@ func (s *ServerImpl) init() (err error) {
 		return err
 	}
 
+	// the workers run on database, skipped if it is disabled
+	if nil == s.context.DBStorage {
+		logger.Warning("database is disabled, block chain publisher, reconciler and leaderboard ranker are skipped")
+	} else {
+		s.publisher, err = worker.NewPublisher(s.context)
+		if nil != err {
+			logger.Errorf("Initialize block chain publisher error: %v", err)
+			return err
+		}
+
+		s.reconciler, err = worker.NewReconciler(s.context, s.publisher)
+		if nil != err {
+			logger.Errorf("Initialize block chain reconciler error: %v", err)
+			return err
+		}
+
+		s.ranker, err = worker.NewRanker(s.context)
+		if nil != err {
+			logger.Errorf("Initialize leaderboard ranker error: %v", err)
+			return err
+		}
+	}
+
 	//Init the rest http service
 	if err = s.httpSrvInit(); err != nil {
 		logger.Errorf("Failed to Initialize %s restful API: %s", s.version.ProgramName, err)
@@ -7,8 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package service
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
 	"github.com/csiabb/donation-service/common/metadata"
+	"github.com/csiabb/donation-service/components/wx/fakewx"
 	"github.com/csiabb/donation-service/config"
+	srvctx "github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+	"github.com/csiabb/donation-service/worker"
 )
 
 // Server interface ...
@@ -43,3 +54,138 @@ func NewServer(c *config.SrvcCfg, sVer *metadata.Version) (Server, error) {
 	}
 	return DonationServer, nil
 }
+
+// Reconcile re-publishes the publicities stuck without block chain call back one-shot
+func Reconcile(c *config.SrvcCfg) error {
+	context := srvctx.GetServerContext()
+	context.Config = c
+	if err := context.Init(); err != nil {
+		return err
+	}
+
+	if nil == context.DBStorage {
+		return fmt.Errorf("database is disabled")
+	}
+
+	publisher, err := worker.NewPublisher(context)
+	if err != nil {
+		return err
+	}
+
+	reconciler, err := worker.NewReconciler(context, publisher)
+	if err != nil {
+		return err
+	}
+
+	num, err := reconciler.Reconcile()
+	if err != nil {
+		return err
+	}
+	logger.Infof("re-published %d stuck publicities", num)
+
+	// publish the pending ones as well since no publisher is running
+	return publisher.Drain()
+}
+
+// RecomputeStats rebuilds the donation statistics from the confirmed funds and supplies one-shot, prints the drift
+// of the stored ones to w and overwrites them unless dry run, returns the num of statistics drifted
+func RecomputeStats(c *config.SrvcCfg, w io.Writer, dryRun bool) (int, error) {
+	context := srvctx.GetServerContext()
+	context.Config = c
+	if err := context.Init(); err != nil {
+		return 0, err
+	}
+
+	if nil == context.DBStorage {
+		return 0, fmt.Errorf("database is disabled")
+	}
+
+	stored, err := context.DBStorage.QueryDonationStats()
+	if err != nil {
+		return 0, err
+	}
+
+	computed, err := context.DBStorage.SumDonationStats()
+	if err != nil {
+		return 0, err
+	}
+
+	drifts := models.StatDrifts(stored, computed)
+	for _, v := range drifts {
+		if v.Stored == nil {
+			fmt.Fprintf(w, "MISSING %s received funds %s supplies %d, distributed funds %s supplies %d\n", v.UID,
+				v.Computed.ReceivedFunds, v.Computed.ReceivedSupplies, v.Computed.DistributedFunds, v.Computed.DistributedSupplies)
+			continue
+		}
+
+		fmt.Fprintf(w, "DRIFT %s\n", v.UID)
+		fmt.Fprintf(w, "    received funds: stored %s, computed %s\n", v.Stored.ReceivedFunds, v.Computed.ReceivedFunds)
+		fmt.Fprintf(w, "    received supplies: stored %d, computed %d\n", v.Stored.ReceivedSupplies, v.Computed.ReceivedSupplies)
+		fmt.Fprintf(w, "    distributed funds: stored %s, computed %s\n", v.Stored.DistributedFunds, v.Computed.DistributedFunds)
+		fmt.Fprintf(w, "    distributed supplies: stored %d, computed %d\n", v.Stored.DistributedSupplies, v.Computed.DistributedSupplies)
+	}
+	fmt.Fprintf(w, "%d statistics, %d drifted\n", len(stored), len(drifts))
+
+	if dryRun || len(drifts) == 0 {
+		return len(drifts), nil
+	}
+
+	tx := context.DBStorage.GetDBTransaction()
+	for _, v := range drifts {
+		if err := context.DBStorage.SaveDonationStat(tx, v.Computed); err != nil {
+			context.DBStorage.DBTransactionRollback(tx)
+			return len(drifts), err
+		}
+	}
+	context.DBStorage.DBTransactionCommit(tx)
+	logger.Infof("rebuilt %d drifted donation statistics", len(drifts))
+
+	return len(drifts), nil
+}
+
+// Verify verifies the records exported in bundle file offline and prints the report to w,
+// returns false if any record fails
+func Verify(bundleFile string, w io.Writer) (bool, error) {
+	data, err := ioutil.ReadFile(bundleFile)
+	if err != nil {
+		return false, err
+	}
+
+	bundle := &structs.VerifyBundle{}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return false, fmt.Errorf("decode bundle error, %v", err)
+	}
+
+	report := models.VerifyBundle(bundle)
+	for _, v := range report.Results {
+		if v.Match {
+			fmt.Fprintf(w, "PASS %s %s block %s tx %s hash %s\n", v.Type, v.ID, v.BlockID, v.TxID, v.ChainHash)
+			continue
+		}
+
+		fmt.Fprintf(w, "FAIL %s %s block %s tx %s\n", v.Type, v.ID, v.BlockID, v.TxID)
+		if v.Error != "" {
+			fmt.Fprintf(w, "    error: %s\n", v.Error)
+			continue
+		}
+
+		fmt.Fprintf(w, "    local hash: %s\n    chain hash: %s\n", v.LocalHash, v.ChainHash)
+		if v.StoredHash != "" && v.StoredHash != v.ChainHash {
+			fmt.Fprintf(w, "    stored hash: %s\n", v.StoredHash)
+		}
+		for _, f := range v.Fields {
+			if !f.Match {
+				fmt.Fprintf(w, "    %s: local %v, chain %v\n", f.Field, f.Local, f.Chain)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d records, %d passed, %d failed\n", report.Total, report.Passed, report.Failed)
+
+	return report.Failed == 0, nil
+}
+
+// FakeWX serves the local stand-in of wechat api for the app configured, for development only
+func FakeWX(c *config.SrvcCfg, addr string) error {
+	logger.Warningf("serving fake wechat api of app %s on %s, for development only", c.WXCfg.AppID, addr)
+	return http.ListenAndServe(addr, fakewx.NewServer(c.WXCfg.AppID, c.WXCfg.Secret))
+}
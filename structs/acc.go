@@ -19,11 +19,121 @@ type LoginRequest struct {
 	ID          int    `json:"id"`           // id of wechat app user
 	AppID       string `json:"app_id"`       // app id
 	Remark      string `json:"remark"`       // user description
+	Source      string `json:"source"`       // client of user, app or web
 }
 
 // LoginResp defines the response of user registration
 type LoginResp struct {
-	UID string `json:"uid"` // user id
+	UID          string `json:"uid"`           // user id
+	AccessToken  string `json:"access_token"`  // token to access api
+	RefreshToken string `json:"refresh_token"` // token to refresh the access token
+	ExpiresIn    int64  `json:"expires_in"`    // seconds the access token is valid
+}
+
+// RefreshTokenRequest defines the request of refreshing access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"` // refresh token issued on login
+}
+
+// SmsCodeRequest defines the request of sending sms code to phone
+type SmsCodeRequest struct {
+	Phone string `json:"phone" binding:"required"` // phone to receive the code
+}
+
+// SmsCodeResp defines the response of sending sms code to phone
+type SmsCodeResp struct {
+	Phone     string `json:"phone"`      // phone the code sent to
+	ExpiresIn int64  `json:"expires_in"` // seconds the code is valid
+}
+
+// LinkAccountRequest defines the request of setting the phone and password to login the account from app or web
+type LinkAccountRequest struct {
+	Phone    string `json:"phone" binding:"required"`    // phone to login
+	SmsCode  string `json:"sms_code" binding:"required"` // sms code sent to the phone
+	Password string `json:"password"`                    // password to login, unchanged if empty
+}
+
+// LinkAccountResp defines the response of setting the phone and password to login the account
+type LinkAccountResp struct {
+	UID   string `json:"uid"`   // user id
+	Phone string `json:"phone"` // phone to login
+}
+
+// ProfileResp defines the profile of account
+type ProfileResp struct {
+	UID       string `json:"uid"`        // user id
+	Access    string `json:"access"`     // user name
+	NickName  string `json:"nickname"`   // nick name
+	Avatar    string `json:"avatar"`     // avatar url
+	UserType  string `json:"user_type"`  // user type
+	Phone     string `json:"phone"`      // user phone
+	Email     string `json:"email"`      // user email
+	Remark    string `json:"remark"`     // user description
+	KycStatus string `json:"kyc_status"` // kyc status
+	DID       string `json:"did"`        // did on block chain
+	Source    string `json:"source"`     // where the account registered, wx, app or web
+	CreatedAt int64  `json:"created_at"` // created time
+}
+
+// UpdateProfileRequest defines the request of updating the profile of account, the empty fields are unchanged
+type UpdateProfileRequest struct {
+	NickName string `json:"nickname"` // nick name
+	Avatar   string `json:"avatar"`   // avatar url
+	Phone    string `json:"phone"`    // user phone, verified by sms code
+	SmsCode  string `json:"sms_code"` // sms code sent to the phone
+	Remark   string `json:"remark"`   // user description
+}
+
+// BankRequest defines the request of updating the bank details of account
+type BankRequest struct {
+	Bank        string `json:"bank" binding:"required"`          // bank name
+	BankCardNum string `json:"bank_card_num" binding:"required"` // bank card num
+	TaxID       string `json:"tax_id"`                           // tax id
+}
+
+// BankResp defines the bank details of account, the card num and tax id are masked
+type BankResp struct {
+	UID         string `json:"uid"`           // user id
+	Bank        string `json:"bank"`          // bank name
+	BankCardNum string `json:"bank_card_num"` // masked bank card num
+	TaxID       string `json:"tax_id"`        // masked tax id
+}
+
+// AccPubRequest defines the request of the publicity history of account
+type AccPubRequest struct {
+	PubType     string `form:"pub_type"`     // publicity type, all if empty
+	PageNum     int    `form:"page_num"`     // page num
+	PageLimit   int    `form:"page_limit"`   // page limit
+	StartTime   int64  `form:"start_time"`   // start time
+	EndTime     int64  `form:"end_time"`     // end time
+	ChainStatus string `form:"chain_status"` // lifecycle status on block chain
+}
+
+// WXUserInfoRequest defines the request of binding the user info of wechat
+type WXUserInfoRequest struct {
+	RawData       string `json:"raw_data"`                          // raw data of user info
+	Signature     string `json:"signature"`                         // signature of raw data, not checked if empty
+	EncryptedData string `json:"encrypted_data" binding:"required"` // encrypted user info
+	IV            string `json:"iv" binding:"required"`             // iv of encrypted data
+}
+
+// WXUserInfoResp defines the response of binding the user info of wechat
+type WXUserInfoResp struct {
+	UID      string `json:"uid"`      // user id
+	NickName string `json:"nickname"` // nick name
+	Avatar   string `json:"avatar"`   // avatar url
+}
+
+// WXPhoneRequest defines the request of binding the phone number of wechat
+type WXPhoneRequest struct {
+	EncryptedData string `json:"encrypted_data" binding:"required"` // encrypted phone number
+	IV            string `json:"iv" binding:"required"`             // iv of encrypted data
+}
+
+// WXPhoneResp defines the response of binding the phone number of wechat
+type WXPhoneResp struct {
+	UID   string `json:"uid"`   // user id
+	Phone string `json:"phone"` // verified phone number
 }
 
 //CheckFingerPrintRequest ...
@@ -6,11 +6,70 @@ SPDX-License-Identifier: Apache-2.0
 
 package structs
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // QueryParams defines the struct of query by page params
 type QueryParams struct {
-	PageNum   int   // page num
-	PageLimit int   // page limit
-	StartTime int64 // start time
-	EndTime   int64 // end time
-	Total     int64 // total number of query results
+	PageNum     int     // page num
+	PageLimit   int     // page limit
+	StartTime   int64   // start time
+	EndTime     int64   // end time
+	ChainStatus string  // lifecycle status on block chain
+	Total       int64   // total number of query results
+	Cursor      *Cursor // position after which the page starts, page num is ignored if set
+	NextCursor  *Cursor // position of the last item if the page is full, nil if no more items
+}
+
+// Cursor defines the position of item in the list ordered by time and id, the latest first
+type Cursor struct {
+	Time int64  `json:"t"` // created time of item in unix nanoseconds
+	ID   string `json:"i"` // id of item, breaks the tie of the same time
+}
+
+// NewCursor returns the cursor of item
+func NewCursor(t time.Time, id string) *Cursor {
+	return &Cursor{Time: t.UnixNano(), ID: id}
+}
+
+// At returns the created time of item
+func (c *Cursor) At() time.Time {
+	return time.Unix(0, c.Time)
+}
+
+// Encode encodes the cursor to an opaque string, empty if nil
+func (c *Cursor) Encode() string {
+	if c == nil {
+		return ""
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor decodes the opaque cursor, nil if empty
+func DecodeCursor(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+
+	if c.ID == "" || c.Time <= 0 {
+		return nil, fmt.Errorf("cursor %s invalid", s)
+	}
+
+	return c, nil
 }
@@ -130,3 +130,33 @@ type DonationImage struct {
 	URL  string `json:"url"`  // image url
 	Hash string `json:"hash"` // image hash
 }
+
+// BatchPubReq defines the request of publicity in batch
+type BatchPubReq struct {
+	UID         string   `json:"uid"`         // user id
+	Publicities []string `json:"publicities"` // publicity data
+}
+
+// BatchPubResp defines the response of publicity in batch, one result per publicity in order
+type BatchPubResp struct {
+	Code int        `json:"code"`
+	Msg  string     `json:"msg"`
+	Data []*PubResp `json:"data"`
+}
+
+// QueryPubResp defines the response of query publicity
+type QueryPubResp struct {
+	Code int              `json:"code"`
+	Msg  string           `json:"msg"`
+	Data QueryPubRespData `json:"data"`
+}
+
+// QueryPubRespData defines the publicity on block chain
+type QueryPubRespData struct {
+	ID          string `json:"id"`           // block chain id
+	UID         string `json:"uid"`          // did of the one who publish
+	Publicity   string `json:"publicity"`    // publicity data
+	TxID        string `json:"tx_id"`        // block chain tx id
+	BlockHeight int64  `json:"block_height"` // block height
+	BlockTime   int64  `json:"block_time"`   // block time
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package structs
+
+// PersonKycRequest defines the request of submitting the kyc of single person
+type PersonKycRequest struct {
+	RealName    string `json:"real_name" binding:"required"`    // real name
+	Gender      string `json:"gender"`                          // gender
+	CertType    string `json:"cert_type"`                       // the type of certification, id card if empty
+	CertNum     string `json:"cert_num" binding:"required"`     // the num of certification
+	CertExpired int64  `json:"cert_expired"`                    // the expired time of certification
+	IDCardHead  string `json:"id_card_head" binding:"required"` // url of front image of id card
+	IDCardBack  string `json:"id_card_back" binding:"required"` // url of back image of id card
+}
+
+// OrgKycRequest defines the request of submitting the kyc of organization
+type OrgKycRequest struct {
+	Name        string `json:"name" binding:"required"`         // the name of organization
+	CreditCode  string `json:"credit_code" binding:"required"`  // unified social credit code of organization
+	LegalPerson string `json:"legal_person" binding:"required"` // the legal person name of organization
+	Region      string `json:"region"`                          // the region of organization
+	CertType    string `json:"cert_type"`                       // the type of certification
+	OrgType     string `json:"org_type"`                        // the type of organization
+	Expired     int64  `json:"expired"`                         // the expired time of certification
+	IDCardHead  string `json:"id_card_head" binding:"required"` // url of front image of id card of legal person
+	IDCardBack  string `json:"id_card_back" binding:"required"` // url of back image of id card of legal person
+}
+
+// KycListRequest defines the request of querying the kyc review queue
+type KycListRequest struct {
+	KycType   string `form:"kyc_type" binding:"required"` // person or org
+	Status    string `form:"status"`                      // kyc status, pending if empty
+	PageNum   int    `form:"page_num"`                    // page num
+	PageLimit int    `form:"page_limit"`                  // page limit
+	StartTime int64  `form:"start_time"`                  // start time
+	EndTime   int64  `form:"end_time"`                    // end time
+}
+
+// KycReviewRequest defines the request of approving or rejecting the pending kyc
+type KycReviewRequest struct {
+	KycType  string `json:"kyc_type" binding:"required"` // person or org
+	ID       string `json:"id" binding:"required"`       // kyc id
+	Approved bool   `json:"approved"`                    // approved or rejected
+	Remark   string `json:"remark"`                      // remark of review, required on rejection
+}
+
+// KycItem defines the kyc of single person or organization
+type KycItem struct {
+	ID          string `json:"id"`                     // kyc id
+	UID         string `json:"uid"`                    // user id
+	KycType     string `json:"kyc_type"`               // person or org
+	Status      string `json:"status"`                 // kyc status
+	Remark      string `json:"remark"`                 // remark of review
+	RealName    string `json:"real_name,omitempty"`    // real name of person
+	Gender      string `json:"gender,omitempty"`       // gender of person
+	CertType    string `json:"cert_type,omitempty"`    // the type of certification
+	CertNum     string `json:"cert_num,omitempty"`     // the num of certification of person
+	Name        string `json:"name,omitempty"`         // the name of organization
+	CreditCode  string `json:"credit_code,omitempty"`  // unified social credit code of organization
+	LegalPerson string `json:"legal_person,omitempty"` // the legal person name of organization
+	Region      string `json:"region,omitempty"`       // the region of organization
+	OrgType     string `json:"org_type,omitempty"`     // the type of organization
+	Expired     int64  `json:"expired"`                // the expired time of certification
+	IDCardHead  string `json:"id_card_head,omitempty"` // url of front image of id card
+	IDCardBack  string `json:"id_card_back,omitempty"` // url of back image of id card
+	CreatedAt   int64  `json:"created_at"`             // submitted time
+}
+
+// KycListResp defines the response of querying the kyc review queue
+type KycListResp struct {
+	Total     int64      `json:"total"`      // total num
+	PageNum   int        `json:"page_num"`   // page num
+	PageLimit int        `json:"page_limit"` // page limit
+	Results   []*KycItem `json:"results"`    // kyc list
+}
@@ -14,10 +14,11 @@ import (
 
 // QueryOrgCharitiesRequest defines the request of query organizations
 type QueryOrgCharitiesRequest struct {
-	PageNum   int   `form:"page_num"`   // page num
-	PageLimit int   `form:"page_limit"` // page limit
-	StartTime int64 `form:"start_time"` // start time
-	EndTime   int64 `form:"end_time"`   // end time
+	PageNum   int    `form:"page_num"`   // page num
+	PageLimit int    `form:"page_limit"` // page limit
+	StartTime int64  `form:"start_time"` // start time
+	EndTime   int64  `form:"end_time"`   // end time
+	Cursor    string `form:"cursor"`     // opaque cursor of the last item of previous page, page num is ignored if set
 }
 
 // OrgCharitiesItems defines the struct of organization item
@@ -41,12 +42,13 @@ func (ois *OrgCharitiesItems) ConvertTime() {
 
 // QueryOrgCharitiesResp defines the response of organizations
 type QueryOrgCharitiesResp struct {
-	PageNum   int                  `json:"page_num"`   // page num
-	PageLimit int                  `json:"page_limit"` // page limit
-	StartTime int64                `json:"start_time"` // start time
-	EndTime   int64                `json:"end_time"`   // end time
-	Total     int64                `json:"total"`      // total number of query result
-	Results   []*OrgCharitiesItems `json:"results"`    // orgs items
+	PageNum    int                  `json:"page_num"`    // page num
+	PageLimit  int                  `json:"page_limit"`  // page limit
+	StartTime  int64                `json:"start_time"`  // start time
+	EndTime    int64                `json:"end_time"`    // end time
+	Total      int64                `json:"total"`       // total number of query result
+	NextCursor string               `json:"next_cursor"` // opaque cursor of the next page, empty if no more items
+	Results    []*OrgCharitiesItems `json:"results"`     // orgs items
 }
 
 // OrgCharitiesDetailRequest defines the request of query charities detail
@@ -56,26 +58,142 @@ type OrgCharitiesDetailRequest struct {
 
 // OrgCharitiesDetailItem defines the struct of charities detail item
 type OrgCharitiesDetailItem struct {
-	UID         string `json:"uid"`           // user id of the one who donate
-	URL         string `json:"url"`           // image url
-	NickName    string `json:"nick_name"`     // nick name
-	Country     string `json:"country"`       // country
-	Province    string `json:"province"`      // province
-	City        string `json:"city"`          // city
-	District    string `json:"district"`      // district
-	Address     string `json:"address"`       // detail address
-	Phone       string `json:"phone"`         // phone num
-	BankCardNum string `json:"bank_card_num"` // bank card num
-	Remark      string `json:"remark"`        // remark
+	UID                 string          `json:"uid"`                  // user id of the one who donate
+	URL                 string          `json:"url"`                  // image url
+	NickName            string          `json:"nick_name"`            // nick name
+	Country             string          `json:"country"`              // country
+	Province            string          `json:"province"`             // province
+	City                string          `json:"city"`                 // city
+	District            string          `json:"district"`             // district
+	Address             string          `json:"address"`              // detail address
+	ZipCode             string          `json:"zip_code"`             // zip code
+	Phone               string          `json:"phone"`                // contact phone, the phone of account if not set
+	Bank                string          `json:"bank"`                 // bank name
+	BankCardNum         string          `json:"bank_card_num"`        // bank card num
+	Remark              string          `json:"remark"`               // remark
+	ReceivedFunds       decimal.Decimal `json:"received_funds"`       // receiving funds
+	ReceivedSupplies    int64           `json:"received_supplies"`    // receiving supplies
+	DistributedFunds    decimal.Decimal `json:"distributed_funds"`    // distributing funds
+	DistributedSupplies int64           `json:"distributed_supplies"` // distributing supplies
 }
 
 // OrgCharitiesDetailResp defines the response of charities detail
 type OrgCharitiesDetailResp struct {
-	UID         string `json:"uid"`           // user id of the one who donate
-	URL         string `json:"url"`           // image url
-	NickName    string `json:"nick_name"`     // nick name
-	Address     string `json:"address"`       // detail address
-	Phone       string `json:"phone"`         // phone num
+	UID                 string          `json:"uid"`                  // user id of the one who donate
+	URL                 string          `json:"url"`                  // image url
+	NickName            string          `json:"nick_name"`            // nick name
+	Address             string          `json:"address"`              // detail address
+	Phone               string          `json:"phone"`                // phone num
+	Bank                string          `json:"bank"`                 // bank name
+	BankCardNum         string          `json:"bank_card_num"`        // bank card num
+	Remark              string          `json:"remark"`               // remark
+	Gallery             []string        `json:"gallery"`              // gallery image urls
+	ReceivedFunds       decimal.Decimal `json:"received_funds"`       // receiving funds
+	ReceivedSupplies    int64           `json:"received_supplies"`    // receiving supplies
+	DistributedFunds    decimal.Decimal `json:"distributed_funds"`    // distributing funds
+	DistributedSupplies int64           `json:"distributed_supplies"` // distributing supplies
+}
+
+// CharityAddress defines the registration address of charity
+type CharityAddress struct {
+	Country  string `json:"country"`                    // country
+	Province string `json:"province"`                   // province
+	City     string `json:"city"`                       // city
+	District string `json:"district"`                   // district
+	Address  string `json:"address" binding:"required"` // detail address
+	ZipCode  string `json:"zip_code"`                   // zip code
+}
+
+// UpdateCharityProfileRequest defines the request of updating the profile of charity, the empty fields are unchanged
+type UpdateCharityProfileRequest struct {
+	Description  string          `json:"description"`   // description of charity
+	ContactPhone string          `json:"contact_phone"` // public contact phone
+	Address      *CharityAddress `json:"address"`       // registration address
+	Logo         string          `json:"logo"`          // logo url
+	Gallery      []string        `json:"gallery"`       // gallery image urls in order, unchanged if null, cleared if empty
+	Bank         string          `json:"bank"`          // bank name of public bank account
+	BankCardNum  string          `json:"bank_card_num"` // bank card num of public bank account
+}
+
+// ProfileHistoryRequest defines the request of querying the change history of charity profile
+type ProfileHistoryRequest struct {
+	PageNum   int   `form:"page_num"`   // page num
+	PageLimit int   `form:"page_limit"` // page limit
+	StartTime int64 `form:"start_time"` // start time
+	EndTime   int64 `form:"end_time"`   // end time
+}
+
+// ProfileChangeItem defines the change of one field of charity profile
+type ProfileChangeItem struct {
+	Field     string `json:"field"`      // the field changed
+	OldValue  string `json:"old_value"`  // value before changed
+	NewValue  string `json:"new_value"`  // value after changed
+	CreatedAt int64  `json:"created_at"` // changed time
+}
+
+// ProfileHistoryResp defines the response of querying the change history of charity profile
+type ProfileHistoryResp struct {
+	Total     int64                `json:"total"`      // total number of query result
+	PageNum   int                  `json:"page_num"`   // page num
+	PageLimit int                  `json:"page_limit"` // page limit
+	Results   []*ProfileChangeItem `json:"results"`    // changes, the latest first
+}
+
+// CharityApplyRequest defines the request of applying to become charity
+type CharityApplyRequest struct {
+	Name        string `json:"name" binding:"required"`          // the name of charity
+	Logo        string `json:"logo" binding:"required"`          // logo url
+	Bank        string `json:"bank" binding:"required"`          // bank name
+	BankCardNum string `json:"bank_card_num" binding:"required"` // bank card num
+	Description string `json:"description" binding:"required"`   // description of charity
+	Country     string `json:"country"`                          // country of registration address
+	Province    string `json:"province"`                         // province of registration address
+	City        string `json:"city"`                             // city of registration address
+	District    string `json:"district"`                         // district of registration address
+	Address     string `json:"address" binding:"required"`       // detail registration address
+	ZipCode     string `json:"zip_code"`                         // zip code of registration address
+}
+
+// CharityApplyListRequest defines the request of querying the applications waiting for review
+type CharityApplyListRequest struct {
+	Status    string `form:"status"`     // application status, pending if empty
+	PageNum   int    `form:"page_num"`   // page num
+	PageLimit int    `form:"page_limit"` // page limit
+	StartTime int64  `form:"start_time"` // start time
+	EndTime   int64  `form:"end_time"`   // end time
+}
+
+// CharityApplyReviewRequest defines the request of approving or rejecting the pending application
+type CharityApplyReviewRequest struct {
+	ID       string `json:"id" binding:"required"` // application id
+	Approved bool   `json:"approved"`              // approved or rejected
+	Remark   string `json:"remark"`                // remark of review, required on rejection
+}
+
+// CharityApplyItem defines the application to become charity
+type CharityApplyItem struct {
+	ID          string `json:"id"`            // application id
+	UID         string `json:"uid"`           // user id
+	Name        string `json:"name"`          // the name of charity
+	Logo        string `json:"logo"`          // logo url
+	Bank        string `json:"bank"`          // bank name
 	BankCardNum string `json:"bank_card_num"` // bank card num
-	Remark      string `json:"remark"`        // remark
+	Description string `json:"description"`   // description of charity
+	Country     string `json:"country"`       // country of registration address
+	Province    string `json:"province"`      // province of registration address
+	City        string `json:"city"`          // city of registration address
+	District    string `json:"district"`      // district of registration address
+	Address     string `json:"address"`       // detail registration address
+	ZipCode     string `json:"zip_code"`      // zip code of registration address
+	Status      string `json:"status"`        // application status
+	Remark      string `json:"remark"`        // remark of review
+	CreatedAt   int64  `json:"created_at"`    // submitted time
+}
+
+// CharityApplyListResp defines the response of querying the applications waiting for review
+type CharityApplyListResp struct {
+	Total     int64               `json:"total"`      // total number of query result
+	PageNum   int                 `json:"page_num"`   // page num
+	PageLimit int                 `json:"page_limit"` // page limit
+	Results   []*CharityApplyItem `json:"results"`    // applications
 }
@@ -16,10 +16,9 @@ import (
 
 // ReceiveFundsRequest defines the request of receiving funds
 type ReceiveFundsRequest struct {
-	UID               string                  `json:"uid" binding:"required"`                  // user id of the one who donate
-	DonorUID          string                  `json:"donor_uid" binding:"required"`            // user did
+	UID               string                  `json:"uid"`                                     // user id of the one who publishes, set by access token
 	DonorName         string                  `json:"donor_name" binding:"required"`           // user name of the one who donate
-	UserType          string                  `json:"user_type" binding:"required"`            // user type
+	UserType          string                  `json:"user_type"`                               // user type
 	TargetUID         string                  `json:"target_uid" binding:"required"`           // user id of charity
 	TargetName        string                  `json:"target_name" binding:"required"`          // user name of the one who receive donation
 	TargetBankCardNum string                  `json:"target_bank_card_num" binding:"required"` // target bank card number
@@ -28,47 +27,66 @@ type ReceiveFundsRequest struct {
 	Amount            decimal.Decimal         `json:"amount" binding:"required"`               // pay amount
 	Remark            string                  `json:"remark"`                                  // remark text
 	PubProofImage     []*PubProofImageRequest `json:"proof_images" binding:"required"`         // images of proof
+	Soter             *SoterRequest           `json:"soter"`                                   // soter signature, required for high-value distribution
+}
+
+// SoterRequest defines the soter biometric signature of wechat
+type SoterRequest struct {
+	JSONString    string `json:"json_string" binding:"required"`    // signed json string returned by soter
+	JSONSignature string `json:"json_signature" binding:"required"` // signature of json string
 }
 
 // ReceiveFundsResp defines the response of receiving funds
 type ReceiveFundsResp struct {
 	FundsID string `json:"funds_id"` // funds id
+	Status  string `json:"status"`   // status of publicity on block chain
 }
 
 // GetUIDByFundsReq implement get funds uid
 func (rsr *ReceiveFundsRequest) GetUIDByFundsReq() string {
 	switch rsr.PubType {
-	case rest.PubTypeDonate:
+	case rest.PubTypeDonate, rest.PubTypeReceive:
 		return rsr.UID
 	case rest.PubTypeDistribute:
 		return rsr.TargetUID
-	case rest.PubTypeReceive:
-		return rsr.DonorUID
 	default:
 		return ""
 	}
 }
 
+// SetUIDByFundsReq implement set the uid and user type of the one who publishes, the uid sent by
+// client is always overwritten, so is the charity of distribution unless admin distributes for it
+func (rsr *ReceiveFundsRequest) SetUIDByFundsReq(uid, userType string) {
+	rsr.UID = uid
+	rsr.UserType = userType
+	if rsr.PubType == rest.PubTypeDistribute && userType != rest.UserTypeAdmin {
+		rsr.TargetUID = uid
+	}
+}
+
 // QueryFundsRequest defines the request of query funds
 type QueryFundsRequest struct {
-	UID       string `form:"uid"`        // user id of the one who donate
-	TargetUID string `form:"target_uid"` // user id of charity
-	UserType  string `form:"user_type"`  // user type
-	PubType   string `form:"pub_type"`   // publicity type
-	PageNum   int    `form:"page_num"`   // page num
-	PageLimit int    `form:"page_limit"` // page limit
-	StartTime int64  `form:"start_time"` // start time
-	EndTime   int64  `form:"end_time"`   // end time
+	UID         string `form:"uid"`          // user id of the one who donate
+	TargetUID   string `form:"target_uid"`   // user id of charity
+	UserType    string `form:"user_type"`    // user type
+	PubType     string `form:"pub_type"`     // publicity type
+	PageNum     int    `form:"page_num"`     // page num
+	PageLimit   int    `form:"page_limit"`   // page limit
+	StartTime   int64  `form:"start_time"`   // start time
+	EndTime     int64  `form:"end_time"`     // end time
+	ChainStatus string `form:"chain_status"` // lifecycle status on block chain
+	Cursor      string `form:"cursor"`       // opaque cursor of the last item of previous page, page num is ignored if set
 }
 
 // QueryFundsResp defines the response of funds
 type QueryFundsResp struct {
-	PageNum   int                `json:"page_num"`   // page num
-	PageLimit int                `json:"page_limit"` // page limit
-	StartTime int64              `json:"start_time"` // start time
-	EndTime   int64              `json:"end_time"`   // end time
-	Total     int64              `json:"total"`      // total number of query result
-	Results   []*QueryFundsItems `json:"results"`    // funds items
+	PageNum    int                `json:"page_num"`    // page num
+	PageLimit  int                `json:"page_limit"`  // page limit
+	StartTime  int64              `json:"start_time"`  // start time
+	EndTime    int64              `json:"end_time"`    // end time
+	Total      int64              `json:"total"`       // total number of query result
+	NextCursor string             `json:"next_cursor"` // opaque cursor of the next page, empty if no more items
+	Results    []*QueryFundsItems `json:"results"`     // funds items
 }
 
 // QueryFundsItems defines the struct of funds item
@@ -91,6 +109,7 @@ type QueryFundsItems struct {
 	BlockType         string `json:"block_type"`           // block type
 	BlockHeight       int64  `json:"block_height"`         // block height
 	BlockTime         int64  `json:"block_time"`           // block time
+	ChainStatus       string `json:"chain_status"`         // lifecycle status on block chain
 	CreatedAt         int64  `json:"created_at"`           // created time
 }
 
@@ -101,8 +120,7 @@ type FundsDetailRequest struct {
 
 // ReceiveSuppliesRequest defines the struct of received supplies
 type ReceiveSuppliesRequest struct {
-	UID             string                  `json:"uid"`                              // user id
-	DonorUID        string                  `json:"donor_uid"`                        // user id of the one who donate
+	UID             string                  `json:"uid"`                              // user id of the one who publishes, set by access token
 	DonorName       string                  `json:"donor_name"`                       // user name of the one who donate
 	UserType        string                  `json:"user_type"`                        // user type
 	TargetUID       string                  `json:"target_uid" binding:"required"`    // user id of charity
@@ -119,20 +137,29 @@ type ReceiveSuppliesRequest struct {
 // GetUIDBySuppliesReq defines get the uid of who originated
 func (rsr *ReceiveSuppliesRequest) GetUIDBySuppliesReq() string {
 	switch rsr.PubType {
-	case rest.PubTypeDonate:
+	case rest.PubTypeDonate, rest.PubTypeReceive:
 		return rsr.UID
 	case rest.PubTypeDistribute:
 		return rsr.TargetUID
-	case rest.PubTypeReceive:
-		return rsr.DonorUID
 	default:
 		return ""
 	}
 }
 
+// SetUIDBySuppliesReq implement set the uid and user type of the one who publishes, the uid sent by
+// client is always overwritten, so is the charity of distribution unless admin distributes for it
+func (rsr *ReceiveSuppliesRequest) SetUIDBySuppliesReq(uid, userType string) {
+	rsr.UID = uid
+	rsr.UserType = userType
+	if rsr.PubType == rest.PubTypeDistribute && userType != rest.UserTypeAdmin {
+		rsr.TargetUID = uid
+	}
+}
+
 // ReceiveSuppliesRespItem defines the response of receiving supplies
 type ReceiveSuppliesRespItem struct {
 	SuppliesID string `json:"supplies_id"` // supplies id
+	Status     string `json:"status"`      // status of publicity on block chain
 }
 
 // SuppliesItem defines the struct item of received supplies
@@ -144,24 +171,27 @@ type SuppliesItem struct {
 
 // QuerySuppliesRequest defines the request of supplies
 type QuerySuppliesRequest struct {
-	UID       string `form:"uid"`        // user id of the one who donate
-	TargetUID string `form:"target_uid"` // user id of charity
-	UserType  string `form:"user_type"`  // user type
-	PubType   string `form:"pub_type"`   // publicity type
-	PageNum   int    `form:"page_num"`   // page num
-	PageLimit int    `form:"page_limit"` // page limit
-	StartTime int64  `form:"start_time"` // start time
-	EndTime   int64  `form:"end_time"`   // end time
+	UID         string `form:"uid"`          // user id of the one who donate
+	TargetUID   string `form:"target_uid"`   // user id of charity
+	UserType    string `form:"user_type"`    // user type
+	PubType     string `form:"pub_type"`     // publicity type
+	PageNum     int    `form:"page_num"`     // page num
+	PageLimit   int    `form:"page_limit"`   // page limit
+	StartTime   int64  `form:"start_time"`   // start time
+	EndTime     int64  `form:"end_time"`     // end time
+	ChainStatus string `form:"chain_status"` // lifecycle status on block chain
+	Cursor      string `form:"cursor"`       // opaque cursor of the last item of previous page, page num is ignored if set
 }
 
 // QuerySuppliesResp defines the response of supplies
 type QuerySuppliesResp struct {
-	PageNum   int                   `json:"page_num"`   // page num
-	PageLimit int                   `json:"page_limit"` // page limit
-	StartTime int64                 `json:"start_time"` // start time
-	EndTime   int64                 `json:"end_time"`   // end time
-	Total     int64                 `json:"total"`      // total number of query result
-	Results   []*QuerySuppliesItems `json:"results"`    // funds items
+	PageNum    int                   `json:"page_num"`    // page num
+	PageLimit  int                   `json:"page_limit"`  // page limit
+	StartTime  int64                 `json:"start_time"`  // start time
+	EndTime    int64                 `json:"end_time"`    // end time
+	Total      int64                 `json:"total"`       // total number of query result
+	NextCursor string                `json:"next_cursor"` // opaque cursor of the next page, empty if no more items
+	Results    []*QuerySuppliesItems `json:"results"`     // funds items
 }
 
 // QuerySuppliesItems defines the struct of supplies item
@@ -184,6 +214,7 @@ type QuerySuppliesItems struct {
 	BlockType   string `json:"block_type"`   // block type
 	BlockHeight int64  `json:"block_height"` // block height
 	BlockTime   int64  `json:"block_time"`   // block time
+	ChainStatus string `json:"chain_status"` // lifecycle status on block chain
 	CreatedAt   int64  `json:"created_at"`   // created time
 }
 
@@ -194,13 +225,15 @@ type SuppliesDetailRequest struct {
 
 // PubUserRequest defines the request of publicity information
 type PubUserRequest struct {
-	UserType  string `form:"user_type"`                   // user type
-	TargetUID string `form:"target_uid"`                  // user id of charity
-	PubType   string `form:"pub_type" binding:"required"` // publicity type
-	PageNum   int    `form:"page_num"`                    // page num
-	PageLimit int    `form:"page_limit"`                  // page limit
-	StartTime int64  `form:"start_time"`                  // start time
-	EndTime   int64  `form:"end_time"`                    // end time
+	UserType    string `form:"user_type"`                   // user type
+	TargetUID   string `form:"target_uid"`                  // user id of charity
+	PubType     string `form:"pub_type" binding:"required"` // publicity type
+	PageNum     int    `form:"page_num"`                    // page num
+	PageLimit   int    `form:"page_limit"`                  // page limit
+	StartTime   int64  `form:"start_time"`                  // start time
+	EndTime     int64  `form:"end_time"`                    // end time
+	ChainStatus string `form:"chain_status"`                // lifecycle status on block chain
+	Cursor      string `form:"cursor"`                      // opaque cursor of the last item of previous page, page num is ignored if set
 }
 
 // PubUserResp defines the response of publicity information
@@ -210,8 +243,9 @@ type PubUserResp struct {
 	StartTime   int64          `json:"start_time"`   // start time
 	EndTime     int64          `json:"end_time"`     // end time
 	Total       int64          `json:"total"`        // total number of query result
-	SuppliesNum int64          `json:"supplies_num"` // total number of query supplies
-	FundsNum    int64          `json:"funds_num"`    // total number of query funds
+	SuppliesNum int64          `json:"supplies_num"` // number of supplies in results
+	FundsNum    int64          `json:"funds_num"`    // number of funds in results
+	NextCursor  string         `json:"next_cursor"`  // opaque cursor of the next page, empty if no more items
 	Results     []*PubUserItem `json:"results"`      // funds items
 }
 
@@ -237,6 +271,7 @@ type PubUserItem struct {
 	BlockType   string    `json:"block_type"`   // block type
 	BlockHeight int64     `json:"block_height"` // block height
 	BlockTime   int64     `json:"block_time"`   // block time
+	ChainStatus string    `json:"chain_status"` // lifecycle status on block chain
 	CreatedAt   int64     `json:"created_at"`   // created time
 	Time        time.Time `json:"-"`            // time
 }
@@ -300,3 +335,99 @@ type PubProofImageResp struct {
 	Index  string `json:"index"`  // image index
 	Format string `json:"format"` // image file format
 }
+
+// PubStatusRequest defines the request of query publicity status on block chain
+type PubStatusRequest struct {
+	ID   string `form:"id" binding:"required"` // funds or supplies id
+	Wait int    `form:"wait"`                  // seconds to wait for the final status
+}
+
+// PubStatusResp defines the publicity status on block chain
+type PubStatusResp struct {
+	ID          string `json:"id"`           // funds or supplies id
+	Type        string `json:"type"`         // funds or supplies
+	Status      string `json:"status"`       // pending, published, confirmed or failed
+	BlockID     string `json:"block_id"`     // block chain id
+	TxID        string `json:"tx_id"`        // block chain tx id
+	BlockHeight int64  `json:"block_height"` // block height
+	Attempts    int    `json:"attempts"`     // publish attempts
+	LastError   string `json:"last_error"`   // error of last publish attempt
+	UpdatedAt   int64  `json:"updated_at"`   // updated time
+}
+
+// PubVerifyRequest defines the request of verifying publicity against block chain
+type PubVerifyRequest struct {
+	ID   string `form:"id" binding:"required"`   // funds or supplies id
+	Type string `form:"type" binding:"required"` // funds or supplies
+}
+
+// PubVerifyResp defines the report of verifying publicity against block chain
+type PubVerifyResp struct {
+	ID            string            `json:"id"`             // funds or supplies id
+	Type          string            `json:"type"`           // funds or supplies
+	BlockID       string            `json:"block_id"`       // block chain id
+	TxID          string            `json:"tx_id"`          // block chain tx id
+	BlockHeight   int64             `json:"block_height"`   // block height
+	SchemaVersion int               `json:"schema_version"` // schema version of payload
+	Match         bool              `json:"match"`          // whether all fields and hashes match
+	LocalHash     string            `json:"local_hash"`     // sha256 of the publicity recomputed from database
+	ChainHash     string            `json:"chain_hash"`     // sha256 of the publicity on block chain
+	StoredHash    string            `json:"stored_hash"`    // sha256 kept with the record when published, empty for legacy payload
+	Fields        []*PubVerifyField `json:"fields"`         // field by field report
+	Error         string            `json:"error"`          // error of verifying, the record fails
+}
+
+// PubVerifyField defines the report of one publicity field
+type PubVerifyField struct {
+	Field string      `json:"field"` // field name
+	Match bool        `json:"match"` // whether two sides match
+	Local interface{} `json:"local"` // value recomputed from database
+	Chain interface{} `json:"chain"` // value on block chain
+}
+
+// VerifyBundle defines the records exported for offline verification
+type VerifyBundle struct {
+	Records []*BundleRecord `json:"records"` // funds and supplies records
+}
+
+// BundleRecord defines one funds or supplies record with its proof image hashes and chain receipt
+type BundleRecord struct {
+	ID                string           `json:"id"`                   // funds or supplies id
+	Type              string           `json:"type"`                 // funds or supplies
+	PubType           string           `json:"pub_type"`             // donate, receive or distribute
+	SchemaVersion     int              `json:"schema_version"`       // schema version of payload
+	Time              int64            `json:"time"`                 // time of payload
+	UID               string           `json:"uid"`                  // user id
+	DonorName         string           `json:"donor_name"`           // user name of the one who donate
+	AidName           string           `json:"aid_name"`             // user name of the one who aided
+	TargetUID         string           `json:"target_uid"`           // charity user id
+	TargetName        string           `json:"target_name"`          // user name of the one who receive donation
+	TargetBankCardNum string           `json:"target_bank_card_num"` // bank card number of charity
+	Amount            string           `json:"amount"`               // the amount of funds
+	Name              string           `json:"name"`                 // name of supplies
+	Number            int64            `json:"number"`               // number of supplies
+	Unit              string           `json:"unit"`                 // unit of supplies
+	WayBillNum        string           `json:"way_bill_num"`         // supplies way bill number
+	BillingAddress    string           `json:"billing_addr"`         // full billing address
+	ShippingAddress   string           `json:"shipping_addr"`        // full shipping address
+	ProofImages       []*DonationImage `json:"proof_images"`         // donation proof images with hash
+	PayloadHash       string           `json:"payload_hash"`         // sha256 of payload kept with the record
+	Receipt           BundleReceipt    `json:"receipt"`              // chain receipt
+}
+
+// BundleReceipt defines the receipt of publicity on block chain
+type BundleReceipt struct {
+	BlockID     string `json:"block_id"`     // block chain id
+	TxID        string `json:"tx_id"`        // block chain tx id
+	BlockHeight int64  `json:"block_height"` // block height
+	BlockTime   int64  `json:"block_time"`   // block time
+	Publicity   string `json:"publicity"`    // payload on block chain
+}
+
+// VerifyBundleReport defines the report of verifying the records exported
+type VerifyBundleReport struct {
+	Total   int              `json:"total"`   // number of records
+	Passed  int              `json:"passed"`  // number of records passed
+	Failed  int              `json:"failed"`  // number of records failed
+	Results []*PubVerifyResp `json:"results"` // report of every record
+}
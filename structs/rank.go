@@ -0,0 +1,53 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package structs
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// RankStat defines the aggregates of charity or donor in a period, which are ranked on leaderboard
+type RankStat struct {
+	UID                 string          // user id of charity or donor
+	Name                string          // nick name of charity or donor
+	ReceivedFunds       decimal.Decimal // funds donated to charity
+	DistributedFunds    decimal.Decimal // funds distributed by charity
+	ReceivedSupplies    int64           // supplies donated to charity
+	DistributedSupplies int64           // supplies distributed by charity
+	Donors              int64           // number of donors of charity
+	DonatedFunds        decimal.Decimal // funds donated by donor
+	DonatedSupplies     int64           // supplies donated by donor
+}
+
+// LeaderboardRequest defines the request of query leaderboard
+type LeaderboardRequest struct {
+	Board     string `form:"board"`      // type of leaderboard, charity or donor
+	Metric    string `form:"metric"`     // metric ranked
+	Period    string `form:"period"`     // period ranked, week, month, year or all
+	PageNum   int    `form:"page_num"`   // page num
+	PageLimit int    `form:"page_limit"` // page limit
+}
+
+// RankItem defines the struct of leaderboard item
+type RankItem struct {
+	Rank  int             `json:"rank"`  // rank, the same for the same value
+	UID   string          `json:"uid"`   // user id of charity or donor
+	Name  string          `json:"name"`  // nick name of charity or donor
+	Value decimal.Decimal `json:"value"` // value of metric
+}
+
+// LeaderboardResp defines the response of leaderboard
+type LeaderboardResp struct {
+	Board       string      `json:"board"`        // type of leaderboard
+	Metric      string      `json:"metric"`       // metric ranked
+	Period      string      `json:"period"`       // period ranked
+	RefreshedAt int64       `json:"refreshed_at"` // time of leaderboard refreshed, 0 if never
+	PageNum     int         `json:"page_num"`     // page num
+	PageLimit   int         `json:"page_limit"`   // page limit
+	Total       int64       `json:"total"`        // total number of ranked items
+	Results     []*RankItem `json:"results"`      // ranked items
+}
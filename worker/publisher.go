@@ -0,0 +1,257 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Publisher drains the publicity outbox into the block chain adapter
+type Publisher struct {
+	srvcContext *context.Context
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	loop
+}
+
+// NewPublisher ...
+func NewPublisher(c *context.Context) (*Publisher, error) {
+	if nil == c || nil == c.Config || nil == c.DBStorage {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	cfg := c.Config.Publisher
+	p := &Publisher{
+		srvcContext: c,
+		interval:    time.Duration(cfg.Interval) * time.Second,
+		batchSize:   cfg.BatchSize,
+		maxAttempts: cfg.MaxAttempts,
+	}
+
+	if p.interval <= 0 {
+		p.interval = defaultInterval * time.Second
+	}
+
+	if p.batchSize < 1 {
+		p.batchSize = defaultBatchSize
+	}
+
+	if p.maxAttempts < 1 {
+		p.maxAttempts = defaultMaxAttempts
+	}
+
+	return p, nil
+}
+
+// Start starts to drain the outbox in background
+func (p *Publisher) Start() {
+	logger.Infof("starting block chain publisher, interval %v", p.interval)
+	p.loop.start(p.interval, func() {
+		if err := p.Drain(); err != nil {
+			logger.Errorf("drain publicity outbox error, %v", err)
+		}
+	})
+}
+
+// Stop stops the publisher
+func (p *Publisher) Stop() {
+	p.loop.stop()
+	logger.Info("block chain publisher stopped")
+}
+
+// Drain publishes one batch of pending publicities
+func (p *Publisher) Drain() error {
+	boxes, err := p.srvcContext.DBStorage.QueryOutboxPending(p.batchSize)
+	if err != nil {
+		return err
+	}
+
+	boxes, err = p.claim(boxes)
+	if err != nil {
+		return err
+	}
+
+	if len(boxes) == 0 {
+		return nil
+	}
+	logger.Debugf("got %d pending publicities", len(boxes))
+
+	p.dispatch(boxes)
+	return nil
+}
+
+// claim claims the boxes before publishing, so that each box is published by one publisher only,
+// returns the boxes claimed
+func (p *Publisher) claim(boxes []*models.PubOutbox) ([]*models.PubOutbox, error) {
+	if len(boxes) == 0 {
+		return nil, nil
+	}
+
+	tx := p.srvcContext.DBStorage.GetDBTransaction()
+	claimed := make([]*models.PubOutbox, 0)
+	for _, v := range boxes {
+		err := p.srvcContext.DBStorage.ClaimOutbox(tx, v)
+		if err == gorm.ErrRecordNotFound {
+			logger.Debugf("outbox %s claimed by others", v.ID)
+			continue
+		}
+
+		if err != nil {
+			p.srvcContext.DBStorage.DBTransactionRollback(tx)
+			return nil, err
+		}
+
+		claimed = append(claimed, v)
+	}
+
+	p.srvcContext.DBStorage.DBTransactionCommit(tx)
+	return claimed, nil
+}
+
+// dispatch publishes the boxes grouped by the did of publisher
+func (p *Publisher) dispatch(boxes []*models.PubOutbox) {
+	// the adapter publishes on behalf of one did per request
+	groups := make(map[string][]*models.PubOutbox)
+	order := make([]string, 0)
+	for _, v := range boxes {
+		if _, ok := groups[v.BCID]; !ok {
+			order = append(order, v.BCID)
+		}
+		groups[v.BCID] = append(groups[v.BCID], v)
+	}
+
+	for _, bcID := range order {
+		p.publish(bcID, groups[bcID])
+	}
+}
+
+func (p *Publisher) publish(bcID string, boxes []*models.PubOutbox) {
+	pubs := make([]string, 0)
+	for _, v := range boxes {
+		pubs = append(pubs, v.Publicity)
+	}
+
+	// one batch request per did, the supplies items of one request go together
+	results, err := p.srvcContext.IBCAdapter.BatchPubs(bcID, pubs)
+	for i, box := range boxes {
+		if err != nil {
+			p.failed(box, err.Error())
+			continue
+		}
+
+		if i >= len(results) || results[i] == nil {
+			p.failed(box, "missing publish result")
+			continue
+		}
+
+		if results[i].Code == rest.PubToBlockChainFailure || results[i].Data.ID == "" {
+			p.failed(box, results[i].Msg)
+			continue
+		}
+
+		p.published(box, results[i].Data.ID)
+	}
+}
+
+func (p *Publisher) published(box *models.PubOutbox, blockID string) {
+	tx := p.srvcContext.DBStorage.GetDBTransaction()
+	err := p.srvcContext.DBStorage.UpdateOutbox(tx, box.ID, &models.PubOutbox{
+		Status:   rest.ChainStatusPublished,
+		BlockID:  blockID,
+		Attempts: box.Attempts + 1,
+	})
+	if err != nil {
+		p.srvcContext.DBStorage.DBTransactionRollback(tx)
+		logger.Errorf("update outbox %s published error, %v", box.ID, err)
+		return
+	}
+
+	// the block chain id of each attempt is kept, the late call back of a previous one is still matched
+	err = p.srvcContext.DBStorage.CreateAttempt(tx, &models.PubAttempt{
+		RelatedID: box.RelatedID,
+		Type:      box.Type,
+		BlockID:   blockID,
+		Attempt:   box.Attempts + 1,
+	})
+	if err != nil {
+		p.srvcContext.DBStorage.DBTransactionRollback(tx)
+		logger.Errorf("create attempt of %s %s error, %v", box.Type, box.RelatedID, err)
+		return
+	}
+
+	switch box.Type {
+	case rest.DonatedTypeFunds:
+		err = p.srvcContext.DBStorage.UpdateFunds(tx, box.RelatedID, blockID)
+	case rest.DonatedTypeSupplies:
+		err = p.srvcContext.DBStorage.UpdateSupplies(tx, box.RelatedID, blockID)
+	}
+
+	if err == nil {
+		err = p.updateStatus(tx, box, rest.ChainStatusSubmitted, "")
+	}
+
+	if err != nil {
+		p.srvcContext.DBStorage.DBTransactionRollback(tx)
+		logger.Errorf("update %s %s block id error, %v", box.Type, box.RelatedID, err)
+		return
+	}
+
+	p.srvcContext.DBStorage.DBTransactionCommit(tx)
+	logger.Debugf("published %s %s, block chain id %s", box.Type, box.RelatedID, blockID)
+}
+
+func (p *Publisher) failed(box *models.PubOutbox, msg string) {
+	status := rest.ChainStatusPending
+	if box.Attempts+1 >= p.maxAttempts {
+		status = rest.ChainStatusFailed
+	}
+	logger.Errorf("publish %s %s failed, attempts %d, %s", box.Type, box.RelatedID, box.Attempts+1, msg)
+
+	tx := p.srvcContext.DBStorage.GetDBTransaction()
+	err := p.srvcContext.DBStorage.UpdateOutbox(tx, box.ID, &models.PubOutbox{
+		Status:    status,
+		Attempts:  box.Attempts + 1,
+		LastError: msg,
+	})
+	if err != nil {
+		p.srvcContext.DBStorage.DBTransactionRollback(tx)
+		logger.Errorf("update outbox %s failed error, %v", box.ID, err)
+		return
+	}
+
+	if err = p.updateStatus(tx, box, rest.ChainStatusFailed, msg); err == nil && status == rest.ChainStatusPending {
+		err = p.updateStatus(tx, box, rest.ChainStatusRetried, "")
+	}
+
+	if err != nil {
+		p.srvcContext.DBStorage.DBTransactionRollback(tx)
+		logger.Errorf("update %s %s chain status error, %v", box.Type, box.RelatedID, err)
+		return
+	}
+
+	p.srvcContext.DBStorage.DBTransactionCommit(tx)
+}
+
+// updateStatus transits the lifecycle status of the funds or supplies of box
+func (p *Publisher) updateStatus(tx *gorm.DB, box *models.PubOutbox, status, errMsg string) error {
+	switch box.Type {
+	case rest.DonatedTypeFunds:
+		return p.srvcContext.DBStorage.UpdateFundsStatus(tx, box.RelatedID, status, errMsg)
+	case rest.DonatedTypeSupplies:
+		return p.srvcContext.DBStorage.UpdateSuppliesStatus(tx, box.RelatedID, status, errMsg)
+	default:
+		return fmt.Errorf("unknown publicity type %s", box.Type)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/components/bcadapter/mock_bcadapter"
+	"github.com/csiabb/donation-service/config"
+	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/models/mock_backend"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+)
+
+func Init(t *testing.T) (*gomock.Controller, *Publisher, *mock_backend.MockIDBBackend, *mock_bcadapter.MockIBCAdapter) {
+	mockCtl := gomock.NewController(t)
+	mockBackend := mock_backend.NewMockIDBBackend(mockCtl)
+	mockBCAdapter := mock_bcadapter.NewMockIBCAdapter(mockCtl)
+
+	srvcContext := &context.Context{}
+	srvcContext.Config = &config.SrvcCfg{}
+	srvcContext.DBStorage = mockBackend
+	srvcContext.IBCAdapter = mockBCAdapter
+
+	p, err := NewPublisher(srvcContext)
+	if err != nil {
+		t.Fatalf("new publisher error, %v", err)
+	}
+
+	return mockCtl, p, mockBackend, mockBCAdapter
+}
+
+func expectClaim(mockBackend *mock_backend.MockIDBBackend, db *gorm.DB, err error) {
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().ClaimOutbox(db, gomock.Any()).Return(err)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+}
+
+func pendingOutbox() []*models.PubOutbox {
+	return []*models.PubOutbox{
+		{
+			ID:        "outbox_id_1",
+			RelatedID: "funds_id",
+			Type:      rest.DonatedTypeFunds,
+			BCID:      "did:axn:da-322e9abb-841e-4778-be61-93741d8f4621",
+			Publicity: `{"amount":"100"}`,
+			Status:    rest.ChainStatusPending,
+		},
+	}
+}
+
+func TestDrainPublished(t *testing.T) {
+	mockCtl, p, mockBackend, mockBCAdapter := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryOutboxPending(gomock.Any()).Return(pendingOutbox(), nil)
+	expectClaim(mockBackend, db, nil)
+	mockBCAdapter.EXPECT().BatchPubs(gomock.Any(), gomock.Any()).Return([]*structs.PubResp{
+		{
+			Code: 0,
+			Msg:  "",
+			Data: structs.PubRespData{ID: "block_id_1"},
+		},
+	}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().UpdateOutbox(db, "outbox_id_1", &models.PubOutbox{
+		Status:   rest.ChainStatusPublished,
+		BlockID:  "block_id_1",
+		Attempts: 1,
+	}).Return(nil)
+	mockBackend.EXPECT().CreateAttempt(db, &models.PubAttempt{
+		RelatedID: "funds_id",
+		Type:      rest.DonatedTypeFunds,
+		BlockID:   "block_id_1",
+		Attempt:   1,
+	}).Return(nil)
+	mockBackend.EXPECT().UpdateFunds(db, "funds_id", "block_id_1").Return(nil)
+	mockBackend.EXPECT().UpdateFundsStatus(db, "funds_id", rest.ChainStatusSubmitted, "").Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	if err := p.Drain(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDrainAdapterFailed(t *testing.T) {
+	mockCtl, p, mockBackend, mockBCAdapter := Init(t)
+	defer mockCtl.Finish()
+
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryOutboxPending(gomock.Any()).Return(pendingOutbox(), nil)
+	expectClaim(mockBackend, db, nil)
+	mockBCAdapter.EXPECT().BatchPubs(gomock.Any(), gomock.Any()).Return(nil, errors.New("adapter unavailable"))
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().UpdateOutbox(db, "outbox_id_1", &models.PubOutbox{
+		Status:    rest.ChainStatusPending,
+		Attempts:  1,
+		LastError: "adapter unavailable",
+	}).Return(nil)
+	mockBackend.EXPECT().UpdateFundsStatus(db, "funds_id", rest.ChainStatusFailed, "adapter unavailable").Return(nil)
+	mockBackend.EXPECT().UpdateFundsStatus(db, "funds_id", rest.ChainStatusRetried, "").Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+
+	if err := p.Drain(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDrainClaimedByOthers(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	// the box claimed by another publisher is not published again
+	db := &gorm.DB{}
+	mockBackend.EXPECT().QueryOutboxPending(gomock.Any()).Return(pendingOutbox(), nil)
+	expectClaim(mockBackend, db, gorm.ErrRecordNotFound)
+
+	if err := p.Drain(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDrainDB(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	mockBackend.EXPECT().QueryOutboxPending(gomock.Any()).Return(nil, errors.New("query outbox failed"))
+
+	if err := p.Drain(); err == nil {
+		t.Error("drain db check failed")
+	}
+}
+
+func TestNewPublisherWithoutDB(t *testing.T) {
+	srvcContext := &context.Context{Config: &config.SrvcCfg{}}
+	if _, err := NewPublisher(srvcContext); err == nil {
+		t.Error("expect error without database")
+	}
+}
+
+func TestLoopRecovered(t *testing.T) {
+	l := loop{}
+	done := make(chan struct{})
+	l.startNow(time.Hour, func() {
+		defer close(done)
+		panic("job panic")
+	})
+
+	<-done
+	l.stop()
+}
@@ -0,0 +1,103 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
+)
+
+// Ranker refreshes the leaderboard snapshots of charities and donors
+type Ranker struct {
+	srvcContext *context.Context
+	interval    time.Duration
+	topN        int
+	loop
+}
+
+// NewRanker ...
+func NewRanker(c *context.Context) (*Ranker, error) {
+	if nil == c || nil == c.Config || nil == c.DBStorage {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	cfg := c.Config.Ranker
+	r := &Ranker{
+		srvcContext: c,
+		interval:    time.Duration(cfg.Interval) * time.Second,
+		topN:        cfg.TopN,
+	}
+
+	if r.interval <= 0 {
+		r.interval = defaultRankInterval * time.Second
+	}
+
+	if r.topN < 1 {
+		r.topN = defaultRankTopN
+	}
+
+	return r, nil
+}
+
+// Start refreshes the snapshots in background, the first refresh does not block the service from starting
+func (r *Ranker) Start() {
+	logger.Infof("starting leaderboard ranker, interval %v", r.interval)
+	job := func() {
+		if err := r.Refresh(); err != nil {
+			logger.Errorf("refresh leaderboard error, %v", err)
+		}
+	}
+
+	r.loop.startNow(r.interval, job)
+}
+
+// Stop stops the ranker
+func (r *Ranker) Stop() {
+	r.loop.stop()
+	logger.Info("leaderboard ranker stopped")
+}
+
+// Refresh ranks every board in every period, the failed one keeps the previous snapshots and the others
+// are still refreshed, returns the last error
+func (r *Ranker) Refresh() error {
+	now := time.Now()
+
+	var last error
+	for _, board := range []string{rest.BoardCharity, rest.BoardDonor} {
+		for _, period := range models.Periods {
+			if err := r.refresh(board, period, now); err != nil {
+				logger.Errorf("refresh %s leaderboard of %s error, %v", board, period, err)
+				last = err
+			}
+		}
+	}
+
+	return last
+}
+
+// refresh replaces the snapshots of board in period
+func (r *Ranker) refresh(board, period string, now time.Time) error {
+	stats, err := r.srvcContext.DBStorage.SumRankStats(board, models.PeriodSince(period, now))
+	if err != nil {
+		return err
+	}
+
+	snapshots := models.RankSnapshots(board, period, stats, now.UTC().Unix(), r.topN)
+
+	tx := r.srvcContext.DBStorage.GetDBTransaction()
+	if err := r.srvcContext.DBStorage.ReplaceRankSnapshots(tx, board, period, snapshots); err != nil {
+		r.srvcContext.DBStorage.DBTransactionRollback(tx)
+		return err
+	}
+
+	r.srvcContext.DBStorage.DBTransactionCommit(tx)
+	return nil
+}
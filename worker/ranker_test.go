@@ -0,0 +1,132 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/config"
+	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+func TestRankerRefresh(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewRanker(p.srvcContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &gorm.DB{}
+	charities := []*structs.RankStat{
+		{UID: "charity_a", ReceivedFunds: decimal.New(100, 0), DistributedFunds: decimal.New(50, 0), Donors: 2},
+		{UID: "charity_b", ReceivedFunds: decimal.New(200, 0), DistributedFunds: decimal.New(50, 0), Donors: 2},
+		{UID: "charity_c", ReceivedFunds: decimal.Zero, DistributedFunds: decimal.Zero, ReceivedSupplies: 10},
+	}
+
+	snapshots := make(map[string][]*models.RankSnapshot)
+	mockBackend.EXPECT().SumRankStats(rest.BoardCharity, gomock.Any()).Return(charities, nil).Times(len(models.Periods))
+	mockBackend.EXPECT().SumRankStats(rest.BoardDonor, gomock.Any()).Return(nil, nil).Times(len(models.Periods))
+	mockBackend.EXPECT().GetDBTransaction().Return(db).Times(2 * len(models.Periods))
+	mockBackend.EXPECT().ReplaceRankSnapshots(db, gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(tx *gorm.DB, board, period string, data []*models.RankSnapshot) error {
+			snapshots[board+period] = data
+			return nil
+		}).Times(2 * len(models.Periods))
+	mockBackend.EXPECT().DBTransactionCommit(db).Times(2 * len(models.Periods))
+
+	if err := r.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	ranks := make(map[string][]*models.RankSnapshot)
+	for _, v := range snapshots[rest.BoardCharity+rest.PeriodWeek] {
+		ranks[v.Metric] = append(ranks[v.Metric], v)
+	}
+
+	// charity_b received more, charity_c received no funds
+	if v := ranks[rest.MetricReceivedFunds]; len(v) != 2 || v[0].UID != "charity_b" || v[0].Ranking != 1 || v[1].Ranking != 2 {
+		t.Errorf("received funds ranking failed, %v", v)
+	}
+
+	// the same value ranks the same
+	if v := ranks[rest.MetricDonors]; len(v) != 2 || v[0].Ranking != 1 || v[1].Ranking != 1 {
+		t.Errorf("donors ranking failed, %v", v)
+	}
+
+	if v := ranks[rest.MetricDistributionRatio]; len(v) != 2 || v[0].UID != "charity_a" || !v[0].Value.Equal(decimal.New(5, -1)) {
+		t.Errorf("distribution ratio ranking failed, %v", v)
+	}
+
+	if v := snapshots[rest.BoardDonor+rest.PeriodAll]; len(v) != 0 {
+		t.Errorf("donor ranking failed, %v", v)
+	}
+}
+
+func TestRankerRefreshFailed(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewRanker(p.srvcContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the failed board keeps the previous snapshots, the others are refreshed
+	db := &gorm.DB{}
+	mockBackend.EXPECT().SumRankStats(rest.BoardCharity, gomock.Any()).Return(nil, errors.New("db error")).Times(len(models.Periods))
+	mockBackend.EXPECT().SumRankStats(rest.BoardDonor, gomock.Any()).Return(nil, nil).Times(len(models.Periods))
+	mockBackend.EXPECT().GetDBTransaction().Return(db).Times(len(models.Periods))
+	mockBackend.EXPECT().ReplaceRankSnapshots(db, rest.BoardDonor, gomock.Any(), gomock.Any()).Return(nil).Times(len(models.Periods))
+	mockBackend.EXPECT().DBTransactionCommit(db).Times(len(models.Periods))
+
+	if err := r.Refresh(); err == nil {
+		t.Error("refresh error check failed")
+	}
+}
+
+func TestRankerStartInBackground(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewRanker(p.srvcContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first refresh is slow, starting is not blocked by it
+	refreshing := make(chan struct{})
+	release := make(chan struct{})
+	mockBackend.EXPECT().SumRankStats(rest.BoardCharity, gomock.Any()).DoAndReturn(func(board string, since time.Time) ([]*structs.RankStat, error) {
+		close(refreshing)
+		<-release
+		return nil, errors.New("db error")
+	})
+	mockBackend.EXPECT().SumRankStats(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error")).AnyTimes()
+
+	r.Start()
+	<-refreshing
+	close(release)
+	r.Stop()
+}
+
+func TestNewRankerWithoutDB(t *testing.T) {
+	srvcContext := &context.Context{Config: &config.SrvcCfg{}}
+	if _, err := NewRanker(srvcContext); err == nil {
+		t.Error("expect error without database")
+	}
+}
@@ -0,0 +1,267 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/common/utils"
+	"github.com/csiabb/donation-service/context"
+	"github.com/csiabb/donation-service/models"
+)
+
+// Reconciler re-publishes the publicities stuck without block chain call back, and adopts the ones published
+// before the outbox existed
+type Reconciler struct {
+	srvcContext *context.Context
+	publisher   *Publisher
+	interval    time.Duration
+	stuckAge    time.Duration
+	batchSize   int
+	maxAttempts int
+	loop
+}
+
+// NewReconciler ...
+func NewReconciler(c *context.Context, p *Publisher) (*Reconciler, error) {
+	if nil == c || nil == c.Config || nil == c.DBStorage || nil == p {
+		return nil, fmt.Errorf("param is nil")
+	}
+
+	cfg := c.Config.Reconciler
+	r := &Reconciler{
+		srvcContext: c,
+		publisher:   p,
+		interval:    time.Duration(cfg.Interval) * time.Second,
+		stuckAge:    time.Duration(cfg.StuckAge) * time.Second,
+		batchSize:   cfg.BatchSize,
+		maxAttempts: cfg.MaxAttempts,
+	}
+
+	if r.interval <= 0 {
+		r.interval = defaultReconcileInterval * time.Second
+	}
+
+	if r.stuckAge <= 0 {
+		r.stuckAge = defaultStuckAge * time.Second
+	}
+
+	if r.batchSize < 1 {
+		r.batchSize = defaultBatchSize
+	}
+
+	if r.maxAttempts < 1 {
+		r.maxAttempts = defaultReconcileMaxAttempts
+	}
+
+	return r, nil
+}
+
+// Start starts to reconcile in background
+func (r *Reconciler) Start() {
+	logger.Infof("starting block chain reconciler, interval %v", r.interval)
+	r.loop.start(r.interval, func() {
+		if _, err := r.Reconcile(); err != nil {
+			logger.Errorf("reconcile publicity error, %v", err)
+		}
+	})
+}
+
+// Stop stops the reconciler
+func (r *Reconciler) Stop() {
+	r.loop.stop()
+	logger.Info("block chain reconciler stopped")
+}
+
+// Reconcile adopts one batch of publicities without outbox and re-publishes one batch of stuck publicities,
+// returns the number adopted and re-published, the adopted ones are published by the publisher
+func (r *Reconciler) Reconcile() (int, error) {
+	adopted, err := r.adopt()
+	if err != nil {
+		return 0, err
+	}
+
+	boxes, err := r.srvcContext.DBStorage.QueryOutboxStuck(time.Now().Add(-r.stuckAge), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	due := make([]*models.PubOutbox, 0)
+	for _, v := range boxes {
+		if v.Attempts >= r.maxAttempts {
+			continue
+		}
+
+		if time.Since(v.UpdatedAt) < r.backoff(v.Attempts) {
+			continue
+		}
+
+		if err := r.retry(v); err != nil {
+			logger.Errorf("retry %s %s error, %v", v.Type, v.RelatedID, err)
+			continue
+		}
+
+		due = append(due, v)
+	}
+
+	if len(due) == 0 {
+		return adopted, nil
+	}
+	logger.Infof("re-publishing %d stuck publicities", len(due))
+
+	r.publisher.dispatch(due)
+	return adopted + len(due), nil
+}
+
+// adopt creates the missing outbox of the funds and supplies without block chain call back, returns the number
+// adopted
+func (r *Reconciler) adopt() (int, error) {
+	before := time.Now().Add(-r.stuckAge)
+	funds, err := r.srvcContext.DBStorage.QueryFundsWithoutOutbox(before, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	supplies, err := r.srvcContext.DBStorage.QuerySuppliesWithoutOutbox(before, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	num := 0
+	for _, v := range funds {
+		detail, err := r.srvcContext.DBStorage.QueryFundsDetail(v.ID)
+		var publicity string
+		if err == nil {
+			publicity, err = detail.Publicity(v.CreatedAt.Unix())
+		}
+
+		if err == nil {
+			err = r.adoptOne(&models.PubOutbox{RelatedID: v.ID, Type: rest.DonatedTypeFunds, Publicity: publicity},
+				v.PublisherUID(), v.BlockID, v.ChainStatus)
+		}
+
+		if err == models.ErrDuplicated {
+			logger.Infof("funds %s adopted by others", v.ID)
+			continue
+		}
+
+		if err != nil {
+			logger.Errorf("adopt funds %s error, %v", v.ID, err)
+			continue
+		}
+		num++
+	}
+
+	for _, v := range supplies {
+		detail, err := r.srvcContext.DBStorage.QuerySuppliesDetail(v.ID)
+		var publicity string
+		if err == nil {
+			publicity, err = detail.Publicity(v.CreatedAt.Unix())
+		}
+
+		if err == nil {
+			err = r.adoptOne(&models.PubOutbox{RelatedID: v.ID, Type: rest.DonatedTypeSupplies, Publicity: publicity},
+				v.PublisherUID(), v.BlockID, v.ChainStatus)
+		}
+
+		if err == models.ErrDuplicated {
+			logger.Infof("supplies %s adopted by others", v.ID)
+			continue
+		}
+
+		if err != nil {
+			logger.Errorf("adopt supplies %s error, %v", v.ID, err)
+			continue
+		}
+		num++
+	}
+
+	if num > 0 {
+		logger.Infof("adopted %d publicities without outbox", num)
+	}
+
+	return num, nil
+}
+
+// adoptOne creates the pending outbox of the funds or supplies published by uid, the block chain id given before
+// is kept as an attempt so that its late call back is still matched, the submitted one is failed and retried
+func (r *Reconciler) adoptOne(box *models.PubOutbox, uid, blockID, status string) error {
+	acc, err := r.srvcContext.DBStorage.QueryAccount("", "", uid)
+	if err != nil {
+		return err
+	}
+
+	box.ID = utils.GenerateUUID()
+	box.BCID = acc.DID
+	box.Status = rest.ChainStatusPending
+
+	tx := r.srvcContext.DBStorage.GetDBTransaction()
+	err = r.srvcContext.DBStorage.CreateOutbox(tx, []*models.PubOutbox{box})
+	if err == nil && blockID != "" {
+		err = r.srvcContext.DBStorage.CreateAttempt(tx, &models.PubAttempt{
+			RelatedID: box.RelatedID,
+			Type:      box.Type,
+			BlockID:   blockID,
+		})
+	}
+
+	if err == nil && status == rest.ChainStatusSubmitted {
+		err = r.publisher.updateStatus(tx, box, rest.ChainStatusFailed, "block chain call back timeout")
+		status = rest.ChainStatusFailed
+	}
+
+	if err == nil && status == rest.ChainStatusFailed {
+		err = r.publisher.updateStatus(tx, box, rest.ChainStatusRetried, "")
+	}
+
+	if err != nil {
+		r.srvcContext.DBStorage.DBTransactionRollback(tx)
+		return err
+	}
+
+	r.srvcContext.DBStorage.DBTransactionCommit(tx)
+	return nil
+}
+
+// backoff returns the delay before the next attempt, doubled after each attempt
+func (r *Reconciler) backoff(attempts int) time.Duration {
+	delay := r.stuckAge
+	for i := 0; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	return delay
+}
+
+// retry claims the box and marks its funds or supplies to be retried, the box claimed by others is skipped
+func (r *Reconciler) retry(box *models.PubOutbox) error {
+	tx := r.srvcContext.DBStorage.GetDBTransaction()
+
+	// the published or the publishing left by a crashed publisher fails first
+	err := r.srvcContext.DBStorage.ClaimOutbox(tx, box)
+	if err == nil && box.Status != rest.ChainStatusFailed {
+		err = r.publisher.updateStatus(tx, box, rest.ChainStatusFailed, "block chain call back timeout")
+	}
+
+	if err == nil {
+		err = r.publisher.updateStatus(tx, box, rest.ChainStatusRetried, "")
+	}
+
+	if err != nil {
+		r.srvcContext.DBStorage.DBTransactionRollback(tx)
+		return err
+	}
+
+	r.srvcContext.DBStorage.DBTransactionCommit(tx)
+	return nil
+}
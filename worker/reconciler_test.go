@@ -0,0 +1,245 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/csiabb/donation-service/common/rest"
+	"github.com/csiabb/donation-service/models"
+	"github.com/csiabb/donation-service/models/mock_backend"
+	"github.com/csiabb/donation-service/structs"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+	"github.com/shopspring/decimal"
+)
+
+func stuckOutbox(attempts int, updatedAt time.Time) []*models.PubOutbox {
+	return []*models.PubOutbox{
+		{
+			ID:        "outbox_id_1",
+			RelatedID: "supplies_id",
+			Type:      rest.DonatedTypeSupplies,
+			BCID:      "did:axn:da-eecf83b7-2bc9-49f2-8005-e0e39f606450",
+			Publicity: `{"name":"mask"}`,
+			Status:    rest.ChainStatusPublished,
+			BlockID:   "block_id_1",
+			Attempts:  attempts,
+			UpdatedAt: updatedAt,
+		},
+	}
+}
+
+func expectNoAdopt(mockBackend *mock_backend.MockIDBBackend) {
+	mockBackend.EXPECT().QueryFundsWithoutOutbox(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockBackend.EXPECT().QuerySuppliesWithoutOutbox(gomock.Any(), gomock.Any()).Return(nil, nil)
+}
+
+func TestReconcileRepublished(t *testing.T) {
+	mockCtl, p, mockBackend, mockBCAdapter := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewReconciler(p.srvcContext, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := &gorm.DB{}
+	expectNoAdopt(mockBackend)
+	mockBackend.EXPECT().QueryOutboxStuck(gomock.Any(), gomock.Any()).Return(stuckOutbox(1, time.Now().Add(-time.Hour)), nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db).Times(2)
+	mockBackend.EXPECT().ClaimOutbox(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateSuppliesStatus(db, "supplies_id", rest.ChainStatusFailed, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateSuppliesStatus(db, "supplies_id", rest.ChainStatusRetried, "").Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db).Times(2)
+	mockBCAdapter.EXPECT().BatchPubs(gomock.Any(), gomock.Any()).Return([]*structs.PubResp{
+		{
+			Code: 0,
+			Msg:  "",
+			Data: structs.PubRespData{ID: "block_id_2"},
+		},
+	}, nil)
+	mockBackend.EXPECT().UpdateOutbox(db, "outbox_id_1", &models.PubOutbox{
+		Status:   rest.ChainStatusPublished,
+		BlockID:  "block_id_2",
+		Attempts: 2,
+	}).Return(nil)
+	mockBackend.EXPECT().CreateAttempt(db, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateSupplies(db, "supplies_id", "block_id_2").Return(nil)
+	mockBackend.EXPECT().UpdateSuppliesStatus(db, "supplies_id", rest.ChainStatusSubmitted, "").Return(nil)
+
+	num, err := r.Reconcile()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if num != 1 {
+		t.Errorf("re-published %d, expect 1", num)
+	}
+}
+
+func TestReconcileBackoff(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewReconciler(p.srvcContext, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the third attempt waits for 4 times of stuck age
+	expectNoAdopt(mockBackend)
+	mockBackend.EXPECT().QueryOutboxStuck(gomock.Any(), gomock.Any()).Return(stuckOutbox(2, time.Now().Add(-30*time.Minute)), nil)
+
+	num, err := r.Reconcile()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if num != 0 {
+		t.Errorf("re-published %d, expect 0", num)
+	}
+}
+
+func TestReconcileDB(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewReconciler(p.srvcContext, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNoAdopt(mockBackend)
+	mockBackend.EXPECT().QueryOutboxStuck(gomock.Any(), gomock.Any()).Return(nil, errors.New("query outbox failed"))
+
+	if _, err := r.Reconcile(); err == nil {
+		t.Error("reconcile db check failed")
+	}
+}
+
+func TestReconcileClaimedByOthers(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewReconciler(p.srvcContext, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the box re-published by another reconciler is skipped
+	db := &gorm.DB{}
+	expectNoAdopt(mockBackend)
+	mockBackend.EXPECT().QueryOutboxStuck(gomock.Any(), gomock.Any()).Return(stuckOutbox(1, time.Now().Add(-time.Hour)), nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().ClaimOutbox(db, gomock.Any()).Return(gorm.ErrRecordNotFound)
+	mockBackend.EXPECT().DBTransactionRollback(db)
+
+	num, err := r.Reconcile()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if num != 0 {
+		t.Errorf("re-published %d, expect 0", num)
+	}
+}
+
+func TestReconcileAdoptLegacy(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewReconciler(p.srvcContext, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the funds published before outbox got a block chain id but no call back
+	db := &gorm.DB{}
+	funds := &models.PubFunds{
+		ID:          "funds_id",
+		UID:         "uid",
+		PubType:     rest.PubTypeDonate,
+		Amount:      decimal.New(100, 0),
+		BlockID:     "block_id_legacy",
+		ChainStatus: rest.ChainStatusSubmitted,
+		SchemaVer:   rest.PayloadSchemaLegacy,
+		CreatedAt:   time.Now().Add(-time.Hour),
+	}
+	mockBackend.EXPECT().QueryFundsWithoutOutbox(gomock.Any(), gomock.Any()).Return([]*models.PubFunds{funds}, nil)
+	mockBackend.EXPECT().QuerySuppliesWithoutOutbox(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(&models.FundsDetail{Funds: *funds}, nil)
+	mockBackend.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", DID: "did"}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateOutbox(db, gomock.Any()).DoAndReturn(func(tx *gorm.DB, data []*models.PubOutbox) error {
+		if len(data) != 1 || data[0].RelatedID != "funds_id" || data[0].BCID != "did" || data[0].Status != rest.ChainStatusPending ||
+			data[0].Publicity == "" {
+			t.Errorf("outbox check failed, %v", data)
+		}
+		return nil
+	})
+	mockBackend.EXPECT().CreateAttempt(db, &models.PubAttempt{
+		RelatedID: "funds_id",
+		Type:      rest.DonatedTypeFunds,
+		BlockID:   "block_id_legacy",
+	}).Return(nil)
+	mockBackend.EXPECT().UpdateFundsStatus(db, "funds_id", rest.ChainStatusFailed, gomock.Any()).Return(nil)
+	mockBackend.EXPECT().UpdateFundsStatus(db, "funds_id", rest.ChainStatusRetried, "").Return(nil)
+	mockBackend.EXPECT().DBTransactionCommit(db)
+	mockBackend.EXPECT().QueryOutboxStuck(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	num, err := r.Reconcile()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if num != 1 {
+		t.Errorf("adopted %d, expect 1", num)
+	}
+}
+
+func TestReconcileAdoptedByOthers(t *testing.T) {
+	mockCtl, p, mockBackend, _ := Init(t)
+	defer mockCtl.Finish()
+
+	r, err := NewReconciler(p.srvcContext, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the outbox of funds is created by another reconciler at the same time
+	db := &gorm.DB{}
+	funds := &models.PubFunds{
+		ID:          "funds_id",
+		UID:         "uid",
+		PubType:     rest.PubTypeDonate,
+		Amount:      decimal.New(100, 0),
+		ChainStatus: rest.ChainStatusCreated,
+		SchemaVer:   rest.PayloadSchemaLegacy,
+		CreatedAt:   time.Now().Add(-time.Hour),
+	}
+	mockBackend.EXPECT().QueryFundsWithoutOutbox(gomock.Any(), gomock.Any()).Return([]*models.PubFunds{funds}, nil)
+	mockBackend.EXPECT().QuerySuppliesWithoutOutbox(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockBackend.EXPECT().QueryFundsDetail("funds_id").Return(&models.FundsDetail{Funds: *funds}, nil)
+	mockBackend.EXPECT().QueryAccount("", "", "uid").Return(&models.Account{ID: "uid", DID: "did"}, nil)
+	mockBackend.EXPECT().GetDBTransaction().Return(db)
+	mockBackend.EXPECT().CreateOutbox(db, gomock.Any()).Return(models.ErrDuplicated)
+	mockBackend.EXPECT().DBTransactionRollback(db)
+	mockBackend.EXPECT().QueryOutboxStuck(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	num, err := r.Reconcile()
+	if err != nil {
+		t.Error(err)
+	}
+
+	if num != 0 {
+		t.Errorf("adopted %d, expect 0", num)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright ArxanChain Ltd. 2020 All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/csiabb/donation-service/common/log"
+)
+
+var (
+	logger = log.MustGetLogger("worker")
+)
+
+// default value of worker configure
+const (
+	defaultInterval    = 2  // seconds
+	defaultBatchSize   = 50 // items
+	defaultMaxAttempts = 5  // times
+
+	defaultReconcileInterval    = 60  // seconds
+	defaultStuckAge             = 600 // seconds
+	defaultReconcileMaxAttempts = 10  // times
+
+	defaultRankInterval = 600 // seconds
+	defaultRankTopN     = 100 // items
+
+	maxBackoff = 24 * time.Hour
+)
+
+// loop runs the job every interval until stopped
+type loop struct {
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// start runs job in background every interval
+func (l *loop) start(interval time.Duration, job func()) {
+	l.run(interval, job, false)
+}
+
+// startNow runs job in background at once and then every interval
+func (l *loop) startNow(interval time.Duration, job func()) {
+	l.run(interval, job, true)
+}
+
+func (l *loop) run(interval time.Duration, job func(), now bool) {
+	l.stopCh = make(chan struct{})
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		if now {
+			l.do(job)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.stopCh:
+				return
+			case <-ticker.C:
+				l.do(job)
+			}
+		}
+	}()
+}
+
+// do runs job, the panic of which is recovered to keep the service and the loop running
+func (l *loop) do(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("background job panic, %v", r)
+		}
+	}()
+
+	job()
+}
+
+// stop stops the loop and waits for the running job
+func (l *loop) stop() {
+	if l.stopCh == nil {
+		return
+	}
+
+	close(l.stopCh)
+	l.wg.Wait()
+	l.stopCh = nil
+}